@@ -0,0 +1,177 @@
+package baseline
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/bozso/gotoolbox/errors"
+
+	"github.com/bozso/gomma/orbit"
+)
+
+// Baseline holds the baselines of a scene relative to the master.
+type Baseline struct {
+	Name string    `json:"name"`
+	Date time.Time `json:"date"`
+
+	// Perpendicular baseline in metres, positive if the scene was
+	// acquired further from the Earth than the master, perpendicular to
+	// the line of sight.
+	Perpendicular float64 `json:"perpendicular"`
+
+	// Parallel baseline in metres, approximately the slant range of the
+	// master minus the one of the scene.
+	Parallel float64 `json:"parallel"`
+
+	// Temporal baseline in days.
+	Temporal float64 `json:"temporal"`
+
+	// Height difference corresponding to a 2 pi phase cycle in the
+	// interferogram with the master, 0 for zero perpendicular baseline.
+	HeightOfAmbiguity float64 `json:"height_of_ambiguity"`
+}
+
+/*
+Compute returns the baselines of the slave relative to the master at the
+centre of the master image. The target at the centre is located on the
+ellipsoid, then the slave position is taken at the zero Doppler time of
+the target in the slave orbit.
+*/
+func Compute(master, slave Scene) (b Baseline, err error) {
+	m, s := master.SAR, slave.SAR
+
+	center := orbit.RadarPoint{
+		Rng: float64(m.RangeSamples-1) / 2.0,
+		Azi: float64(m.AzimuthLines-1) / 2.0,
+	}
+
+	msv, err := m.Orbit.Interpolate(m.Time(center.Azi))
+	if err != nil {
+		return
+	}
+
+	g, err := m.ToGeo(center, 0.0)
+	if err != nil {
+		return
+	}
+	target := m.Ellipsoid.ToECEF(g)
+
+	r, err := s.ToRadar(g)
+	if err != nil {
+		return b, errors.WrapFmt(err, "failed to locate the centre of "+
+			"master '%s' in scene '%s'", master.Name, slave.Name)
+	}
+
+	ssv, err := s.Orbit.Interpolate(s.Time(r.Azi))
+	if err != nil {
+		return
+	}
+
+	los := target.Sub(msv.Position)
+	rng := los.Norm()
+	los = los.Unit()
+
+	// perpendicular to the line of sight in the zero Doppler plane,
+	// pointing away from the Earth for right looking sensors
+	perp := los.Cross(msv.Velocity).Unit()
+
+	base := ssv.Position.Sub(msv.Position)
+
+	b = Baseline{
+		Name:          slave.Name,
+		Date:          slave.Date,
+		Perpendicular: base.Dot(perp),
+		Parallel:      base.Dot(los),
+		Temporal:      slave.Date.Sub(master.Date).Hours() / 24.0,
+	}
+
+	if math.Abs(b.Perpendicular) > 1e-3 {
+		inc := m.Ellipsoid.IncidenceAngle(msv.Position, target) * math.Pi / 180.0
+		b.HeightOfAmbiguity = m.Wavelength() * rng * math.Sin(inc) /
+			(2.0 * b.Perpendicular)
+	}
+
+	return b, nil
+}
+
+// Table holds the baselines of every scene of a stack.
+type Table struct {
+	Master     string     `json:"master"`
+	MasterDate time.Time  `json:"master_date"`
+	Baselines  []Baseline `json:"baselines"`
+}
+
+// Baselines computes the baselines of the scenes relative to the scene
+// with the index master.
+func (s Stack) Baselines(master int) (t Table, err error) {
+	if master < 0 || master >= len(s) {
+		return t, fmt.Errorf("master index %d is out of the stack of %d "+
+			"scenes", master, len(s))
+	}
+
+	m := s[master]
+	t.Master, t.MasterDate = m.Name, m.Date
+	t.Baselines = make([]Baseline, len(s))
+
+	for ii, sc := range s {
+		if ii == master {
+			t.Baselines[ii] = Baseline{Name: sc.Name, Date: sc.Date}
+			continue
+		}
+
+		if t.Baselines[ii], err = Compute(m, sc); err != nil {
+			return
+		}
+	}
+
+	return t, nil
+}
+
+// MasterIndex returns the index of the master in the baselines.
+func (t Table) MasterIndex() int {
+	for ii, b := range t.Baselines {
+		if b.Name == t.Master {
+			return ii
+		}
+	}
+	return -1
+}
+
+func (t Table) WriteJSON(w io.Writer) (err error) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(t)
+}
+
+func (t Table) WriteCSV(w io.Writer) (err error) {
+	cw := csv.NewWriter(w)
+
+	err = cw.Write([]string{"name", "date", "temporal_days",
+		"perpendicular_m", "parallel_m", "height_of_ambiguity_m"})
+	if err != nil {
+		return
+	}
+
+	for _, b := range t.Baselines {
+		err = cw.Write([]string{
+			b.Name,
+			b.Date.UTC().Format(time.RFC3339),
+			fmt.Sprintf("%.2f", b.Temporal),
+			fmt.Sprintf("%.2f", b.Perpendicular),
+			fmt.Sprintf("%.2f", b.Parallel),
+			fmt.Sprintf("%.2f", b.HeightOfAmbiguity),
+		})
+
+		if err != nil {
+			return
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
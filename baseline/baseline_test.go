@@ -0,0 +1,169 @@
+package baseline
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bozso/gomma/utils/params"
+)
+
+const testOmega = 2.0 * math.Pi / 6000.0
+
+// testScene creates a scene on a circular polar orbit with the given
+// radius.
+func testScene(t *testing.T, name, date string, radius float64) (s Scene) {
+	sb := &strings.Builder{}
+
+	fmt.Fprintf(sb, "date:                    %s\n", date)
+	fmt.Fprintf(sb, "start_time:              100.000000   s\n")
+	fmt.Fprintf(sb, "azimuth_line_time:       2.0000000e-03   s\n")
+	fmt.Fprintf(sb, "range_samples:           1000\n")
+	fmt.Fprintf(sb, "azimuth_lines:           5000\n")
+	fmt.Fprintf(sb, "range_pixel_spacing:     10.000000   m\n")
+	fmt.Fprintf(sb, "near_range_slc:          800000.0000  m\n")
+	fmt.Fprintf(sb, "center_range_slc:        805000.0000  m\n")
+	fmt.Fprintf(sb, "radar_frequency:         5.4050005e+09   Hz\n")
+	fmt.Fprintf(sb, "doppler_polynomial:      0.0 0.0 0.0 0.0  Hz Hz/m Hz/m^2 Hz/m^3\n")
+	fmt.Fprintf(sb, "number_of_state_vectors:      12\n")
+	fmt.Fprintf(sb, "time_of_first_state_vector:   60.000000   s\n")
+	fmt.Fprintf(sb, "state_vector_interval:        10.000000   s\n")
+
+	for ii := 0; ii < 12; ii++ {
+		s, c := math.Sincos(testOmega * (60.0 + 10.0*float64(ii)))
+		fmt.Fprintf(sb, "state_vector_position_%d:  %.4f  0.0  %.4f   m   m   m\n",
+			ii+1, radius*c, radius*s)
+		fmt.Fprintf(sb, "state_vector_velocity_%d:  %.5f  0.0  %.5f   m/s m/s m/s\n",
+			ii+1, -radius*testOmega*s, radius*testOmega*c)
+	}
+
+	s, err := ParseScene(name, params.FromString(sb.String(), ":"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestBaselines(t *testing.T) {
+	stack := NewStack(
+		testScene(t, "20200313", "2020 03 13", 7071100.0),
+		testScene(t, "20200301", "2020 03 01", 7071000.0),
+	)
+
+	if stack[0].Name != "20200301" {
+		t.Fatalf("stack is not sorted by date")
+	}
+
+	table, err := stack.Baselines(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := table.Baselines[1]
+
+	if math.Abs(b.Temporal-12.0) > 1e-6 {
+		t.Errorf("expected temporal baseline of 12 days, got %g", b.Temporal)
+	}
+
+	// a radial offset of 100 m seen from a look angle of about 30 degrees
+	if b.Perpendicular < 40.0 || b.Perpendicular > 60.0 {
+		t.Errorf("unexpected perpendicular baseline %g", b.Perpendicular)
+	}
+
+	if b.Parallel > -75.0 || b.Parallel < -95.0 {
+		t.Errorf("unexpected parallel baseline %g", b.Parallel)
+	}
+
+	if d := math.Hypot(b.Perpendicular, b.Parallel); math.Abs(d-100.0) > 1.0 {
+		t.Errorf("baseline components do not add up to 100 m: %g", d)
+	}
+
+	if b.HeightOfAmbiguity <= 0.0 {
+		t.Errorf("unexpected height of ambiguity %g", b.HeightOfAmbiguity)
+	}
+
+	if table.MasterIndex() != 0 || !table.Baselines[0].Date.Equal(
+		time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC).Add(
+			time.Duration(104.999*float64(time.Second)))) {
+		t.Errorf("unexpected master %+v", table.Baselines[0])
+	}
+
+	buf := &bytes.Buffer{}
+	if err = table.WriteCSV(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := strings.Count(buf.String(), "\n"); lines != 3 {
+		t.Errorf("expected 3 lines of CSV, got %d", lines)
+	}
+
+	buf.Reset()
+	if err = table.WriteSVG(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := strings.Count(buf.String(), "<circle"); n != 2 {
+		t.Errorf("expected 2 scenes in the plot, got %d", n)
+	}
+}
+
+func testStack(t *testing.T) Stack {
+	return NewStack(
+		testScene(t, "20200301", "2020 03 01", 7071000.0),
+		testScene(t, "20200313", "2020 03 13", 7071050.0),
+		testScene(t, "20200325", "2020 03 25", 7071100.0),
+		testScene(t, "20200406", "2020 04 06", 7071150.0),
+		testScene(t, "20200418", "2020 04 18", 7071200.0),
+	)
+}
+
+func TestSelectMaster(t *testing.T) {
+	stack := testStack(t)
+
+	for _, weighting := range []bool{false, true} {
+		c, err := stack.SelectMaster(MasterOptions{CoherenceWeighting: weighting})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(c) != len(stack) {
+			t.Fatalf("expected %d candidates, got %d", len(stack), len(c))
+		}
+
+		best, err := c.Best()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if best.Name != "20200325" || best.Rank != 1 || best.Rationale == "" {
+			t.Errorf("expected the middle scene as master, got %+v", best)
+		}
+
+		for ii := 1; ii < len(c); ii++ {
+			if c[ii].Cost < c[ii-1].Cost {
+				t.Errorf("candidates are not ranked by cost")
+			}
+		}
+	}
+
+	c, err := stack.SelectMaster(MasterOptions{
+		ExcludeMonths: []time.Month{time.March},
+		Candidates:    1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c) != 1 || c[0].Name != "20200406" {
+		t.Errorf("expected the first scene of April as master, got %+v", c)
+	}
+
+	if _, err = stack.SelectMaster(MasterOptions{
+		ExcludeMonths: []time.Month{time.March, time.April},
+	}); err == nil {
+		t.Errorf("expected an error when every scene is excluded")
+	}
+}
@@ -0,0 +1,186 @@
+package baseline
+
+import (
+	"math"
+	"sort"
+)
+
+type point struct {
+	x, y float64
+}
+
+// triangle holds the indices of its vertices and its circumcircle.
+type triangle struct {
+	v      [3]int
+	cx, cy float64
+	r2     float64
+}
+
+func newTriangle(pts []point, a, b, c int) (t triangle) {
+	t.v = [3]int{a, b, c}
+	pa, pb, pc := pts[a], pts[b], pts[c]
+
+	d := 2.0 * (pa.x*(pb.y-pc.y) + pb.x*(pc.y-pa.y) + pc.x*(pa.y-pb.y))
+	if d == 0.0 {
+		// degenerate triangles contain every point so they are replaced
+		t.r2 = math.Inf(1)
+		return
+	}
+
+	a2 := pa.x*pa.x + pa.y*pa.y
+	b2 := pb.x*pb.x + pb.y*pb.y
+	c2 := pc.x*pc.x + pc.y*pc.y
+
+	t.cx = (a2*(pb.y-pc.y) + b2*(pc.y-pa.y) + c2*(pa.y-pb.y)) / d
+	t.cy = (a2*(pc.x-pb.x) + b2*(pa.x-pc.x) + c2*(pb.x-pa.x)) / d
+	t.r2 = (pa.x-t.cx)*(pa.x-t.cx) + (pa.y-t.cy)*(pa.y-t.cy)
+
+	return
+}
+
+func (t triangle) inCircle(p point) bool {
+	dx, dy := p.x-t.cx, p.y-t.cy
+	return dx*dx+dy*dy < t.r2*(1.0+1e-12)
+}
+
+func (t triangle) edges() [3][2]int {
+	return [3][2]int{{t.v[0], t.v[1]}, {t.v[1], t.v[2]}, {t.v[2], t.v[0]}}
+}
+
+func edgeKey(a, b int) [2]int {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]int{a, b}
+}
+
+// collinear returns the points ordered along their common line if all of
+// them lie on one, as the triangulation is undefined in that case.
+func collinear(pts []point) (order []int, ok bool) {
+	// the point furthest from the first one spans the line
+	far, dist := 0, 0.0
+	for ii, p := range pts {
+		if d := math.Hypot(p.x-pts[0].x, p.y-pts[0].y); d > dist {
+			far, dist = ii, d
+		}
+	}
+
+	dx, dy := pts[far].x-pts[0].x, pts[far].y-pts[0].y
+
+	for _, p := range pts {
+		cross := dx*(p.y-pts[0].y) - dy*(p.x-pts[0].x)
+		if math.Abs(cross) > 1e-9*(dist*dist+1e-300) {
+			return nil, false
+		}
+	}
+
+	order = make([]int, len(pts))
+	for ii := range order {
+		order[ii] = ii
+	}
+
+	proj := func(ii int) float64 {
+		return dx*(pts[ii].x-pts[0].x) + dy*(pts[ii].y-pts[0].y)
+	}
+
+	sort.SliceStable(order, func(ii, jj int) bool {
+		return proj(order[ii]) < proj(order[jj])
+	})
+
+	return order, true
+}
+
+/*
+delaunay returns the edges of the Delaunay triangulation of the points
+with the Bowyer-Watson algorithm. The points are inserted one by one into
+a triangulation enclosed by a super triangle; the triangles whose
+circumcircle contains the new point are removed and the hole is
+triangulated from the point. Finally the triangles sharing a vertex with
+the super triangle are dropped.
+*/
+func delaunay(pts []point) (edges [][2]int) {
+	n := len(pts)
+	if n < 2 {
+		return nil
+	}
+
+	if order, ok := collinear(pts); ok {
+		for ii := 1; ii < n; ii++ {
+			edges = append(edges, edgeKey(order[ii-1], order[ii]))
+		}
+		return
+	}
+
+	minX, minY := pts[0].x, pts[0].y
+	maxX, maxY := minX, minY
+
+	for _, p := range pts {
+		minX, maxX = math.Min(minX, p.x), math.Max(maxX, p.x)
+		minY, maxY = math.Min(minY, p.y), math.Max(maxY, p.y)
+	}
+
+	span := math.Max(maxX-minX, maxY-minY)
+	midX, midY := (minX+maxX)/2.0, (minY+maxY)/2.0
+
+	all := append(append([]point{}, pts...),
+		point{midX - 20.0*span, midY - span},
+		point{midX, midY + 20.0*span},
+		point{midX + 20.0*span, midY - span},
+	)
+
+	tris := []triangle{newTriangle(all, n, n+1, n+2)}
+
+	for ii := 0; ii < n; ii++ {
+		p := all[ii]
+		count := make(map[[2]int]int)
+		kept := tris[:0:0]
+		var bad []triangle
+
+		for _, t := range tris {
+			if t.inCircle(p) {
+				bad = append(bad, t)
+				for _, e := range t.edges() {
+					count[edgeKey(e[0], e[1])]++
+				}
+			} else {
+				kept = append(kept, t)
+			}
+		}
+
+		// edges of a single bad triangle bound the hole
+		for _, t := range bad {
+			for _, e := range t.edges() {
+				if count[edgeKey(e[0], e[1])] == 1 {
+					kept = append(kept, newTriangle(all, e[0], e[1], ii))
+				}
+			}
+		}
+
+		tris = kept
+	}
+
+	seen := make(map[[2]int]bool)
+
+	for _, t := range tris {
+		if t.v[0] >= n || t.v[1] >= n || t.v[2] >= n {
+			continue
+		}
+
+		for _, e := range t.edges() {
+			key := edgeKey(e[0], e[1])
+			if !seen[key] {
+				seen[key] = true
+				edges = append(edges, key)
+			}
+		}
+	}
+
+	sort.Slice(edges, func(ii, jj int) bool {
+		if edges[ii][0] != edges[jj][0] {
+			return edges[ii][0] < edges[jj][0]
+		}
+		return edges[ii][1] < edges[jj][1]
+	})
+
+	return
+}
@@ -0,0 +1,224 @@
+package baseline
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	DefaultTemporalScale         = 365.0
+	DefaultPerpendicularScale    = 150.0
+	DefaultTemporalDecorrelation = 40.0
+	DefaultCriticalBaseline      = 5000.0
+)
+
+// MasterOptions configures the selection of the master scene.
+type MasterOptions struct {
+	// Temporal (days) and perpendicular (m) baselines are divided by
+	// these before being summed, so both contribute to the cost.
+	TemporalScale      float64 `json:"temporal_scale"`
+	PerpendicularScale float64 `json:"perpendicular_scale"`
+
+	/*
+		If set, the cost is the summed expected decorrelation of the
+		interferograms with the master instead of the scaled baselines.
+		The expected coherence decays exponentially with the temporal
+		baseline and linearly with the perpendicular baseline.
+	*/
+	CoherenceWeighting bool `json:"coherence_weighting"`
+
+	// Time constant of the temporal decorrelation in days.
+	TemporalDecorrelation float64 `json:"temporal_decorrelation"`
+
+	// Perpendicular baseline of total decorrelation in metres.
+	CriticalBaseline float64 `json:"critical_baseline"`
+
+	// Scenes acquired in these months are not considered as master,
+	// e.g. because of snow cover.
+	ExcludeMonths []time.Month `json:"exclude_months"`
+
+	// Exclude December, January and February.
+	ExcludeWinter bool `json:"exclude_winter"`
+
+	// Number of candidates returned, all of them if not positive.
+	Candidates int `json:"candidates"`
+}
+
+func (m *MasterOptions) Default() {
+	if m.TemporalScale <= 0 {
+		m.TemporalScale = DefaultTemporalScale
+	}
+
+	if m.PerpendicularScale <= 0 {
+		m.PerpendicularScale = DefaultPerpendicularScale
+	}
+
+	if m.TemporalDecorrelation <= 0 {
+		m.TemporalDecorrelation = DefaultTemporalDecorrelation
+	}
+
+	if m.CriticalBaseline <= 0 {
+		m.CriticalBaseline = DefaultCriticalBaseline
+	}
+}
+
+func (m MasterOptions) excluded(t time.Time) bool {
+	month := t.UTC().Month()
+
+	if m.ExcludeWinter {
+		switch month {
+		case time.December, time.January, time.February:
+			return true
+		}
+	}
+
+	for _, ex := range m.ExcludeMonths {
+		if ex == month {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpectedCoherence returns the coherence expected for the baselines.
+func (m MasterOptions) ExpectedCoherence(temporal, perpendicular float64) float64 {
+	spatial := math.Max(0.0, 1.0-math.Abs(perpendicular)/m.CriticalBaseline)
+	return spatial * math.Exp(-math.Abs(temporal)/m.TemporalDecorrelation)
+}
+
+// Candidate is a possible master scene with the figures it was ranked by.
+type Candidate struct {
+	Rank  int       `json:"rank"`
+	Index int       `json:"index"`
+	Name  string    `json:"name"`
+	Date  time.Time `json:"date"`
+	Cost  float64   `json:"cost"`
+
+	// Sums of the absolute baselines to the other scenes.
+	SumTemporal      float64 `json:"sum_temporal"`
+	SumPerpendicular float64 `json:"sum_perpendicular"`
+
+	MaxTemporal      float64 `json:"max_temporal"`
+	MaxPerpendicular float64 `json:"max_perpendicular"`
+
+	// Mean expected coherence of the interferograms with the master.
+	MeanCoherence float64 `json:"mean_coherence"`
+
+	Rationale string `json:"rationale"`
+}
+
+type Candidates []Candidate
+
+// Best returns the highest ranked candidate.
+func (c Candidates) Best() (best Candidate, err error) {
+	if len(c) == 0 {
+		return best, fmt.Errorf("no master candidates are available")
+	}
+	return c[0], nil
+}
+
+func (c Candidates) WriteJSON(w io.Writer) (err error) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(c)
+}
+
+/*
+SelectMaster ranks the scenes of the stack as master candidates by the
+summed baselines of the interferograms they would form with every other
+scene. The baselines are computed once relative to the first scene and
+the ones between other scenes are approximated by their differences.
+*/
+func (s Stack) SelectMaster(opt MasterOptions) (c Candidates, err error) {
+	opt.Default()
+
+	if len(s) == 0 {
+		return nil, fmt.Errorf("stack is empty")
+	}
+
+	table, err := s.Baselines(0)
+	if err != nil {
+		return
+	}
+	bs := table.Baselines
+
+	for ii, m := range bs {
+		if opt.excluded(m.Date) {
+			continue
+		}
+
+		cand := Candidate{Index: ii, Name: m.Name, Date: m.Date}
+		sumCoh, decorr := 0.0, 0.0
+
+		for jj, b := range bs {
+			if ii == jj {
+				continue
+			}
+
+			bt := math.Abs(b.Temporal - m.Temporal)
+			bp := math.Abs(b.Perpendicular - m.Perpendicular)
+
+			cand.SumTemporal += bt
+			cand.SumPerpendicular += bp
+			cand.MaxTemporal = math.Max(cand.MaxTemporal, bt)
+			cand.MaxPerpendicular = math.Max(cand.MaxPerpendicular, bp)
+
+			coh := opt.ExpectedCoherence(bt, bp)
+			sumCoh += coh
+			decorr += 1.0 - coh
+		}
+
+		if n := len(bs) - 1; n > 0 {
+			cand.MeanCoherence = sumCoh / float64(n)
+		} else {
+			cand.MeanCoherence = 1.0
+		}
+
+		if opt.CoherenceWeighting {
+			cand.Cost = decorr
+		} else {
+			cand.Cost = cand.SumTemporal/opt.TemporalScale +
+				cand.SumPerpendicular/opt.PerpendicularScale
+		}
+
+		c = append(c, cand)
+	}
+
+	if len(c) == 0 {
+		return nil, fmt.Errorf("every scene of the stack is excluded from " +
+			"being the master")
+	}
+
+	sort.SliceStable(c, func(ii, jj int) bool {
+		return c[ii].Cost < c[jj].Cost
+	})
+
+	if opt.Candidates > 0 && len(c) > opt.Candidates {
+		c = c[:opt.Candidates]
+	}
+
+	for ii := range c {
+		c[ii].Rank = ii + 1
+		c[ii].Rationale = c[ii].rationale(opt, len(bs))
+	}
+
+	return c, nil
+}
+
+func (c Candidate) rationale(opt MasterOptions, n int) string {
+	cost := "scaled baseline sum"
+	if opt.CoherenceWeighting {
+		cost = "expected decorrelation sum"
+	}
+
+	return fmt.Sprintf("rank %d of %d scenes by %s %.3f: temporal "+
+		"baselines sum to %.0f days (max %.0f), perpendicular baselines "+
+		"sum to %.0f m (max %.0f), mean expected coherence %.2f",
+		c.Rank, n, cost, c.Cost, c.SumTemporal, c.MaxTemporal,
+		c.SumPerpendicular, c.MaxPerpendicular, c.MeanCoherence)
+}
@@ -0,0 +1,355 @@
+package baseline
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	DefaultBridgeTolerance = 30.0
+
+	daysPerYear   = 365.25
+	daysPerSeason = daysPerYear / 4.0
+)
+
+// NetworkOptions selects the pairs of the interferogram network. The
+// network is the union of the pairs of every enabled method.
+type NetworkOptions struct {
+	// Pair every scene with the given number of following scenes.
+	Sequential int `json:"sequential"`
+
+	// Pair every scene with every other scene within the baseline
+	// thresholds.
+	SmallBaseline bool `json:"small_baseline"`
+
+	// Pairs of the Delaunay triangulation in the baseline-time plane.
+	Delaunay bool `json:"delaunay"`
+
+	/*
+		Thresholds of the temporal (days) and perpendicular (m) baselines,
+		not applied if not positive. The temporal threshold does not
+		apply to bridging pairs, as they are meant to be long.
+	*/
+	MaxTemporal      float64 `json:"max_temporal"`
+	MaxPerpendicular float64 `json:"max_perpendicular"`
+
+	// Temporal (days) and perpendicular (m) baselines are divided by
+	// these before the triangulation, so both axes are comparable.
+	TemporalScale      float64 `json:"temporal_scale"`
+	PerpendicularScale float64 `json:"perpendicular_scale"`
+
+	/*
+		Bridging pairs connect every scene with the scene acquired closest
+		to one year (Annual) or one season (Seasonal) later, so that parts
+		of the stack separated by long gaps, e.g. winters, are connected
+		with pairs of similar conditions.
+	*/
+	Annual   bool `json:"annual"`
+	Seasonal bool `json:"seasonal"`
+
+	// Allowed deviation of the bridging pairs from their period in days.
+	BridgeTolerance float64 `json:"bridge_tolerance"`
+}
+
+func (n *NetworkOptions) Default() {
+	if n.TemporalScale <= 0 {
+		n.TemporalScale = DefaultTemporalScale
+	}
+
+	if n.PerpendicularScale <= 0 {
+		n.PerpendicularScale = DefaultPerpendicularScale
+	}
+
+	if n.BridgeTolerance <= 0 {
+		n.BridgeTolerance = DefaultBridgeTolerance
+	}
+}
+
+func (n NetworkOptions) Validate() (err error) {
+	if n.Sequential < 0 {
+		return fmt.Errorf("number of sequential pairs should not be "+
+			"negative, got %d", n.Sequential)
+	}
+
+	if n.Sequential == 0 && !n.SmallBaseline && !n.Delaunay &&
+		!n.Annual && !n.Seasonal {
+		return fmt.Errorf("no pair selection method is enabled")
+	}
+	return nil
+}
+
+// within checks the thresholds, the temporal one only if temporal is set.
+func (n NetworkOptions) within(p Pair, temporal bool) bool {
+	if temporal && n.MaxTemporal > 0 && math.Abs(p.Temporal) > n.MaxTemporal {
+		return false
+	}
+
+	if n.MaxPerpendicular > 0 && math.Abs(p.Perpendicular) > n.MaxPerpendicular {
+		return false
+	}
+	return true
+}
+
+// Pair is an interferogram of the network, the reference scene is
+// acquired before the secondary one.
+type Pair struct {
+	Reference int    `json:"reference"`
+	Secondary int    `json:"secondary"`
+	RefName   string `json:"reference_name"`
+	SecName   string `json:"secondary_name"`
+
+	// Baselines of the secondary scene relative to the reference in days
+	// and metres.
+	Temporal      float64 `json:"temporal"`
+	Perpendicular float64 `json:"perpendicular"`
+}
+
+func newPair(bs []Baseline, ii, jj int) Pair {
+	if bs[jj].Date.Before(bs[ii].Date) {
+		ii, jj = jj, ii
+	}
+
+	return Pair{
+		Reference:     ii,
+		Secondary:     jj,
+		RefName:       bs[ii].Name,
+		SecName:       bs[jj].Name,
+		Temporal:      bs[jj].Temporal - bs[ii].Temporal,
+		Perpendicular: bs[jj].Perpendicular - bs[ii].Perpendicular,
+	}
+}
+
+// Network is the set of interferograms formed from the scenes of a stack.
+type Network struct {
+	Master    string     `json:"master"`
+	Baselines []Baseline `json:"baselines"`
+	Pairs     []Pair     `json:"pairs"`
+
+	// Names of the scenes in the connected subsets of the network. A
+	// connected network has a single subset.
+	Subsets [][]string `json:"subsets"`
+}
+
+/*
+Network designs the interferogram network of the scenes of the table.
+The baselines between scenes are approximated by the differences of
+their baselines relative to the master.
+*/
+func (t Table) Network(opt NetworkOptions) (n Network, err error) {
+	opt.Default()
+
+	if err = opt.Validate(); err != nil {
+		return
+	}
+
+	bs := t.Baselines
+	if len(bs) < 2 {
+		return n, fmt.Errorf("at least 2 scenes are needed for a network, "+
+			"got %d", len(bs))
+	}
+
+	// scenes are paired in time order, independent of the table order
+	order := make([]int, len(bs))
+	for ii := range order {
+		order[ii] = ii
+	}
+
+	sort.SliceStable(order, func(ii, jj int) bool {
+		return bs[order[ii]].Date.Before(bs[order[jj]].Date)
+	})
+
+	seen := make(map[[2]int]bool)
+	add := func(ii, jj int, temporal bool) {
+		if ii == jj {
+			return
+		}
+
+		p := newPair(bs, ii, jj)
+		key := [2]int{p.Reference, p.Secondary}
+
+		if seen[key] || !opt.within(p, temporal) {
+			return
+		}
+
+		seen[key] = true
+		n.Pairs = append(n.Pairs, p)
+	}
+
+	for ii := range order {
+		for jj := ii + 1; jj < len(order) && jj <= ii+opt.Sequential; jj++ {
+			add(order[ii], order[jj], true)
+		}
+	}
+
+	if opt.SmallBaseline {
+		for ii := range bs {
+			for jj := ii + 1; jj < len(bs); jj++ {
+				add(ii, jj, true)
+			}
+		}
+	}
+
+	if opt.Delaunay {
+		pts := make([]point, len(bs))
+		for ii, b := range bs {
+			pts[ii] = point{
+				x: b.Temporal / opt.TemporalScale,
+				y: b.Perpendicular / opt.PerpendicularScale,
+			}
+		}
+
+		for _, e := range delaunay(pts) {
+			add(e[0], e[1], true)
+		}
+	}
+
+	var periods []float64
+	if opt.Annual {
+		periods = append(periods, daysPerYear)
+	}
+
+	if opt.Seasonal {
+		periods = append(periods, daysPerSeason)
+	}
+
+	for _, period := range periods {
+		for _, ii := range order {
+			best, dev := -1, opt.BridgeTolerance
+
+			for _, jj := range order {
+				d := math.Abs(bs[jj].Temporal - bs[ii].Temporal - period)
+				if d <= dev {
+					best, dev = jj, d
+				}
+			}
+
+			if best >= 0 {
+				add(ii, best, false)
+			}
+		}
+	}
+
+	sort.SliceStable(n.Pairs, func(ii, jj int) bool {
+		pi, pj := n.Pairs[ii], n.Pairs[jj]
+		ri, rj := bs[pi.Reference].Date, bs[pj.Reference].Date
+
+		if !ri.Equal(rj) {
+			return ri.Before(rj)
+		}
+		return bs[pi.Secondary].Date.Before(bs[pj.Secondary].Date)
+	})
+
+	n.Master, n.Baselines = t.Master, bs
+	n.Subsets = n.subsets(order)
+
+	return n, nil
+}
+
+// subsets returns the names of the scenes of the connected components,
+// each one in time order.
+func (n Network) subsets(order []int) (s [][]string) {
+	parent := make([]int, len(n.Baselines))
+	for ii := range parent {
+		parent[ii] = ii
+	}
+
+	var find func(int) int
+	find = func(ii int) int {
+		if parent[ii] != ii {
+			parent[ii] = find(parent[ii])
+		}
+		return parent[ii]
+	}
+
+	for _, p := range n.Pairs {
+		parent[find(p.Reference)] = find(p.Secondary)
+	}
+
+	index := make(map[int]int)
+	for _, ii := range order {
+		root := find(ii)
+
+		idx, ok := index[root]
+		if !ok {
+			idx = len(s)
+			index[root] = idx
+			s = append(s, nil)
+		}
+
+		s[idx] = append(s[idx], n.Baselines[ii].Name)
+	}
+
+	return
+}
+
+// Connected reports whether every scene can be reached from every other
+// one through the pairs of the network.
+func (n Network) Connected() bool {
+	return len(n.Subsets) <= 1
+}
+
+// Check returns an error describing the disconnected subsets of the
+// network, nil if the network is connected.
+func (n Network) Check() (err error) {
+	if n.Connected() {
+		return nil
+	}
+
+	sets := make([]string, len(n.Subsets))
+	for ii, s := range n.Subsets {
+		sets[ii] = "[" + strings.Join(s, " ") + "]"
+	}
+
+	return fmt.Errorf("network has %d disconnected subsets: %s",
+		len(n.Subsets), strings.Join(sets, ", "))
+}
+
+/*
+WritePairs writes the pair list of the network, one interferogram on
+every line with the names of the reference and secondary scenes followed
+by the temporal and perpendicular baselines. Batch jobs creating the
+interferograms only need the first two columns.
+*/
+func (n Network) WritePairs(w io.Writer) (err error) {
+	bw := bufio.NewWriter(w)
+
+	for _, p := range n.Pairs {
+		_, err = fmt.Fprintf(bw, "%s %s %.2f %.2f\n", p.RefName, p.SecName,
+			p.Temporal, p.Perpendicular)
+		if err != nil {
+			return
+		}
+	}
+
+	return bw.Flush()
+}
+
+func (n Network) WriteJSON(w io.Writer) (err error) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(n)
+}
+
+// WriteSVG draws the pairs of the network in the baseline-time plane.
+func (n Network) WriteSVG(w io.Writer) (err error) {
+	master := -1
+	pairs := make([][2]int, len(n.Pairs))
+
+	for ii, b := range n.Baselines {
+		if b.Name == n.Master {
+			master = ii
+		}
+	}
+
+	for ii, p := range n.Pairs {
+		pairs[ii] = [2]int{p.Reference, p.Secondary}
+	}
+
+	return writeSVG(w, n.Baselines, master, pairs)
+}
@@ -0,0 +1,215 @@
+package baseline
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testTable creates a table from temporal and perpendicular baselines
+// relative to the first scene.
+func testTable(base [][2]float64) (t Table) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for ii, b := range base {
+		date := start.Add(time.Duration(b[0] * 24 * float64(time.Hour)))
+		t.Baselines = append(t.Baselines, Baseline{
+			Name:          fmt.Sprintf("s%d", ii),
+			Date:          date,
+			Temporal:      b[0],
+			Perpendicular: b[1],
+		})
+	}
+
+	t.Master, t.MasterDate = t.Baselines[0].Name, start
+	return
+}
+
+func pairNames(n Network) (s []string) {
+	for _, p := range n.Pairs {
+		s = append(s, p.RefName+"-"+p.SecName)
+	}
+	return
+}
+
+func TestSequential(t *testing.T) {
+	table := testTable([][2]float64{
+		{0, 0}, {24, 30}, {12, -20}, {36, 10}, {48, 200},
+	})
+
+	n, err := table.Network(NetworkOptions{Sequential: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := strings.Join(pairNames(n), " ")
+	expected := "s0-s2 s0-s1 s2-s1 s2-s3 s1-s3 s1-s4 s3-s4"
+
+	if got != expected {
+		t.Errorf("expected pairs '%s', got '%s'", expected, got)
+	}
+
+	if err = n.Check(); err != nil {
+		t.Error(err)
+	}
+
+	for _, p := range n.Pairs {
+		if p.Temporal <= 0 {
+			t.Errorf("reference of pair %+v is not the earlier scene", p)
+		}
+	}
+
+	// the last scene is too far away in perpendicular baseline
+	n, err = table.Network(NetworkOptions{Sequential: 2, MaxPerpendicular: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n.Connected() || len(n.Subsets) != 2 || len(n.Subsets[1]) != 1 ||
+		n.Subsets[1][0] != "s4" {
+		t.Errorf("expected 's4' to be disconnected, got %v", n.Subsets)
+	}
+
+	if err = n.Check(); err == nil || !strings.Contains(err.Error(), "[s4]") {
+		t.Errorf("expected an error about the disconnected subsets, got %v",
+			err)
+	}
+
+	if _, err = table.Network(NetworkOptions{}); err == nil {
+		t.Errorf("expected an error without pair selection method")
+	}
+}
+
+func TestSmallBaseline(t *testing.T) {
+	table := testTable([][2]float64{
+		{0, 0}, {12, 30}, {24, -20}, {36, 10},
+	})
+
+	n, err := table.Network(NetworkOptions{
+		SmallBaseline:    true,
+		MaxTemporal:      25,
+		MaxPerpendicular: 45,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := strings.Join(pairNames(n), " ")
+	expected := "s0-s1 s0-s2 s1-s3 s2-s3"
+
+	if got != expected {
+		t.Errorf("expected pairs '%s', got '%s'", expected, got)
+	}
+
+	if !n.Connected() {
+		t.Errorf("network should be connected, got %v", n.Subsets)
+	}
+}
+
+func TestDelaunay(t *testing.T) {
+	// a square with a point in the middle, in normalised coordinates
+	pts := []point{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0.5, 0.4}}
+	edges := delaunay(pts)
+
+	// 3n - 3 - h edges for n points with h on the convex hull
+	if len(edges) != 8 {
+		t.Fatalf("expected 8 edges, got %v", edges)
+	}
+
+	// the diagonals cross each other, only the middle point is connected
+	// to the corners
+	for _, e := range edges {
+		if e == [2]int{0, 2} || e == [2]int{1, 3} {
+			t.Errorf("unexpected diagonal %v", e)
+		}
+	}
+
+	// points on a line are connected in their order
+	edges = delaunay([]point{{0, 0}, {2, 2}, {1, 1}})
+	if len(edges) != 2 || edges[0] != [2]int{0, 2} || edges[1] != [2]int{1, 2} {
+		t.Errorf("unexpected edges of collinear points %v", edges)
+	}
+
+	table := testTable([][2]float64{
+		{0, 0}, {12, 100}, {24, -80}, {36, 20}, {48, 150}, {60, -30},
+	})
+
+	n, err := table.Network(NetworkOptions{
+		Delaunay:           true,
+		TemporalScale:      12,
+		PerpendicularScale: 50,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !n.Connected() || len(n.Pairs) < len(n.Baselines)-1 {
+		t.Errorf("unexpected Delaunay network %v", pairNames(n))
+	}
+}
+
+func TestBridging(t *testing.T) {
+	// two summers of acquisitions separated by a winter gap
+	var base [][2]float64
+	for _, start := range []float64{120, 485} {
+		for ii := 0; ii < 4; ii++ {
+			base = append(base, [2]float64{start + 12*float64(ii), 0})
+		}
+	}
+	table := testTable(base)
+
+	n, err := table.Network(NetworkOptions{Sequential: 1, MaxTemporal: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(n.Subsets) != 2 {
+		t.Fatalf("expected 2 subsets without bridging, got %v", n.Subsets)
+	}
+
+	n, err = table.Network(NetworkOptions{
+		Sequential:  1,
+		MaxTemporal: 50,
+		Annual:      true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !n.Connected() {
+		t.Errorf("expected a connected network, got %v", n.Subsets)
+	}
+
+	annual := 0
+	for _, p := range n.Pairs {
+		if p.Temporal > 300 {
+			annual++
+		}
+	}
+
+	if annual != 4 {
+		t.Errorf("expected 4 annual pairs, got %d", annual)
+	}
+
+	buf := &bytes.Buffer{}
+	if err = n.WritePairs(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(n.Pairs) || !strings.HasPrefix(lines[0], "s0 s1 ") {
+		t.Errorf("unexpected pair list:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err = n.WriteSVG(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if c := strings.Count(buf.String(), "<line"); c < len(n.Pairs) {
+		t.Errorf("expected at least %d lines in the plot, got %d",
+			len(n.Pairs), c)
+	}
+}
@@ -0,0 +1,190 @@
+package baseline
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	plotWidth, plotHeight = 900.0, 500.0
+	marginLeft            = 80.0
+	marginRight           = 30.0
+	marginTop             = 30.0
+	marginBottom          = 60.0
+)
+
+// niceTicks returns roughly n round numbered ticks covering [min, max].
+func niceTicks(min, max float64, n int) (ticks []float64) {
+	if max <= min {
+		max = min + 1.0
+	}
+
+	raw := (max - min) / float64(n)
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+
+	step := mag
+	for _, f := range []float64{1, 2, 5, 10} {
+		if step = f * mag; step >= raw {
+			break
+		}
+	}
+
+	for v := math.Floor(min/step) * step; v <= max+step/2; v += step {
+		ticks = append(ticks, v)
+	}
+	return
+}
+
+// svgPlot maps the baseline-time plane into the drawing area.
+type svgPlot struct {
+	t0, t1 time.Time
+	b0, b1 float64
+}
+
+func newSVGPlot(bs []Baseline) (p svgPlot) {
+	p.t0, p.t1 = bs[0].Date, bs[0].Date
+	p.b0, p.b1 = bs[0].Perpendicular, bs[0].Perpendicular
+
+	for _, b := range bs {
+		if b.Date.Before(p.t0) {
+			p.t0 = b.Date
+		}
+
+		if b.Date.After(p.t1) {
+			p.t1 = b.Date
+		}
+
+		p.b0 = math.Min(p.b0, b.Perpendicular)
+		p.b1 = math.Max(p.b1, b.Perpendicular)
+	}
+
+	// padding so that points do not sit on the frame
+	pad := p.t1.Sub(p.t0) / 20
+	if pad == 0 {
+		pad = 24 * time.Hour
+	}
+	p.t0, p.t1 = p.t0.Add(-pad), p.t1.Add(pad)
+
+	ticks := niceTicks(p.b0, p.b1, 6)
+	p.b0, p.b1 = math.Min(p.b0, ticks[0]), math.Max(p.b1, ticks[len(ticks)-1])
+
+	if p.b1-p.b0 < 1.0 {
+		p.b0, p.b1 = p.b0-1.0, p.b1+1.0
+	}
+	return
+}
+
+func (p svgPlot) x(t time.Time) float64 {
+	f := float64(t.Sub(p.t0)) / float64(p.t1.Sub(p.t0))
+	return marginLeft + f*(plotWidth-marginLeft-marginRight)
+}
+
+func (p svgPlot) y(b float64) float64 {
+	f := (b - p.b0) / (p.b1 - p.b0)
+	return plotHeight - marginBottom - f*(plotHeight-marginTop-marginBottom)
+}
+
+func (p svgPlot) axes(sb *strings.Builder) {
+	left, right := marginLeft, plotWidth-marginRight
+	top, bottom := marginTop, plotHeight-marginBottom
+
+	fmt.Fprintf(sb, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" `+
+		`fill="none" stroke="black"/>`+"\n", left, top, right-left,
+		bottom-top)
+
+	for _, v := range niceTicks(p.b0, p.b1, 6) {
+		if v < p.b0 || v > p.b1 {
+			continue
+		}
+
+		y := p.y(v)
+		fmt.Fprintf(sb, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" `+
+			`stroke="#dddddd"/>`+"\n", left, y, right, y)
+		fmt.Fprintf(sb, `<text x="%.1f" y="%.1f" text-anchor="end" `+
+			`font-size="12">%g</text>`+"\n", left-6, y+4, v)
+	}
+
+	// monthly ticks, labelled at most about ten times
+	months := int(p.t1.Sub(p.t0).Hours()/(24*30)) + 1
+	every := months/10 + 1
+
+	y, m, _ := p.t0.Date()
+	t := time.Date(y, m+1, 1, 0, 0, 0, 0, time.UTC)
+
+	for ii := 0; t.Before(p.t1); ii, t = ii+1, t.AddDate(0, 1, 0) {
+		x := p.x(t)
+		fmt.Fprintf(sb, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" `+
+			`stroke="black"/>`+"\n", x, bottom, x, bottom+5)
+
+		if ii%every == 0 {
+			fmt.Fprintf(sb, `<text x="%.1f" y="%.1f" text-anchor="middle" `+
+				`font-size="12">%s</text>`+"\n", x, bottom+20,
+				t.Format("2006-01"))
+		}
+	}
+
+	fmt.Fprintf(sb, `<text x="%.1f" y="%.1f" text-anchor="middle" `+
+		`font-size="14">Acquisition date</text>`+"\n",
+		(left+right)/2, plotHeight-15)
+	fmt.Fprintf(sb, `<text x="20" y="%.1f" text-anchor="middle" `+
+		`font-size="14" transform="rotate(-90 20 %.1f)">`+
+		`Perpendicular baseline [m]</text>`+"\n",
+		(top+bottom)/2, (top+bottom)/2)
+}
+
+/*
+writeSVG draws the scenes in the baseline-time plane. Pairs given as
+indices of the baselines are connected with lines and the scene with the
+index master is highlighted, if it is not negative.
+*/
+func writeSVG(w io.Writer, bs []Baseline, master int, pairs [][2]int) (err error) {
+	if len(bs) == 0 {
+		return fmt.Errorf("no scenes to plot")
+	}
+
+	p := newSVGPlot(bs)
+	sb := &strings.Builder{}
+
+	fmt.Fprintf(sb, `<svg xmlns="http://www.w3.org/2000/svg" `+
+		`width="%.0f" height="%.0f" font-family="sans-serif">`+"\n",
+		plotWidth, plotHeight)
+	fmt.Fprintf(sb, `<rect width="100%%" height="100%%" fill="white"/>`+"\n")
+
+	p.axes(sb)
+
+	for _, pair := range pairs {
+		b1, b2 := bs[pair[0]], bs[pair[1]]
+		fmt.Fprintf(sb, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" `+
+			`stroke="steelblue" stroke-width="1"/>`+"\n",
+			p.x(b1.Date), p.y(b1.Perpendicular),
+			p.x(b2.Date), p.y(b2.Perpendicular))
+	}
+
+	for ii, b := range bs {
+		color, radius := "black", 4.0
+		if ii == master {
+			color, radius = "crimson", 6.0
+		}
+
+		fmt.Fprintf(sb, `<circle cx="%.1f" cy="%.1f" r="%.1f" fill="%s">`+
+			`<title>%s %s %.1f m</title></circle>`+"\n",
+			p.x(b.Date), p.y(b.Perpendicular), radius, color,
+			html.EscapeString(b.Name),
+			b.Date.Format("2006-01-02"), b.Perpendicular)
+	}
+
+	sb.WriteString("</svg>\n")
+
+	_, err = io.WriteString(w, sb.String())
+	return
+}
+
+// WriteSVG draws the baseline-time plot of the stack with the master
+// highlighted.
+func (t Table) WriteSVG(w io.Writer) (err error) {
+	return writeSVG(w, t.Baselines, t.MasterIndex(), nil)
+}
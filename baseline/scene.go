@@ -0,0 +1,150 @@
+/*
+Package baseline computes the interferometric baselines of a stack of
+SAR images from their orbit state vectors, helps selecting the reference
+image of the stack and designs interferogram networks.
+*/
+package baseline
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bozso/gotoolbox/errors"
+	"github.com/bozso/gotoolbox/path"
+
+	"github.com/bozso/gomma/orbit"
+	"github.com/bozso/gomma/utils/params"
+)
+
+// Scene is an image of the stack with its acquisition geometry.
+type Scene struct {
+	Name string `json:"name"`
+
+	// Time of the middle of the acquisition.
+	Date time.Time `json:"date"`
+
+	SAR orbit.SAR `json:"-"`
+}
+
+// parseDate reads the day of the acquisition from the "date" parameter.
+func parseDate(p params.Retreiver) (t time.Time, err error) {
+	s, err := p.Param("date:")
+	if err != nil {
+		return
+	}
+
+	fields := strings.Fields(s)
+	if len(fields) < 3 {
+		return t, fmt.Errorf("invalid date '%s'", s)
+	}
+
+	var ymd [3]int
+	for ii := range ymd {
+		// days and months may be written as floats in some versions
+		f, err := strconv.ParseFloat(fields[ii], 64)
+		if err != nil {
+			return t, errors.WrapFmt(err, "invalid date '%s'", s)
+		}
+		ymd[ii] = int(f)
+	}
+
+	return time.Date(ymd[0], time.Month(ymd[1]), ymd[2], 0, 0, 0, 0,
+		time.UTC), nil
+}
+
+func ParseScene(name string, p params.Retreiver) (s Scene, err error) {
+	s.Name = name
+
+	if s.SAR, err = orbit.ParseSAR(p); err != nil {
+		return
+	}
+
+	day, err := parseDate(p)
+	if err != nil {
+		return
+	}
+
+	center := s.SAR.CenterTime()
+	s.Date = day.Add(time.Duration(math.Round(center*1e6)) * time.Microsecond)
+
+	return s, nil
+}
+
+// ReadScene reads the scene from a SLC or MLI parameter file.
+func ReadScene(par path.ValidFile) (s Scene, err error) {
+	p, err := params.FromFile(par, ":")
+	if err != nil {
+		return
+	}
+
+	name := strings.TrimSuffix(par.Base().String(), ".par")
+
+	if s, err = ParseScene(name, p); err != nil {
+		err = errors.WrapFmt(err, "failed to parse parameter file '%s'", par)
+	}
+	return
+}
+
+/*
+ReadTab reads the scene from a SLC_TAB file, like the ones of Sentinel-1
+stacks. The geometry is taken from the parameter file of the first
+subswath, as the orbit is common to all of them.
+*/
+func ReadTab(tab path.ValidFile) (s Scene, err error) {
+	scan, err := tab.Scanner()
+	if err != nil {
+		return
+	}
+	defer scan.Close()
+
+	for scan.Scan() {
+		fields := strings.Fields(scan.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		par, err := path.New(fields[1]).ToValidFile()
+		if err != nil {
+			return s, err
+		}
+
+		if s, err = ReadScene(par); err != nil {
+			return s, err
+		}
+
+		s.Name = strings.TrimSuffix(tab.Base().String(), ".SLC_TAB")
+		return s, nil
+	}
+
+	return s, fmt.Errorf("no parameter file found in '%s'", tab)
+}
+
+// Stack is a list of scenes sorted by their dates.
+type Stack []Scene
+
+func NewStack(scenes ...Scene) (s Stack) {
+	s = append(Stack{}, scenes...)
+
+	sort.SliceStable(s, func(ii, jj int) bool {
+		return s[ii].Date.Before(s[jj].Date)
+	})
+	return
+}
+
+// Index returns the index of the scene acquired on the day of t, -1 if
+// there is no such scene.
+func (s Stack) Index(t time.Time) int {
+	y, m, d := t.UTC().Date()
+
+	for ii, sc := range s {
+		yy, mm, dd := sc.Date.UTC().Date()
+		if y == yy && m == mm && d == dd {
+			return ii
+		}
+	}
+	return -1
+}
@@ -0,0 +1,147 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/bozso/gotoolbox/errors"
+)
+
+var exeDirectories = [...]string{"bin", "scripts"}
+
+/*
+Command is a GAMMA program of the command registry. The executable is
+looked up in the bin and scripts directories of the configured GAMMA
+modules (Settings.Path and Settings.Modules) when the command is first
+called, so packages can declare their commands at initialization even if
+GAMMA is not installed. It is safe for concurrent use.
+*/
+type Command struct {
+	*command
+}
+
+type command struct {
+	names []string
+
+	once sync.Once
+	exe  string
+	err  error
+}
+
+// NewCommand creates a command running the executable at exe.
+func NewCommand(exe string) (c Command) {
+	c = Select(filepath.Base(exe))
+	c.once.Do(func() { c.exe = exe })
+	return
+}
+
+// Must returns the GAMMA program called name.
+func Must(name string) (c Command) {
+	return Select(name)
+}
+
+// Select returns the first one of the GAMMA programs that is available.
+func Select(names ...string) (c Command) {
+	return Command{&command{names: names}}
+}
+
+func (c Command) resolve() (err error) {
+	c.once.Do(func() {
+		for _, name := range c.names {
+			for _, module := range Settings.Modules {
+				for _, dir := range exeDirectories {
+					exe := filepath.Join(Settings.Path, module, dir, name)
+
+					if fi, err := os.Stat(exe); err == nil && fi.Mode().IsRegular() {
+						c.exe = exe
+						return
+					}
+				}
+			}
+		}
+
+		c.err = fmt.Errorf("none of the GAMMA executables %v were found in "+
+			"modules %v of directory '%s'", c.names, Settings.Modules,
+			Settings.Path)
+	})
+
+	return c.err
+}
+
+func (c Command) String() string {
+	if len(c.exe) > 0 {
+		return c.exe
+	}
+	return strings.Join(c.names, "|")
+}
+
+// Call runs the program in the current directory and returns its
+// standard output.
+func (c Command) Call(args ...interface{}) (out string, err error) {
+	return c.CallIn("", args...)
+}
+
+/*
+CallIn runs the program with dir as its working directory, so the
+temporary files of the program are created there. Relative paths among
+the arguments are interpreted relative to dir. The current directory is
+used if dir is empty.
+*/
+func (c Command) CallIn(dir string, args ...interface{}) (out string, err error) {
+	if err = c.resolve(); err != nil {
+		return
+	}
+
+	sargs := make([]string, len(args))
+	for ii, arg := range args {
+		sargs[ii] = formatArg(arg)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(c.exe, sargs...)
+	cmd.Dir, cmd.Stdout, cmd.Stderr = dir, &stdout, &stderr
+
+	if err = cmd.Run(); err != nil {
+		err = errors.WrapFmt(err, "execution of '%s %s' failed\nstderr: %s",
+			c.exe, strings.Join(sargs, " "), stderr.String())
+		return
+	}
+
+	return stdout.String(), nil
+}
+
+// pathLike is implemented by the path types of gotoolbox.
+type pathLike interface {
+	GetPath() string
+}
+
+// formatArg converts an argument to its command line representation.
+// Missing (nil) arguments are replaced with "-" as GAMMA programs expect.
+func formatArg(arg interface{}) (s string) {
+	if arg == nil {
+		return "-"
+	}
+
+	if v := reflect.ValueOf(arg); v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "-"
+		}
+		arg = v.Elem().Interface()
+	}
+
+	if p, ok := arg.(pathLike); ok {
+		if s = p.GetPath(); len(s) == 0 {
+			return "-"
+		}
+		return s
+	}
+
+	return fmt.Sprint(arg)
+}
@@ -1,9 +1,10 @@
 package common
 
 import (
-	"github.com/bozso/gotoolbox/errors"
-
+	"encoding/json"
 	"strings"
+
+	"github.com/bozso/gotoolbox/errors"
 )
 
 type Pol int
@@ -53,3 +54,53 @@ func (p Pol) String() (s string) {
 	}
 	return
 }
+
+func (p Pol) MarshalJSON() (b []byte, err error) {
+	s := p.String()
+	if p == AllPolarisation {
+		s = "all"
+	}
+
+	return json.Marshal(s)
+}
+
+func (p *Pol) UnmarshalJSON(b []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(b, &s); err != nil {
+		return
+	}
+
+	return p.Set(s)
+}
+
+// IsCross reports whether the polarization is a cross-polarized one.
+func (p Pol) IsCross() (b bool) {
+	return p == VH || p == HV
+}
+
+/*
+ParseProductPol parses the polarization code of a Sentinel-1 product
+name, e.g. "DV" for dual VV+VH or "SH" for single HH acquisitions. The
+co-polarized channel is always the first one.
+*/
+func ParseProductPol(s string) (pols []Pol, err error) {
+	const mode errors.Mode = "product polarization"
+
+	switch strings.ToUpper(s) {
+	case "SV", "VV":
+		pols = []Pol{VV}
+	case "SH", "HH":
+		pols = []Pol{HH}
+	case "DV":
+		pols = []Pol{VV, VH}
+	case "DH":
+		pols = []Pol{HH, HV}
+	case "VH":
+		pols = []Pol{VH}
+	case "HV":
+		pols = []Pol{HV}
+	default:
+		err = mode.Error(s)
+	}
+	return
+}
@@ -1,6 +1,8 @@
 package date
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -18,9 +20,16 @@ type OptionalTime struct {
 	time.Time
 }
 
+/*
+Parse parses the time from a JSON string. An empty string or null leaves
+the time unset.
+*/
 func (ot *OptionalTime) Parse(b []byte, p ParseFmt) (err error) {
-	if len(b) == 0 {
-		ot.set = false
+	b = bytes.Trim(b, "\"")
+	ot.set = false
+
+	if len(b) == 0 || string(b) == "null" {
+		return nil
 	}
 
 	ot.Time, err = p.Parse(string(b))
@@ -34,22 +43,49 @@ func (ot OptionalTime) IsSet() (b bool) {
 	return ot.set
 }
 
+// Marshal formats the time as a JSON string, null if it is not set.
+func (ot OptionalTime) Marshal(p ParseFmt) (b []byte, err error) {
+	if !ot.set {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(p.Format(ot.Time))
+}
+
 type ShortTime struct {
 	OptionalTime
 }
 
+func NewShortTime(t time.Time) (st ShortTime) {
+	st.set, st.Time = true, t
+	return
+}
+
 func (st *ShortTime) UnmarshalJSON(b []byte) (err error) {
 	return st.OptionalTime.Parse(b, Short)
 }
 
+func (st ShortTime) MarshalJSON() (b []byte, err error) {
+	return st.OptionalTime.Marshal(Short)
+}
+
 type LongTime struct {
 	OptionalTime
 }
 
+func NewLongTime(t time.Time) (lt LongTime) {
+	lt.set, lt.Time = true, t
+	return
+}
+
 func (lt *LongTime) UnmarshalJSON(b []byte) (err error) {
 	return lt.OptionalTime.Parse(b, Long)
 }
 
+func (lt LongTime) MarshalJSON() (b []byte, err error) {
+	return lt.OptionalTime.Marshal(Long)
+}
+
 type ParseFmt string
 
 const (
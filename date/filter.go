@@ -0,0 +1,394 @@
+package date
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/bozso/gotoolbox/errors"
+)
+
+type allOf []Checker
+
+// And returns a checker that passes if every one of the checkers passes.
+func And(ch ...Checker) Checker {
+	return allOf(ch)
+}
+
+func (a allOf) In(t time.Time) (b bool) {
+	for _, ch := range a {
+		if !ch.In(t) {
+			return false
+		}
+	}
+	return true
+}
+
+type anyOf []Checker
+
+// Or returns a checker that passes if any of the checkers passes.
+func Or(ch ...Checker) Checker {
+	return anyOf(ch)
+}
+
+func (a anyOf) In(t time.Time) (b bool) {
+	for _, ch := range a {
+		if ch.In(t) {
+			return true
+		}
+	}
+	return false
+}
+
+type negated struct {
+	Checker
+}
+
+// Not returns a checker that passes if the checker fails.
+func Not(ch Checker) Checker {
+	return negated{ch}
+}
+
+func (n negated) In(t time.Time) (b bool) {
+	return !n.Checker.In(t)
+}
+
+// day returns the day of t in UTC.
+func day(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+/*
+Interval selects the days between Start and Stop. The bounds are
+included unless they are open and an unset bound is unlimited. Days are
+compared, so a closed Stop includes the acquisitions of the whole day.
+*/
+type Interval struct {
+	Start     ShortTime `json:"start"`
+	Stop      ShortTime `json:"stop"`
+	OpenStart bool      `json:"open_start"`
+	OpenStop  bool      `json:"open_stop"`
+}
+
+func (iv Interval) Validate() (err error) {
+	if iv.Start.IsSet() && iv.Stop.IsSet() &&
+		day(iv.Stop.Time).Before(day(iv.Start.Time)) {
+		return fmt.Errorf("start of the interval (%s) is after its stop (%s)",
+			Short.Format(iv.Start.Time), Short.Format(iv.Stop.Time))
+	}
+	return nil
+}
+
+func (iv Interval) In(t time.Time) (b bool) {
+	d := day(t)
+
+	if iv.Start.IsSet() {
+		start := day(iv.Start.Time)
+
+		if d.Before(start) || (iv.OpenStart && d.Equal(start)) {
+			return false
+		}
+	}
+
+	if iv.Stop.IsSet() {
+		stop := day(iv.Stop.Time)
+
+		if d.After(stop) || (iv.OpenStop && d.Equal(stop)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Months selects the days in the given months of any year.
+type Months []time.Month
+
+func (m Months) Validate() (err error) {
+	for _, month := range m {
+		if month < time.January || month > time.December {
+			return fmt.Errorf("invalid month %d", month)
+		}
+	}
+	return nil
+}
+
+func (m Months) In(t time.Time) (b bool) {
+	month := t.UTC().Month()
+
+	for _, mm := range m {
+		if mm == month {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Season is a meteorological season of the northern hemisphere, each one
+consisting of three whole months, e.g. winter is December, January and
+February.
+*/
+type Season int
+
+const (
+	Winter Season = iota
+	Spring
+	Summer
+	Autumn
+)
+
+func (s *Season) Set(str string) (err error) {
+	const mode errors.Mode = "season"
+
+	switch strings.ToLower(str) {
+	case "winter":
+		*s = Winter
+	case "spring":
+		*s = Spring
+	case "summer":
+		*s = Summer
+	case "autumn", "fall":
+		*s = Autumn
+	default:
+		err = mode.Error(str)
+	}
+
+	return
+}
+
+func (s Season) String() (str string) {
+	switch s {
+	case Winter:
+		str = "winter"
+	case Spring:
+		str = "spring"
+	case Summer:
+		str = "summer"
+	case Autumn:
+		str = "autumn"
+	default:
+		str = "unknown"
+	}
+	return
+}
+
+func (s Season) MarshalJSON() (b []byte, err error) {
+	return json.Marshal(s.String())
+}
+
+func (s *Season) UnmarshalJSON(b []byte) (err error) {
+	var str string
+	if err = json.Unmarshal(b, &str); err != nil {
+		return
+	}
+
+	return s.Set(str)
+}
+
+// Months returns the months of the season.
+func (s Season) Months() (m Months) {
+	// winter starts in December
+	first := time.Month((int(s)*3+11)%12 + 1)
+
+	for ii := 0; ii < 3; ii++ {
+		m = append(m, time.Month((int(first)+ii-1)%12+1))
+	}
+	return
+}
+
+func (s Season) In(t time.Time) (b bool) {
+	return s.Months().In(t)
+}
+
+type Seasons []Season
+
+func (s Seasons) In(t time.Time) (b bool) {
+	for _, season := range s {
+		if season.In(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dates selects the listed days.
+type Dates []ShortTime
+
+func (d Dates) In(t time.Time) (b bool) {
+	dt := day(t)
+
+	for _, date := range d {
+		if date.IsSet() && day(date.Time).Equal(dt) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Cycle selects the days repeating with the given period from Start, e.g.
+the acquisitions of a single satellite with a 12 day repeat cycle. Days
+within Tolerance of the cycle are also selected.
+*/
+type Cycle struct {
+	Start     ShortTime `json:"start"`
+	Days      int       `json:"days"`
+	Tolerance int       `json:"tolerance"`
+}
+
+func (c Cycle) Validate() (err error) {
+	if !c.Start.IsSet() {
+		return fmt.Errorf("start of the repeat cycle is not set")
+	}
+
+	if c.Days <= 0 {
+		return fmt.Errorf("length of the repeat cycle should be positive, "+
+			"got %d days", c.Days)
+	}
+
+	if c.Tolerance < 0 {
+		return fmt.Errorf("tolerance of the repeat cycle should not be "+
+			"negative, got %d days", c.Tolerance)
+	}
+	return nil
+}
+
+func (c Cycle) In(t time.Time) (b bool) {
+	if c.Days <= 0 || !c.Start.IsSet() {
+		return false
+	}
+
+	days := int(math.Round(day(t).Sub(day(c.Start.Time)).Hours() / 24.0))
+
+	off := days % c.Days
+	if off < 0 {
+		off += c.Days
+	}
+
+	if rest := c.Days - off; rest < off {
+		off = rest
+	}
+
+	return off <= c.Tolerance
+}
+
+/*
+Filter is a JSON serialisable date filter. A date passes the filter if
+it satisfies every criterion that is set, so an empty filter passes
+every date. Filters are combined with And, Or and Not, e.g.
+
+	{
+	    "interval": {"start": "20190101", "stop": "20201231"},
+	    "not": {"seasons": ["winter"]},
+	    "exclude": ["20190613"]
+	}
+
+selects the dates of two years except the winters and a single day.
+*/
+type Filter struct {
+	And []Filter `json:"and,omitempty"`
+	Or  []Filter `json:"or,omitempty"`
+	Not *Filter  `json:"not,omitempty"`
+
+	Interval *Interval `json:"interval,omitempty"`
+	Months   Months    `json:"months,omitempty"`
+	Seasons  Seasons   `json:"seasons,omitempty"`
+
+	// Only the listed dates pass if Include is not empty, the dates of
+	// Exclude never pass.
+	Include Dates `json:"include,omitempty"`
+	Exclude Dates `json:"exclude,omitempty"`
+
+	Cycle *Cycle `json:"cycle,omitempty"`
+}
+
+func (f Filter) Validate() (err error) {
+	for _, sub := range append(append([]Filter{}, f.And...), f.Or...) {
+		if err = sub.Validate(); err != nil {
+			return
+		}
+	}
+
+	if f.Not != nil {
+		if err = f.Not.Validate(); err != nil {
+			return
+		}
+	}
+
+	if f.Interval != nil {
+		if err = f.Interval.Validate(); err != nil {
+			return
+		}
+	}
+
+	if err = f.Months.Validate(); err != nil {
+		return
+	}
+
+	if f.Cycle != nil {
+		if err = f.Cycle.Validate(); err != nil {
+			return
+		}
+	}
+
+	return nil
+}
+
+// Checker returns the checker equivalent to the filter.
+func (f Filter) Checker() Checker {
+	var ch allOf
+
+	if len(f.And) > 0 {
+		and := make(allOf, len(f.And))
+		for ii, sub := range f.And {
+			and[ii] = sub.Checker()
+		}
+		ch = append(ch, and)
+	}
+
+	if len(f.Or) > 0 {
+		or := make(anyOf, len(f.Or))
+		for ii, sub := range f.Or {
+			or[ii] = sub.Checker()
+		}
+		ch = append(ch, or)
+	}
+
+	if f.Not != nil {
+		ch = append(ch, Not(f.Not.Checker()))
+	}
+
+	if f.Interval != nil {
+		ch = append(ch, *f.Interval)
+	}
+
+	if len(f.Months) > 0 {
+		ch = append(ch, f.Months)
+	}
+
+	if len(f.Seasons) > 0 {
+		ch = append(ch, f.Seasons)
+	}
+
+	if len(f.Include) > 0 {
+		ch = append(ch, f.Include)
+	}
+
+	if len(f.Exclude) > 0 {
+		ch = append(ch, Not(f.Exclude))
+	}
+
+	if f.Cycle != nil {
+		ch = append(ch, *f.Cycle)
+	}
+
+	return ch
+}
+
+func (f Filter) In(t time.Time) (b bool) {
+	return f.Checker().In(t)
+}
@@ -0,0 +1,176 @@
+package date
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func mustShort(t *testing.T, s string) time.Time {
+	d, err := Short.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+type filterCase struct {
+	date     string
+	expected bool
+}
+
+func testChecker(t *testing.T, name string, ch Checker, cases []filterCase) {
+	for _, c := range cases {
+		// acquisitions are in the middle of the day
+		d := mustShort(t, c.date).Add(17 * time.Hour)
+
+		if got := ch.In(d); got != c.expected {
+			t.Errorf("%s: expected %v for %s, got %v", name, c.expected,
+				c.date, got)
+		}
+	}
+}
+
+func TestRangeAndLimits(t *testing.T) {
+	r := NewRange(mustShort(t, "20200101"), mustShort(t, "20200201"))
+
+	testChecker(t, "range", r, []filterCase{
+		{"20191231", false},
+		{"20200115", true},
+		{"20200201", false},
+	})
+
+	// a minimum and a maximum together select an interval
+	ch := NewCheckers()
+	ch.Append(Min.New(mustShort(t, "20200101")))
+	ch.Append(Max.New(mustShort(t, "20200201")))
+
+	testChecker(t, "limits", ch, []filterCase{
+		{"20191231", false},
+		{"20200101", true},
+		{"20200115", true},
+		{"20200202", false},
+	})
+}
+
+func TestCombinators(t *testing.T) {
+	summer := Summer.Months()
+	march := Months{time.March}
+
+	testChecker(t, "or", Or(summer, march), []filterCase{
+		{"20200315", true},
+		{"20200415", false},
+		{"20200715", true},
+	})
+
+	testChecker(t, "and not", And(Winter, Not(Months{time.January})),
+		[]filterCase{
+			{"20191215", true},
+			{"20200115", false},
+			{"20200215", true},
+			{"20200315", false},
+		})
+}
+
+func TestInterval(t *testing.T) {
+	iv := Interval{
+		Start: NewShortTime(mustShort(t, "20200101")),
+		Stop:  NewShortTime(mustShort(t, "20200131")),
+	}
+
+	testChecker(t, "closed", iv, []filterCase{
+		{"20191231", false},
+		{"20200101", true},
+		{"20200131", true},
+		{"20200201", false},
+	})
+
+	iv.OpenStart, iv.OpenStop = true, true
+
+	testChecker(t, "open", iv, []filterCase{
+		{"20200101", false},
+		{"20200102", true},
+		{"20200131", false},
+	})
+
+	half := Interval{Start: NewShortTime(mustShort(t, "20200101"))}
+	testChecker(t, "unbounded", half, []filterCase{
+		{"20191231", false},
+		{"20300101", true},
+	})
+
+	iv.Start, iv.Stop = iv.Stop, iv.Start
+	if err := iv.Validate(); err == nil {
+		t.Errorf("expected an error for an inverted interval")
+	}
+}
+
+func TestCycle(t *testing.T) {
+	c := Cycle{Start: NewShortTime(mustShort(t, "20200101")), Days: 12}
+
+	testChecker(t, "cycle", c, []filterCase{
+		{"20200101", true},
+		{"20200113", true},
+		{"20200114", false},
+		{"20191220", true},
+	})
+
+	c.Tolerance = 1
+	testChecker(t, "cycle tolerance", c, []filterCase{
+		{"20200114", true},
+		{"20200112", true},
+		{"20200115", false},
+	})
+
+	if err := (Cycle{Days: 12}).Validate(); err == nil {
+		t.Errorf("expected an error for a cycle without start")
+	}
+}
+
+func TestFilterJSON(t *testing.T) {
+	const js = `{
+		"interval": {"start": "20190101", "stop": "20201231"},
+		"not": {"seasons": ["winter"]},
+		"or": [{"months": [3, 4, 5]}, {"include": ["20190815"]}],
+		"exclude": ["20190413"]
+	}`
+
+	var f Filter
+	if err := json.Unmarshal([]byte(js), &f); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []filterCase{
+		{"20190412", true},
+		{"20190413", false},
+		{"20190815", true},
+		{"20190816", false},
+		{"20190301", true},
+		{"20210301", false},
+		{"20200201", false},
+	}
+	testChecker(t, "filter", f, cases)
+
+	b, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var back Filter
+	if err = json.Unmarshal(b, &back); err != nil {
+		t.Fatalf("failed to parse %s: %s", b, err)
+	}
+	testChecker(t, "filter round trip", back, cases)
+
+	var empty Filter
+	testChecker(t, "empty", empty, []filterCase{{"20200101", true}})
+
+	var bad Filter
+	if err = json.Unmarshal([]byte(`{"seasons": ["monsoon"]}`), &bad); err == nil {
+		t.Errorf("expected an error for an unknown season")
+	}
+}
@@ -38,8 +38,9 @@ func (df ParseFmt) NewRange(start, stop string) (d Range, err error) {
 	return d, nil
 }
 
+// In checks whether t falls within the closed interval of the range.
 func (d Range) In(t time.Time) (b bool) {
-	return d.start.After(t) && d.stop.Before(t)
+	return !t.Before(d.start) && !t.After(d.stop)
 }
 
 func (d Range) Start() time.Time {
@@ -71,12 +72,13 @@ type Limit struct {
 	minOrMax MinOrMax
 }
 
+// In checks whether t is not before a Min or not after a Max limit.
 func (l Limit) In(t time.Time) (b bool) {
 	switch l.minOrMax {
 	case Min:
-		b = l.Time.After(t)
+		b = !t.Before(l.Time)
 	case Max:
-		b = l.Time.Before(t)
+		b = !t.After(l.Time)
 	}
 	return
 }
@@ -98,18 +100,14 @@ func (c *Checkers) Append(ch Checker) {
 	c.checkers = append(c.checkers, ch)
 }
 
+// In checks whether t passes every checker, true if there are none.
 func (c Checkers) In(t time.Time) (b bool) {
-	if len(c.checkers) == 0 {
-		return true
-	}
-
 	for _, checker := range c.checkers {
-		b = checker.In(t)
-		if b {
-			break
+		if !checker.In(t) {
+			return false
 		}
 	}
-	return
+	return true
 }
 
 var noCheck NoChecker
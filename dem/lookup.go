@@ -2,9 +2,11 @@ package dem
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/bozso/gomma/common"
 	"github.com/bozso/gomma/data"
+	"github.com/bozso/gomma/orbit"
 	"github.com/bozso/gomma/plot"
 	"github.com/bozso/gomma/utils/params"
 
@@ -83,6 +85,29 @@ func ToRadar(ll common.LatLon, mpar, hgt, diffPar string) (ra common.RngAzi, err
 	return ra, nil
 }
 
+/*
+ToRadarNative returns the pixel of the point like ToRadar, but it solves
+the range-Doppler equations with the state vectors of the parameter file
+instead of calling coord_to_sarpix. The offsets of a diff_par are not
+applied. Use orbit.SAR.ToRadarBatch directly for transforming many
+points.
+*/
+func ToRadarNative(ll common.LatLon, mpar path.ValidFile, hgt float64) (ra common.RngAzi, err error) {
+	sar, err := orbit.ReadSAR(mpar)
+	if err != nil {
+		return
+	}
+
+	r, err := sar.ToRadar(orbit.Geodetic{Lat: ll.Lat, Lon: ll.Lon, Height: hgt})
+	if err != nil {
+		err = errors.WrapFmt(err, "failed to retreive radar coordinates")
+		return
+	}
+
+	ra.Rng, ra.Azi = int(math.Round(r.Rng)), int(math.Round(r.Azi))
+	return ra, nil
+}
+
 type InterpolationMode int
 
 const (
@@ -1,22 +1,69 @@
 package download
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/bozso/gomma/geometry"
 )
 
+// Query selects the products to be searched for in the catalogue.
 type Query struct {
-	AOI geometry.AreaOfInterest
+	AOI geometry.AreaOfInterest `json:"aoi"`
+
+	// Products with a sensing start inside [Start, Stop) are selected.
+	// Zero values leave the range open.
+	Start time.Time `json:"start"`
+	Stop  time.Time `json:"stop"`
+
+	// Product type as used by the catalogue, e.g. "IW_SLC__1S".
+	ProductType string `json:"product_type"`
+
+	// Polarization channels as used by the catalogue, e.g. "VV&VH".
+	Polarization string `json:"polarization"`
+
+	// Relative orbit number, 0 selects every orbit.
+	RelativeOrbit int `json:"relative_orbit"`
 }
 
+func (q Query) Validate() (err error) {
+	if !q.Start.IsZero() && !q.Stop.IsZero() && !q.Start.Before(q.Stop) {
+		return fmt.Errorf("start of the query (%s) is not before its stop "+
+			"(%s)", q.Start, q.Stop)
+	}
+
+	if q.RelativeOrbit < 0 || q.RelativeOrbit > 175 {
+		return fmt.Errorf("invalid relative orbit %d", q.RelativeOrbit)
+	}
+
+	return nil
+}
+
+// SceneMeta describes a product found in the catalogue.
 type SceneMeta struct {
-	Date time.Time
-	URL  string
+	ID   string    `json:"id"`
+	Name string    `json:"name"`
+	Date time.Time `json:"date"`
+	URL  string    `json:"url"`
+
+	// Size of the product in bytes, 0 if unknown.
+	Size int64 `json:"size"`
+
+	// MD5 checksum of the product as a hex string, empty if unknown.
+	MD5 string `json:"md5"`
+
+	// Footprint of the product in WKT.
+	Footprint string `json:"footprint"`
 }
 
 type Downloader interface {
+	// QueryImages returns the products matching the query.
 	QueryImages(Query) ([]SceneMeta, error)
-	DownloadImages([]SceneMeta, error)
-	DownloadQuery(Query)
+
+	// DownloadImages downloads the products.
+	DownloadImages([]SceneMeta) error
+
+	// DownloadQuery downloads the products matching the query and
+	// returns them.
+	DownloadQuery(Query) ([]SceneMeta, error)
 }
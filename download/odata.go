@@ -0,0 +1,284 @@
+package download
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const odataTimeFmt = "2006-01-02T15:04:05.000Z"
+
+func stringAttribute(name, value string) string {
+	return fmt.Sprintf("Attributes/OData.CSC.StringAttribute/any("+
+		"att:att/Name eq '%s' and "+
+		"att/OData.CSC.StringAttribute/Value eq '%s')", name, value)
+}
+
+// Filter returns the OData $filter expression of the query.
+func (q Query) Filter() (s string) {
+	f := []string{"Collection/Name eq 'SENTINEL-1'"}
+
+	if q.AOI.IsSet() {
+		f = append(f, fmt.Sprintf(
+			"OData.CSC.Intersects(area=geography'SRID=4326;%s')", q.AOI))
+	}
+
+	if !q.Start.IsZero() {
+		f = append(f, "ContentDate/Start ge "+q.Start.UTC().Format(odataTimeFmt))
+	}
+
+	if !q.Stop.IsZero() {
+		f = append(f, "ContentDate/Start lt "+q.Stop.UTC().Format(odataTimeFmt))
+	}
+
+	if q.ProductType != "" {
+		f = append(f, stringAttribute("productType", q.ProductType))
+	}
+
+	if q.Polarization != "" {
+		f = append(f, stringAttribute("polarisationChannels", q.Polarization))
+	}
+
+	if q.RelativeOrbit > 0 {
+		f = append(f, fmt.Sprintf("Attributes/OData.CSC.IntegerAttribute/any("+
+			"att:att/Name eq 'relativeOrbitNumber' and "+
+			"att/OData.CSC.IntegerAttribute/Value eq %d)", q.RelativeOrbit))
+	}
+
+	return strings.Join(f, " and ")
+}
+
+type odataProduct struct {
+	ID            string `json:"Id"`
+	Name          string `json:"Name"`
+	ContentLength int64  `json:"ContentLength"`
+	ContentDate   struct {
+		Start time.Time `json:"Start"`
+		End   time.Time `json:"End"`
+	} `json:"ContentDate"`
+	Footprint string `json:"Footprint"`
+	Checksum  []struct {
+		Value     string `json:"Value"`
+		Algorithm string `json:"Algorithm"`
+	} `json:"Checksum"`
+}
+
+type odataPage struct {
+	Value    []odataProduct `json:"value"`
+	NextLink string         `json:"@odata.nextLink"`
+}
+
+// footprint strips the "geography'SRID=4326;...'" wrapper of the WKT.
+func footprint(s string) string {
+	s = strings.TrimPrefix(s, "geography'")
+	s = strings.TrimSuffix(s, "'")
+
+	if idx := strings.Index(s, ";"); idx >= 0 {
+		s = s[idx+1:]
+	}
+
+	return s
+}
+
+func (c *Client) sceneMeta(p odataProduct) (sm SceneMeta) {
+	sm = SceneMeta{
+		ID:        p.ID,
+		Name:      p.Name,
+		Date:      p.ContentDate.Start,
+		URL:       fmt.Sprintf("%s/Products(%s)/$value", c.DownloadURL, p.ID),
+		Size:      p.ContentLength,
+		Footprint: footprint(p.Footprint),
+	}
+
+	for _, sum := range p.Checksum {
+		if strings.EqualFold(sum.Algorithm, "MD5") {
+			sm.MD5 = strings.ToLower(sum.Value)
+		}
+	}
+
+	return
+}
+
+// Filename returns the name of the downloaded zipfile.
+func (sm SceneMeta) Filename() string {
+	return strings.TrimSuffix(sm.Name, ".SAFE") + ".zip"
+}
+
+/*
+Client searches and downloads products using an OData catalogue, like
+the one of the Copernicus Data Space Ecosystem.
+*/
+type Client struct {
+	Settings
+	HTTP *http.Client
+
+	// Progress of the downloads is reported to it if it is set.
+	Progress ProgressFunc
+
+	mutex  sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func NewClient(s Settings) (c *Client, err error) {
+	s.Default()
+
+	if err = s.Validate(); err != nil {
+		return
+	}
+
+	return &Client{
+		Settings: s,
+		HTTP:     &http.Client{},
+	}, nil
+}
+
+func (c *Client) getJSON(u string, v interface{}) (err error) {
+	resp, err := c.HTTP.Get(u)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("request '%s' failed with status '%s': %s", u,
+			resp.Status, body)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+/*
+QueryImages searches the catalogue and returns the products matching the
+query sorted by their sensing start. The pages of the results are
+followed by the next links of the responses or, if the service does not
+provide them, by increasing $skip.
+*/
+func (c *Client) QueryImages(q Query) (sm []SceneMeta, err error) {
+	if err = q.Validate(); err != nil {
+		return
+	}
+
+	params := url.Values{}
+	params.Set("$filter", q.Filter())
+	params.Set("$orderby", "ContentDate/Start asc")
+	params.Set("$top", fmt.Sprint(c.PageSize))
+
+	next := c.BaseURL + "/Products?" + params.Encode()
+
+	for skip := 0; next != ""; {
+		var page odataPage
+		if err = c.getJSON(next, &page); err != nil {
+			return
+		}
+
+		for _, p := range page.Value {
+			sm = append(sm, c.sceneMeta(p))
+		}
+
+		switch {
+		case page.NextLink != "":
+			next = page.NextLink
+		case len(page.Value) < c.PageSize:
+			next = ""
+		default:
+			skip += len(page.Value)
+			params.Set("$skip", fmt.Sprint(skip))
+			next = c.BaseURL + "/Products?" + params.Encode()
+		}
+	}
+
+	return sm, nil
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// Token returns an access token, requesting a new one if the previous
+// one (almost) expired.
+func (c *Client) Token() (token string, err error) {
+	if !c.HasCredentials() {
+		return "", fmt.Errorf("no credentials are set for downloading")
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.token != "" && time.Now().Before(c.expiry) {
+		return c.token, nil
+	}
+
+	resp, err := c.HTTP.PostForm(c.TokenURL, url.Values{
+		"grant_type": {"password"},
+		"client_id":  {c.ClientID},
+		"username":   {c.Username},
+		"password":   {c.Password},
+	})
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("authentication failed with status '%s'",
+			resp.Status)
+	}
+
+	var tr tokenResponse
+	if err = json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return
+	}
+
+	// renew the token a bit before it expires
+	c.token = tr.AccessToken
+	c.expiry = time.Now().Add(time.Duration(tr.ExpiresIn)*time.Second -
+		30*time.Second)
+
+	return c.token, nil
+}
+
+func (c *Client) authorize(req *http.Request) (err error) {
+	token, err := c.Token()
+	if err != nil {
+		return
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Transfer returns the transfer engine configured by the settings of the
+// client.
+func (c *Client) Transfer() (t *Transfer) {
+	return &Transfer{
+		HTTP:        c.HTTP,
+		OutputDir:   c.OutputDir,
+		Concurrency: c.Concurrency,
+		MaxRetries:  c.MaxRetries,
+		Limiter:     NewRateLimiter(c.BandwidthLimit),
+		Authorize:   c.authorize,
+		Progress:    c.Progress,
+	}
+}
+
+// DownloadImages downloads the products into the output directory.
+func (c *Client) DownloadImages(sm []SceneMeta) (err error) {
+	return c.Transfer().Run(sm)
+}
+
+func (c *Client) DownloadQuery(q Query) (sm []SceneMeta, err error) {
+	if sm, err = c.QueryImages(q); err != nil {
+		return
+	}
+
+	err = c.DownloadImages(sm)
+	return
+}
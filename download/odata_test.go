@@ -0,0 +1,126 @@
+package download
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testContent = "zipfile content"
+	// MD5 checksum of testContent
+	testMD5 = "EFF443D8B1EDAF0CB95B8DEFD0347E94"
+)
+
+func testServer(t *testing.T, products int) *httptest.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("username") != "user" || r.FormValue("password") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		json.NewEncoder(w).Encode(tokenResponse{
+			AccessToken: "token",
+			ExpiresIn:   600,
+		})
+	})
+
+	mux.HandleFunc("/odata/Products", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		if f := q.Get("$filter"); !strings.Contains(f, "relativeOrbitNumber") {
+			t.Errorf("relative orbit is missing from filter '%s'", f)
+		}
+
+		top, _ := strconv.Atoi(q.Get("$top"))
+		skip, _ := strconv.Atoi(q.Get("$skip"))
+
+		var page odataPage
+		for ii := skip; ii < skip+top && ii < products; ii++ {
+			var p odataProduct
+			p.ID = fmt.Sprintf("id-%d", ii)
+			p.Name = fmt.Sprintf("S1A_%d.SAFE", ii)
+			p.ContentLength = int64(len(testContent))
+			p.Footprint = "geography'SRID=4326;POLYGON((0 0,1 0,1 1,0 0))'"
+			p.Checksum = append(p.Checksum, struct {
+				Value     string `json:"Value"`
+				Algorithm string `json:"Algorithm"`
+			}{testMD5, "MD5"})
+			page.Value = append(page.Value, p)
+		}
+
+		json.NewEncoder(w).Encode(page)
+	})
+
+	mux.HandleFunc("/zipper/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(testContent))
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestQueryImages(t *testing.T) {
+	srv := testServer(t, 5)
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := NewClient(Settings{
+		BaseURL:     srv.URL + "/odata",
+		DownloadURL: srv.URL + "/zipper",
+		TokenURL:    srv.URL + "/token",
+		Username:    "user",
+		Password:    "secret",
+		PageSize:    2,
+		OutputDir:   dir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sm, err := c.DownloadQuery(Query{
+		Start:         time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		Stop:          time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC),
+		RelativeOrbit: 51,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sm) != 5 {
+		t.Fatalf("expected 5 products, got %d", len(sm))
+	}
+
+	s := sm[4]
+	if s.MD5 != strings.ToLower(testMD5) || s.Footprint != "POLYGON((0 0,1 0,1 1,0 0))" ||
+		s.URL != srv.URL+"/zipper/Products(id-4)/$value" {
+		t.Errorf("unexpected scene metadata %+v", s)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "S1A_4.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != testContent {
+		t.Errorf("unexpected content of download '%s'", b)
+	}
+}
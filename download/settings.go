@@ -0,0 +1,105 @@
+package download
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	DefaultBaseURL     = "https://catalogue.dataspace.copernicus.eu/odata/v1"
+	DefaultDownloadURL = "https://zipper.dataspace.copernicus.eu/odata/v1"
+	DefaultTokenURL    = "https://identity.dataspace.copernicus.eu/auth/realms/" +
+		"CDSE/protocol/openid-connect/token"
+	DefaultClientID = "cdse-public"
+	DefaultPageSize = 100
+)
+
+/*
+Settings configures the access of the catalogue. Empty fields are
+replaced with the defaults of the Copernicus Data Space Ecosystem.
+Credentials are only needed for downloading.
+*/
+type Settings struct {
+	// Root of the OData service used for searching.
+	BaseURL string `json:"base_url"`
+
+	// Root of the OData service used for downloading.
+	DownloadURL string `json:"download_url"`
+
+	// OpenID Connect token endpoint.
+	TokenURL string `json:"token_url"`
+	ClientID string `json:"client_id"`
+
+	Username string `json:"username"`
+	Password string `json:"password"`
+
+	// Number of products requested in one page of the search.
+	PageSize int `json:"page_size"`
+
+	// Directory where the products are downloaded.
+	OutputDir string `json:"output_dir"`
+
+	// Number of products downloaded at the same time.
+	Concurrency int `json:"concurrency"`
+
+	// Number of retries of a failed download.
+	MaxRetries int `json:"max_retries"`
+
+	// Limit of the total download speed in bytes per second, 0 means no
+	// limit.
+	BandwidthLimit int64 `json:"bandwidth_limit"`
+}
+
+func (s *Settings) Default() {
+	if s.BaseURL == "" {
+		s.BaseURL = DefaultBaseURL
+	}
+
+	if s.DownloadURL == "" {
+		s.DownloadURL = DefaultDownloadURL
+	}
+
+	if s.TokenURL == "" {
+		s.TokenURL = DefaultTokenURL
+	}
+
+	if s.ClientID == "" {
+		s.ClientID = DefaultClientID
+	}
+
+	if s.PageSize <= 0 {
+		s.PageSize = DefaultPageSize
+	}
+
+	if s.OutputDir == "" {
+		s.OutputDir = "."
+	}
+
+	if s.Concurrency <= 0 {
+		s.Concurrency = DefaultConcurrency
+	}
+
+	if s.MaxRetries <= 0 {
+		s.MaxRetries = DefaultMaxRetries
+	}
+
+	s.BaseURL = strings.TrimSuffix(s.BaseURL, "/")
+	s.DownloadURL = strings.TrimSuffix(s.DownloadURL, "/")
+}
+
+func (s Settings) Validate() (err error) {
+	if s.BandwidthLimit < 0 {
+		return fmt.Errorf("bandwidth limit must not be negative")
+	}
+
+	if (s.Username == "") != (s.Password == "") {
+		return fmt.Errorf("both username and password must be set for " +
+			"authentication")
+	}
+
+	return nil
+}
+
+func (s Settings) HasCredentials() bool {
+	return s.Username != ""
+}
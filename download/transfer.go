@@ -0,0 +1,419 @@
+package download
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultConcurrency = 2
+	DefaultMaxRetries  = 5
+	DefaultBackoff     = 2 * time.Second
+	maxBackoff         = 2 * time.Minute
+	partSuffix         = ".part"
+	chunkSize          = 32 * 1024
+)
+
+// Progress describes the state of the download of a file.
+type Progress struct {
+	Scene SceneMeta
+
+	// Bytes already written into the file, including the ones of
+	// previous attempts.
+	Written int64
+
+	// Expected size of the file, 0 if unknown.
+	Total int64
+
+	Done bool
+	Err  error
+}
+
+func (p Progress) Percent() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return 100.0 * float64(p.Written) / float64(p.Total)
+}
+
+// ProgressFunc is called with the state of the download after each
+// received chunk and when a download finishes. It is called from
+// multiple goroutines.
+type ProgressFunc func(Progress)
+
+/*
+RateLimiter limits the throughput shared by concurrent readers. Reserved
+bytes are spread evenly in time, so the readers together never exceed
+the set rate.
+*/
+type RateLimiter struct {
+	mutex sync.Mutex
+	// bytes per second
+	rate float64
+	next time.Time
+}
+
+// NewRateLimiter returns a limiter of bytesPerSec throughput or nil, which
+// does not limit, if bytesPerSec is not positive.
+func NewRateLimiter(bytesPerSec int64) *RateLimiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &RateLimiter{rate: float64(bytesPerSec)}
+}
+
+// Wait blocks until n bytes can be transferred.
+func (r *RateLimiter) Wait(n int) {
+	if r == nil || n <= 0 {
+		return
+	}
+
+	r.mutex.Lock()
+	now := time.Now()
+	if r.next.Before(now) {
+		r.next = now
+	}
+	delay := r.next.Sub(now)
+	r.next = r.next.Add(time.Duration(float64(n) / r.rate * float64(time.Second)))
+	r.mutex.Unlock()
+
+	time.Sleep(delay)
+}
+
+// retryable marks errors after which the download is attempted again.
+type retryable struct {
+	err   error
+	after time.Duration
+}
+
+func (r retryable) Error() string {
+	return r.err.Error()
+}
+
+func retryAfter(resp *http.Response) (d time.Duration) {
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		d = time.Duration(s) * time.Second
+	}
+	return
+}
+
+/*
+Transfer downloads files concurrently. Files are written into a ".part"
+file next to their destination, which is renamed only after its size
+and MD5 checksum were verified against the catalogue metadata. An
+existing ".part" file is resumed with an HTTP Range request, so an
+interrupted download does not restart from zero. Failed requests are
+retried with exponential backoff on network errors, 429 and 5xx
+responses.
+*/
+type Transfer struct {
+	HTTP      *http.Client
+	OutputDir string
+
+	// Number of files downloaded at the same time.
+	Concurrency int
+
+	// Number of retries of a file before giving up.
+	MaxRetries int
+
+	// Wait before the first retry, doubled after each one.
+	Backoff time.Duration
+
+	// Shared by the downloads, nil means no limit.
+	Limiter *RateLimiter
+
+	// Authorize is called before each request, e.g. to set an access
+	// token that may have expired during a long download.
+	Authorize func(*http.Request) error
+
+	Progress ProgressFunc
+}
+
+func (t *Transfer) defaults() {
+	if t.HTTP == nil {
+		t.HTTP = &http.Client{}
+	}
+
+	if t.Concurrency <= 0 {
+		t.Concurrency = DefaultConcurrency
+	}
+
+	if t.MaxRetries < 0 {
+		t.MaxRetries = 0
+	}
+
+	if t.Backoff <= 0 {
+		t.Backoff = DefaultBackoff
+	}
+
+	if t.OutputDir == "" {
+		t.OutputDir = "."
+	}
+}
+
+func (t *Transfer) report(p Progress) {
+	if t.Progress != nil {
+		t.Progress(p)
+	}
+}
+
+/*
+Run downloads the scenes. Every scene is attempted even if some of them
+fail; the failures are logged and summarized in the returned error.
+*/
+func (t *Transfer) Run(scenes []SceneMeta) (err error) {
+	t.defaults()
+
+	jobs := make(chan int)
+	errs := make([]error, len(scenes))
+
+	var wg sync.WaitGroup
+	for ii := 0; ii < t.Concurrency; ii++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for jj := range jobs {
+				sm := scenes[jj]
+
+				errs[jj] = t.Fetch(sm)
+				t.report(Progress{
+					Scene: sm,
+					Total: sm.Size,
+					Done:  true,
+					Err:   errs[jj],
+				})
+
+				if errs[jj] != nil {
+					log.Printf("download of '%s' failed: %s", sm.Name, errs[jj])
+				}
+			}
+		}()
+	}
+
+	for ii := range scenes {
+		jobs <- ii
+	}
+	close(jobs)
+	wg.Wait()
+
+	failed := 0
+	for _, e := range errs {
+		if e == nil {
+			continue
+		}
+
+		if err == nil {
+			err = e
+		}
+		failed++
+	}
+
+	if failed > 0 {
+		err = fmt.Errorf("%d of %d downloads failed, first error: %w",
+			failed, len(scenes), err)
+	}
+
+	return
+}
+
+/*
+Fetch downloads a single scene, resuming its ".part" file if there is
+one. A destination that already exists with the expected size is not
+downloaded again.
+*/
+func (t *Transfer) Fetch(sm SceneMeta) (err error) {
+	t.defaults()
+
+	dst := filepath.Join(t.OutputDir, sm.Filename())
+	if info, err := os.Stat(dst); err == nil &&
+		(sm.Size <= 0 || info.Size() == sm.Size) {
+		return nil
+	}
+
+	part := dst + partSuffix
+	wait := t.Backoff
+
+	for attempt := 0; ; attempt++ {
+		err = t.attempt(sm, part)
+
+		if err == nil {
+			if err = verify(sm, part); err == nil {
+				return os.Rename(part, dst)
+			}
+			// the file is complete (or oversized) but corrupted, it has
+			// to be downloaded from scratch
+			os.Remove(part)
+			err = retryable{err: err}
+		}
+
+		var r retryable
+		if !errors.As(err, &r) {
+			return err
+		}
+
+		if attempt >= t.MaxRetries {
+			return fmt.Errorf("giving up after %d retries: %w", attempt, r.err)
+		}
+
+		if r.after > wait {
+			wait = r.after
+		}
+
+		log.Printf("download of '%s' failed: %s; retrying in %s", sm.Name,
+			r.err, wait)
+		time.Sleep(wait)
+
+		if wait *= 2; wait > maxBackoff {
+			wait = maxBackoff
+		}
+	}
+}
+
+// attempt continues the download of the scene into the part file.
+func (t *Transfer) attempt(sm SceneMeta, part string) (err error) {
+	var offset int64
+	if info, err := os.Stat(part); err == nil {
+		offset = info.Size()
+	}
+
+	if sm.Size > 0 {
+		if offset == sm.Size {
+			return nil
+		}
+
+		if offset > sm.Size {
+			if err = os.Remove(part); err != nil {
+				return
+			}
+			offset = 0
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, sm.URL, nil)
+	if err != nil {
+		return
+	}
+
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	if t.Authorize != nil {
+		if err = t.Authorize(req); err != nil {
+			return
+		}
+	}
+
+	resp, err := t.HTTP.Do(req)
+	if err != nil {
+		return retryable{err: err}
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+
+	switch code := resp.StatusCode; {
+	case code == http.StatusPartialContent:
+		flags |= os.O_APPEND
+	case code == http.StatusOK:
+		// the server ignored the range, start again
+		flags |= os.O_TRUNC
+		offset = 0
+	case code == http.StatusRequestedRangeNotSatisfiable:
+		os.Remove(part)
+		return retryable{err: fmt.Errorf("invalid range of partial file")}
+	case code == http.StatusTooManyRequests || code >= 500:
+		return retryable{
+			err:   fmt.Errorf("server responded with '%s'", resp.Status),
+			after: retryAfter(resp),
+		}
+	default:
+		return fmt.Errorf("server responded with '%s'", resp.Status)
+	}
+
+	f, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	p := Progress{Scene: sm, Written: offset, Total: sm.Size}
+	buf := make([]byte, chunkSize)
+
+	for {
+		n, rerr := resp.Body.Read(buf)
+
+		if n > 0 {
+			t.Limiter.Wait(n)
+
+			if _, err = f.Write(buf[:n]); err != nil {
+				return
+			}
+
+			p.Written += int64(n)
+			t.report(p)
+		}
+
+		if rerr == io.EOF {
+			break
+		}
+
+		if rerr != nil {
+			// keep what we got so far, the next attempt resumes from there
+			return retryable{err: rerr}
+		}
+	}
+
+	if err = f.Close(); err != nil {
+		return
+	}
+
+	// the connection may be closed cleanly before the whole file arrives,
+	// the part file is kept so the next attempt resumes from there
+	if sm.Size > 0 && p.Written < sm.Size {
+		return retryable{err: fmt.Errorf("download ended after %d of %d "+
+			"bytes", p.Written, sm.Size)}
+	}
+
+	return nil
+}
+
+// verify checks the size and MD5 checksum of the file if they are known.
+func verify(sm SceneMeta, file string) (err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	h := md5.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return
+	}
+
+	if sm.Size > 0 && n != sm.Size {
+		return fmt.Errorf("size of '%s' is %d bytes, expected %d", file, n,
+			sm.Size)
+	}
+
+	if sm.MD5 != "" {
+		if sum := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(sum, sm.MD5) {
+			return fmt.Errorf("MD5 checksum of '%s' is '%s', expected '%s'",
+				file, sum, sm.MD5)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package download
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type flakyServer struct {
+	content []byte
+
+	mutex    sync.Mutex
+	requests int
+	ranges   []string
+}
+
+/*
+ServeHTTP fails the first request with 503, cuts the connection in the
+middle of the second one and serves the rest normally, honouring Range
+requests.
+*/
+func (f *flakyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	f.requests++
+	n := f.requests
+	f.ranges = append(f.ranges, r.Header.Get("Range"))
+	f.mutex.Unlock()
+
+	switch n {
+	case 1:
+		w.WriteHeader(http.StatusServiceUnavailable)
+	case 2:
+		w.Header().Set("Content-Length", "1000")
+		w.Write(f.content[:len(f.content)/2])
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	default:
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(f.content))
+	}
+}
+
+func testTransfer(t *testing.T) (tr *Transfer, dir string) {
+	dir, err := ioutil.TempDir("", "transfer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Transfer{
+		OutputDir:   dir,
+		Concurrency: 2,
+		MaxRetries:  3,
+		Backoff:     time.Millisecond,
+	}, dir
+}
+
+func TestTransferResume(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 100)
+	sum := md5.Sum(content)
+
+	fs := &flakyServer{content: content}
+	srv := httptest.NewServer(fs)
+	defer srv.Close()
+
+	tr, dir := testTransfer(t)
+	defer os.RemoveAll(dir)
+
+	var last Progress
+	tr.Progress = func(p Progress) {
+		if !p.Done {
+			last = p
+		}
+	}
+
+	sm := SceneMeta{
+		Name: "S1A_TEST.SAFE",
+		URL:  srv.URL,
+		Size: int64(len(content)),
+		MD5:  hex.EncodeToString(sum[:]),
+	}
+
+	if err := tr.Run([]SceneMeta{sm}); err != nil {
+		t.Fatal(err)
+	}
+
+	if fs.requests != 3 {
+		t.Errorf("expected 3 requests, got %d", fs.requests)
+	}
+
+	if r := fs.ranges[2]; r != "bytes=500-" {
+		t.Errorf("expected the download to resume with a range request, "+
+			"got '%s'", r)
+	}
+
+	if last.Written != sm.Size {
+		t.Errorf("expected %d bytes reported, got %d", sm.Size, last.Written)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "S1A_TEST.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(b, content) {
+		t.Errorf("downloaded content differs")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "S1A_TEST.zip.part")); err == nil {
+		t.Errorf("partial file was not removed")
+	}
+}
+
+/*
+TestTransferShortRead checks that a download ending cleanly before the
+expected size is resumed instead of being discarded, and that the
+checksum is compared case-insensitively.
+*/
+func TestTransferShortRead(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 100)
+	sum := md5.Sum(content)
+
+	var (
+		mutex  sync.Mutex
+		ranges []string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			mutex.Lock()
+			ranges = append(ranges, r.Header.Get("Range"))
+			n := len(ranges)
+			mutex.Unlock()
+
+			if n == 1 {
+				w.Write(content[:len(content)/4])
+				return
+			}
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+		}))
+	defer srv.Close()
+
+	tr, dir := testTransfer(t)
+	defer os.RemoveAll(dir)
+
+	sm := SceneMeta{
+		Name: "short.SAFE",
+		URL:  srv.URL,
+		Size: int64(len(content)),
+		MD5:  strings.ToUpper(hex.EncodeToString(sum[:])),
+	}
+
+	if err := tr.Fetch(sm); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ranges) != 2 || ranges[1] != "bytes=250-" {
+		t.Errorf("expected the download to resume from byte 250, got "+
+			"requests with ranges %q", ranges)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "short.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(b, content) {
+		t.Errorf("downloaded content differs")
+	}
+}
+
+func TestTransferChecksum(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("corrupted"))
+		}))
+	defer srv.Close()
+
+	tr, dir := testTransfer(t)
+	defer os.RemoveAll(dir)
+	tr.MaxRetries = 1
+
+	err := tr.Run([]SceneMeta{
+		{Name: "checksum.SAFE", URL: srv.URL, MD5: "d41d8cd98f00b204e9800998ecf8427e"},
+		{Name: "size.SAFE", URL: srv.URL, Size: 9},
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "1 of 2") {
+		t.Fatalf("expected the checksum failure of one download, got %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "checksum.zip")); err == nil {
+		t.Errorf("file with a wrong checksum was kept")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "size.zip")); err != nil {
+		t.Errorf("file with matching size was not kept: %s", err)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := NewRateLimiter(1000)
+
+	start := time.Now()
+	for ii := 0; ii < 5; ii++ {
+		l.Wait(100)
+	}
+
+	// the last reservation waits for the first 400 bytes
+	if d := time.Since(start); d < 350*time.Millisecond {
+		t.Errorf("rate limiter did not wait enough: %s", d)
+	}
+}
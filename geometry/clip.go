@@ -0,0 +1,208 @@
+package geometry
+
+import (
+	"math"
+	"sort"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+)
+
+/*
+ConvexHull returns the convex hull of the points as a closed ring with
+counter-clockwise orientation (Andrew's monotone chain algorithm).
+*/
+func ConvexHull(points []orb.Point) (r orb.Ring) {
+	n := len(points)
+	if n < 3 {
+		return nil
+	}
+
+	pts := make([]orb.Point, n)
+	copy(pts, points)
+
+	sort.Slice(pts, func(i, j int) bool {
+		if pts[i][0] == pts[j][0] {
+			return pts[i][1] < pts[j][1]
+		}
+		return pts[i][0] < pts[j][0]
+	})
+
+	hull := make([]orb.Point, 0, 2*n)
+
+	// lower hull
+	for _, p := range pts {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+
+	// upper hull
+	lower := len(hull) + 1
+	for ii := n - 2; ii >= 0; ii-- {
+		p := pts[ii]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+
+	// the last point equals the first one, so the ring is closed
+	return orb.Ring(hull)
+}
+
+// z component of the cross product of (a - o) and (b - o)
+func cross(o, a, b orb.Point) float64 {
+	return (a[0]-o[0])*(b[1]-o[1]) - (a[1]-o[1])*(b[0]-o[0])
+}
+
+/*
+ClipConvex clips the subject ring with a convex, counter-clockwise
+clip ring using the Sutherland-Hodgman algorithm. The subject ring
+does not need to be convex.
+*/
+func ClipConvex(subject, clip orb.Ring) (r orb.Ring) {
+	out := openRing(subject)
+	edges := openRing(clip)
+	n := len(edges)
+
+	for ii := 0; ii < n && len(out) > 0; ii++ {
+		a, b := edges[ii], edges[(ii+1)%n]
+		in := out
+		out = make([]orb.Point, 0, len(in)+2)
+
+		for jj, curr := range in {
+			prev := in[(jj+len(in)-1)%len(in)]
+			currIn, prevIn := cross(a, b, curr) >= 0, cross(a, b, prev) >= 0
+
+			if currIn {
+				if !prevIn {
+					out = append(out, intersection(prev, curr, a, b))
+				}
+				out = append(out, curr)
+			} else if prevIn {
+				out = append(out, intersection(prev, curr, a, b))
+			}
+		}
+	}
+
+	if len(out) < 3 {
+		return nil
+	}
+
+	return append(orb.Ring(out), out[0])
+}
+
+// openRing drops the closing point of the ring
+func openRing(r orb.Ring) (pts []orb.Point) {
+	pts = []orb.Point(r)
+	if n := len(pts); n > 1 && pts[0] == pts[n-1] {
+		pts = pts[:n-1]
+	}
+	return
+}
+
+// intersection of the segment p1-p2 and the line going through a and b
+func intersection(p1, p2, a, b orb.Point) orb.Point {
+	d1, d2 := cross(a, b, p1), cross(a, b, p2)
+	t := d1 / (d1 - d2)
+
+	return orb.Point{
+		p1[0] + t*(p2[0]-p1[0]),
+		p1[1] + t*(p2[1]-p1[1]),
+	}
+}
+
+func ringArea(r orb.Ring) float64 {
+	if len(r) < 4 {
+		return 0.0
+	}
+	return math.Abs(planar.Area(r))
+}
+
+/*
+IntersectionArea returns the area of the intersection of the area of
+interest and a convex footprint, in the units of the coordinates. Holes
+of the polygons are taken into account.
+*/
+func (a AreaOfInterest) IntersectionArea(convex orb.Ring) (area float64) {
+	mp, err := a.MultiPolygon()
+	if err != nil {
+		return 0.0
+	}
+
+	if !a.Bound().Intersects(convex.Bound()) {
+		return 0.0
+	}
+
+	for _, poly := range mp {
+		for ii, ring := range poly {
+			clipped := ringArea(ClipConvex(ring, convex))
+
+			if ii == 0 {
+				area += clipped
+			} else {
+				area -= clipped
+			}
+		}
+	}
+
+	return math.Max(area, 0.0)
+}
+
+// Overlap returns the fraction of the area of interest covered by the
+// convex footprint.
+func (a AreaOfInterest) Overlap(convex orb.Ring) (f float64) {
+	total := a.Area()
+	if total == 0.0 {
+		return 0.0
+	}
+
+	return math.Min(a.IntersectionArea(convex)/total, 1.0)
+}
+
+// Number of samples along one axis used by Coverage.
+const CoverageSamples = 200
+
+/*
+Coverage estimates the fraction of the area of interest covered by the
+union of the footprints. Since footprints may overlap, the union is not
+computed exactly; the bounding box of the area of interest is sampled
+on a regular grid of CoverageSamples x CoverageSamples points and the
+samples falling inside the area are checked.
+*/
+func (a AreaOfInterest) Coverage(footprints []orb.Ring) (f float64) {
+	b := a.Bound()
+	dx := (b.Max[0] - b.Min[0]) / CoverageSamples
+	dy := (b.Max[1] - b.Min[1]) / CoverageSamples
+
+	inside, covered := 0, 0
+
+	for ii := 0; ii < CoverageSamples; ii++ {
+		for jj := 0; jj < CoverageSamples; jj++ {
+			p := orb.Point{
+				b.Min[0] + (float64(ii)+0.5)*dx,
+				b.Min[1] + (float64(jj)+0.5)*dy,
+			}
+
+			if !a.Contains(p) {
+				continue
+			}
+			inside++
+
+			for _, fp := range footprints {
+				if planar.RingContains(fp, p) {
+					covered++
+					break
+				}
+			}
+		}
+	}
+
+	if inside == 0 {
+		return 0.0
+	}
+
+	return float64(covered) / float64(inside)
+}
@@ -0,0 +1,137 @@
+package geometry
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/paulmach/orb"
+)
+
+func square(x0, y0, size float64) orb.Ring {
+	return orb.Ring{
+		{x0, y0}, {x0 + size, y0}, {x0 + size, y0 + size},
+		{x0, y0 + size}, {x0, y0},
+	}
+}
+
+func isClose(one, two float64) bool {
+	return math.Abs(one-two) < 1e-9
+}
+
+func TestConvexHull(t *testing.T) {
+	hull := ConvexHull([]orb.Point{
+		{0, 0}, {2, 0}, {1, 1}, {2, 2}, {0, 2}, {1, 0.5},
+	})
+
+	if len(hull) != 5 {
+		t.Fatalf("expected hull with 4 corners, got %v", hull)
+	}
+
+	if a := ringArea(hull); !isClose(a, 4.0) {
+		t.Errorf("expected hull area 4.0, got %f", a)
+	}
+}
+
+func TestIntersectionArea(t *testing.T) {
+	aoi, err := NewAreaOfInterest(orb.Polygon{square(0, 0, 2)})
+	if err != nil {
+		t.Fatalf("creating area of interest failed: %s", err)
+	}
+
+	cases := []struct {
+		footprint orb.Ring
+		expected  float64
+	}{
+		{square(1, 1, 2), 1.0},
+		{square(-1, -1, 4), 4.0},
+		{square(0.5, 0.5, 1), 1.0},
+		{square(3, 3, 1), 0.0},
+	}
+
+	for _, c := range cases {
+		if a := aoi.IntersectionArea(c.footprint); !isClose(a, c.expected) {
+			t.Errorf("expected intersection area of %v to be %f, got %f",
+				c.footprint, c.expected, a)
+		}
+	}
+
+	holed, err := NewAreaOfInterest(orb.Polygon{
+		square(0, 0, 2), square(0.5, 0.5, 1),
+	})
+	if err != nil {
+		t.Fatalf("creating area of interest failed: %s", err)
+	}
+
+	if a := holed.IntersectionArea(square(-1, -1, 4)); !isClose(a, 3.0) {
+		t.Errorf("expected intersection area with hole to be 3.0, got %f", a)
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	aoi, err := NewAreaOfInterest(orb.Polygon{square(0, 0, 2)})
+	if err != nil {
+		t.Fatalf("creating area of interest failed: %s", err)
+	}
+
+	full := aoi.Coverage([]orb.Ring{square(-3, -1, 4), square(0.9, -1, 4)})
+	if full != 1.0 {
+		t.Errorf("expected full coverage, got %f", full)
+	}
+
+	half := aoi.Coverage([]orb.Ring{square(1, -1, 4)})
+	if math.Abs(half-0.5) > 0.01 {
+		t.Errorf("expected half coverage, got %f", half)
+	}
+}
+
+func TestUnmarshalAreaOfInterest(t *testing.T) {
+	payloads := []string{
+		`"POLYGON((0 0, 2 0, 2 2, 0 2, 0 0))"`,
+		`{"type": "Polygon", "coordinates": [[[0, 0], [2, 0], [2, 2], [0, 2], [0, 0]]]}`,
+		`{"type": "Feature", "properties": {}, "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [2, 0], [2, 2], [0, 2], [0, 0]]]}}`,
+		`{"footprint": "MULTIPOLYGON(((0 0, 2 0, 2 2, 0 2, 0 0)))"}`,
+	}
+
+	for _, payload := range payloads {
+		var aoi AreaOfInterest
+		if err := json.Unmarshal([]byte(payload), &aoi); err != nil {
+			t.Errorf("failed to parse '%s': %s", payload, err)
+			continue
+		}
+
+		if a := aoi.Area(); !isClose(a, 4.0) {
+			t.Errorf("expected area of '%s' to be 4.0, got %f", payload, a)
+		}
+	}
+
+	var aoi AreaOfInterest
+	if err := json.Unmarshal([]byte(`"POINT(1 1)"`), &aoi); err == nil {
+		t.Errorf("expected parsing of a point to fail")
+	}
+}
+
+func TestNullGeometryFeature(t *testing.T) {
+	const payload = `{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "properties": {}, "geometry": null},
+		{"type": "Feature", "properties": {}, "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [2, 0], [2, 2], [0, 2], [0, 0]]]}}
+	]}`
+
+	var aoi AreaOfInterest
+	if err := json.Unmarshal([]byte(payload), &aoi); err != nil {
+		t.Fatalf("failed to parse feature collection with a null geometry: %s",
+			err)
+	}
+
+	if a := aoi.Area(); !isClose(a, 4.0) {
+		t.Errorf("expected area to be 4.0, got %f", a)
+	}
+
+	const empty = `{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "properties": {}, "geometry": null}
+	]}`
+
+	if err := json.Unmarshal([]byte(empty), &aoi); err == nil {
+		t.Errorf("expected an error for a collection without polygons")
+	}
+}
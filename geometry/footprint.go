@@ -1,9 +1,202 @@
 package geometry
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
 	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/wkt"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/planar"
 )
 
+/*
+AreaOfInterest describes an area with a polygon or multipolygon
+footprint. Points are stored in (longitude, latitude) order.
+
+When unmarshaled from JSON it accepts a GeoJSON geometry, a GeoJSON
+Feature, a GeoJSON FeatureCollection (the polygons of all features are
+merged) or a string holding a WKT POLYGON or MULTIPOLYGON. The latter
+two forms can also be wrapped into an object under the "footprint" key.
+*/
 type AreaOfInterest struct {
 	Footprint orb.Geometry `json:"footprint"`
 }
+
+func NewAreaOfInterest(g orb.Geometry) (a AreaOfInterest, err error) {
+	a.Footprint = g
+	err = a.Validate()
+	return
+}
+
+func (a AreaOfInterest) IsSet() (b bool) {
+	return a.Footprint != nil
+}
+
+func (a AreaOfInterest) Validate() (err error) {
+	if _, err = a.MultiPolygon(); err != nil {
+		return
+	}
+
+	if a.Area() == 0.0 {
+		return fmt.Errorf("area of interest has zero area")
+	}
+
+	return nil
+}
+
+// MultiPolygon returns the footprint as a multipolygon regardless of
+// whether it is a single polygon or not.
+func (a AreaOfInterest) MultiPolygon() (mp orb.MultiPolygon, err error) {
+	switch g := a.Footprint.(type) {
+	case orb.Polygon:
+		mp = orb.MultiPolygon{g}
+	case orb.MultiPolygon:
+		mp = g
+	case orb.Bound:
+		mp = orb.MultiPolygon{g.ToPolygon()}
+	case nil:
+		err = fmt.Errorf("area of interest is not set")
+	default:
+		err = UnsupportedGeometry{g.GeoJSONType()}
+	}
+	return
+}
+
+func (a AreaOfInterest) Area() (f float64) {
+	return planar.Area(a.Footprint)
+}
+
+func (a AreaOfInterest) Bound() (b orb.Bound) {
+	return a.Footprint.Bound()
+}
+
+func (a AreaOfInterest) Contains(p orb.Point) (b bool) {
+	mp, err := a.MultiPolygon()
+	if err != nil {
+		return false
+	}
+
+	return planar.MultiPolygonContains(mp, p)
+}
+
+func (a AreaOfInterest) MarshalJSON() (b []byte, err error) {
+	return json.Marshal(geojson.NewGeometry(a.Footprint))
+}
+
+func (a *AreaOfInterest) UnmarshalJSON(b []byte) (err error) {
+	b = bytes.TrimSpace(b)
+
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err = json.Unmarshal(b, &s); err != nil {
+			return
+		}
+
+		return a.Set(s)
+	}
+
+	var probe struct {
+		Type      string          `json:"type"`
+		Footprint json.RawMessage `json:"footprint"`
+	}
+
+	if err = json.Unmarshal(b, &probe); err != nil {
+		return
+	}
+
+	switch probe.Type {
+	case "Feature":
+		var f geojson.Feature
+		if err = json.Unmarshal(b, &f); err != nil {
+			return
+		}
+		a.Footprint = f.Geometry
+	case "FeatureCollection":
+		var fc geojson.FeatureCollection
+		if err = json.Unmarshal(b, &fc); err != nil {
+			return
+		}
+
+		if a.Footprint, err = mergeFeatures(fc); err != nil {
+			return
+		}
+	case "":
+		if len(probe.Footprint) == 0 {
+			return fmt.Errorf("expected a GeoJSON object or WKT string " +
+				"as area of interest")
+		}
+
+		return a.UnmarshalJSON(probe.Footprint)
+	default:
+		var g geojson.Geometry
+		if err = json.Unmarshal(b, &g); err != nil {
+			return
+		}
+		a.Footprint = g.Geometry()
+	}
+
+	return a.Validate()
+}
+
+// Set parses the footprint from a WKT string.
+func (a *AreaOfInterest) Set(s string) (err error) {
+	s = strings.TrimSpace(s)
+
+	switch upper := strings.ToUpper(s); {
+	case strings.HasPrefix(upper, "MULTIPOLYGON"):
+		a.Footprint, err = wkt.UnmarshalMultiPolygon(s)
+	case strings.HasPrefix(upper, "POLYGON"):
+		a.Footprint, err = wkt.UnmarshalPolygon(s)
+	default:
+		err = fmt.Errorf("expected a WKT POLYGON or MULTIPOLYGON, got '%s'", s)
+	}
+
+	if err != nil {
+		return
+	}
+
+	return a.Validate()
+}
+
+func (a AreaOfInterest) String() string {
+	if a.Footprint == nil {
+		return ""
+	}
+
+	return wkt.MarshalString(a.Footprint)
+}
+
+func mergeFeatures(fc geojson.FeatureCollection) (mp orb.MultiPolygon, err error) {
+	for _, f := range fc.Features {
+		switch g := f.Geometry.(type) {
+		case orb.Polygon:
+			mp = append(mp, g)
+		case orb.MultiPolygon:
+			mp = append(mp, g...)
+		case nil:
+			// unlocated features are valid GeoJSON, they do not
+			// contribute to the area
+			continue
+		default:
+			return nil, UnsupportedGeometry{g.GeoJSONType()}
+		}
+	}
+
+	if len(mp) == 0 {
+		err = fmt.Errorf("feature collection does not contain any polygons")
+	}
+
+	return
+}
+
+type UnsupportedGeometry struct {
+	Type string
+}
+
+func (u UnsupportedGeometry) Error() string {
+	return fmt.Sprintf("unsupported geometry type '%s', expected a "+
+		"Polygon or MultiPolygon", u.Type)
+}
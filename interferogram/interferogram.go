@@ -31,31 +31,26 @@ func (i File) Move(dir path.Dir) (im File, err error) {
 		return
 	}
 
-	f, err := i.SimUnwrap.ToValid()
+	// optional files are only moved if they exist
+	im.SimUnwrap, im.Quality = i.SimUnwrap, i.Quality
 
-	if err != nil {
-		f, err = f.Move(dir)
-
-		if err != nil {
+	if f, Err := i.SimUnwrap.ToValid(); Err == nil {
+		if f, err = f.Move(dir); err != nil {
 			return
 		}
 
 		im.SimUnwrap = f.ToFile()
 	}
 
-	f, err = i.Quality.ToValid()
-
-	if err != nil {
-		f, err = f.Move(dir)
-
-		if err != nil {
+	if f, Err := i.Quality.ToValid(); Err == nil {
+		if f, err = f.Move(dir); err != nil {
 			return
 		}
 
 		im.Quality = f.ToFile()
 	}
 
-	im.Meta, im.DeltaT = i.Meta, im.DeltaT
+	im.Meta, im.DeltaT = i.Meta, i.DeltaT
 	return
 }
 
@@ -85,10 +85,10 @@ func (p Paths) Load() (f File, err error) {
 	return
 }
 
-// TODO: implement
+// Dimensions of the interferogram are read from the OFF_par file.
 var keys = &data.ParamKeys{
-	Rng:  "",
-	Azi:  "",
-	Type: "",
-	Date: "date",
+	Range:   "interferogram_width",
+	Azimuth: "interferogram_azimuth_lines",
+	Type:    "",
+	Date:    "date",
 }
@@ -0,0 +1,430 @@
+package interferogram
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bozso/gotoolbox/errors"
+	"github.com/bozso/gotoolbox/path"
+
+	"github.com/bozso/gomma/baseline"
+	"github.com/bozso/gomma/data"
+	"github.com/bozso/gomma/date"
+	"github.com/bozso/gomma/utils/params"
+)
+
+// Pair holds the acquisition dates of the two scenes of an interferogram.
+type Pair struct {
+	Reference time.Time `json:"reference"`
+	Secondary time.Time `json:"secondary"`
+}
+
+func NewPair(one, two time.Time) (p Pair) {
+	if two.Before(one) {
+		one, two = two, one
+	}
+
+	return Pair{Reference: day(one), Secondary: day(two)}
+}
+
+func day(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// ID returns the identifier of the pair used in file names, e.g.
+// "20200301_20200313".
+func (p Pair) ID() string {
+	return date.Short.ID(p.Reference, p.Secondary)
+}
+
+func (p Pair) String() string {
+	return p.ID()
+}
+
+func (p Pair) Temporal() time.Duration {
+	return p.Secondary.Sub(p.Reference)
+}
+
+var pairID = regexp.MustCompile(`(\d{8})_(\d{8})`)
+
+// ParsePair reads the dates of the pair from a name containing its ID.
+func ParsePair(name string) (p Pair, err error) {
+	m := pairID.FindStringSubmatch(name)
+	if m == nil {
+		return p, fmt.Errorf("no pair of dates found in '%s'", name)
+	}
+
+	one, err := date.Short.Parse(m[1])
+	if err != nil {
+		return
+	}
+
+	two, err := date.Short.Parse(m[2])
+	if err != nil {
+		return
+	}
+
+	return NewPair(one, two), nil
+}
+
+// PairsOf returns the pairs of an interferogram network.
+func PairsOf(n baseline.Network) (p []Pair) {
+	for _, pair := range n.Pairs {
+		p = append(p, NewPair(n.Baselines[pair.Reference].Date,
+			n.Baselines[pair.Secondary].Date))
+	}
+	return
+}
+
+// Entry is an interferogram of the stack.
+type Entry struct {
+	File
+	Pair Pair `json:"pair"`
+}
+
+/*
+Geometry holds the parameters that have to agree for the interferograms
+to be on the same grid: the dimensions and the multi-looking factors.
+*/
+type Geometry struct {
+	Shape        data.RngAzi `json:"shape"`
+	RangeLooks   int         `json:"range_looks"`
+	AzimuthLooks int         `json:"azimuth_looks"`
+}
+
+func (e Entry) Geometry() (g Geometry, err error) {
+	p, err := params.FromFile(e.ParFile, ":")
+	if err != nil {
+		return
+	}
+	parser := p.ToParser()
+
+	g.Shape = e.Meta.RngAzi
+
+	if g.RangeLooks, err = parser.Int("range_looks:", 0); err != nil {
+		return
+	}
+
+	g.AzimuthLooks, err = parser.Int("azimuth_looks:", 0)
+	return
+}
+
+func (g Geometry) MustSame(other Geometry) (err error) {
+	if err = g.Shape.MustSameShape(other.Shape); err != nil {
+		return
+	}
+
+	if g.RangeLooks != other.RangeLooks || g.AzimuthLooks != other.AzimuthLooks {
+		return fmt.Errorf("expected multi-looking factors to match "+
+			"(%d x %d != %d x %d)", g.RangeLooks, g.AzimuthLooks,
+			other.RangeLooks, other.AzimuthLooks)
+	}
+	return nil
+}
+
+/*
+Stack is a set of interferograms on the same grid, sorted by their
+reference and then their secondary dates.
+*/
+type Stack struct {
+	Geometry Geometry `json:"geometry"`
+	Entries  []Entry  `json:"entries"`
+}
+
+/*
+NewStack checks that the interferograms share their geometry and sorts
+them. Duplicated pairs are kept, they are reported by Check.
+*/
+func NewStack(entries ...Entry) (s Stack, err error) {
+	for ii, e := range entries {
+		g, err := e.Geometry()
+		if err != nil {
+			return s, errors.WrapFmt(err,
+				"failed to read the geometry of interferogram '%s'", e.Pair)
+		}
+
+		if ii == 0 {
+			s.Geometry = g
+		} else if err = s.Geometry.MustSame(g); err != nil {
+			return s, errors.WrapFmt(err, "interferogram '%s' does not "+
+				"match the geometry of '%s'", e.Pair, entries[0].Pair)
+		}
+	}
+
+	s.Entries = append([]Entry(nil), entries...)
+	s.sort()
+
+	return s, nil
+}
+
+func (s *Stack) sort() {
+	sort.SliceStable(s.Entries, func(ii, jj int) bool {
+		pi, pj := s.Entries[ii].Pair, s.Entries[jj].Pair
+
+		if !pi.Reference.Equal(pj.Reference) {
+			return pi.Reference.Before(pj.Reference)
+		}
+		return pi.Secondary.Before(pj.Secondary)
+	})
+}
+
+/*
+LoadStack loads every interferogram of the directory. Interferograms are
+found by their diff_par files and their pairs are read from their names,
+e.g. "20200301_20200313.diff" with "20200301_20200313.diff.diff_par".
+*/
+func LoadStack(dir path.Dir) (s Stack, err error) {
+	const ext = ".diff_par"
+
+	glob, err := dir.Join("*" + ext).Glob()
+	if err != nil {
+		return
+	}
+
+	entries := make([]Entry, 0, len(glob))
+
+	for _, diffPar := range glob {
+		dat := path.New(strings.TrimSuffix(diffPar.String(), ext))
+
+		pair, err := ParsePair(dat.Base().String())
+		if err != nil {
+			return s, err
+		}
+
+		f, err := New(dat).Load()
+		if err != nil {
+			return s, errors.WrapFmt(err, "failed to load interferogram '%s'",
+				dat)
+		}
+
+		entries = append(entries, Entry{File: f, Pair: pair})
+	}
+
+	if len(entries) == 0 {
+		return s, fmt.Errorf("no interferograms found in '%s'", dir)
+	}
+
+	return NewStack(entries...)
+}
+
+func (s Stack) Len() int {
+	return len(s.Entries)
+}
+
+// Each calls fn with every interferogram of the stack until it returns an
+// error.
+func (s Stack) Each(fn func(Entry) error) (err error) {
+	for _, e := range s.Entries {
+		if err = fn(e); err != nil {
+			return errors.WrapFmt(err, "failed to process interferogram '%s'",
+				e.Pair)
+		}
+	}
+	return nil
+}
+
+// Pairs returns the pairs of the interferograms in the stack.
+func (s Stack) Pairs() (p []Pair) {
+	p = make([]Pair, len(s.Entries))
+	for ii, e := range s.Entries {
+		p[ii] = e.Pair
+	}
+	return
+}
+
+// Epochs returns the sorted, distinct dates of the scenes of the stack.
+func (s Stack) Epochs() (t []time.Time) {
+	seen := make(map[time.Time]bool)
+
+	for _, e := range s.Entries {
+		for _, d := range []time.Time{e.Pair.Reference, e.Pair.Secondary} {
+			if !seen[d] {
+				seen[d] = true
+				t = append(t, d)
+			}
+		}
+	}
+
+	sort.Slice(t, func(ii, jj int) bool {
+		return t[ii].Before(t[jj])
+	})
+	return
+}
+
+// Select returns the stack of the interferograms for which keep is true.
+func (s Stack) Select(keep func(Entry) bool) (sub Stack) {
+	sub.Geometry = s.Geometry
+
+	for _, e := range s.Entries {
+		if keep(e) {
+			sub.Entries = append(sub.Entries, e)
+		}
+	}
+	return
+}
+
+// Between selects the interferograms with both scenes acquired within
+// [start, stop].
+func (s Stack) Between(start, stop time.Time) (sub Stack) {
+	start, stop = day(start), day(stop)
+
+	return s.Select(func(e Entry) bool {
+		return !e.Pair.Reference.Before(start) && !e.Pair.Secondary.After(stop)
+	})
+}
+
+// MaxTemporal selects the interferograms with temporal baselines not
+// longer than max.
+func (s Stack) MaxTemporal(max time.Duration) (sub Stack) {
+	return s.Select(func(e Entry) bool {
+		return e.Pair.Temporal() <= max
+	})
+}
+
+/*
+MaxPerpendicular selects the interferograms with perpendicular baselines
+not longer than max metres. The baselines of the pairs are derived from
+the baselines of their scenes in the table; pairs with a scene missing
+from the table are dropped.
+*/
+func (s Stack) MaxPerpendicular(t baseline.Table, max float64) (sub Stack) {
+	perp := make(map[time.Time]float64)
+	for _, b := range t.Baselines {
+		perp[day(b.Date)] = b.Perpendicular
+	}
+
+	return s.Select(func(e Entry) bool {
+		ref, ok1 := perp[e.Pair.Reference]
+		sec, ok2 := perp[e.Pair.Secondary]
+
+		d := sec - ref
+		return ok1 && ok2 && d <= max && d >= -max
+	})
+}
+
+// Report summarises the problems of the pair graph of the stack.
+type Report struct {
+	// Pairs that occur more than once.
+	Duplicates []string `json:"duplicates"`
+
+	// Expected pairs that are not in the stack.
+	Missing []string `json:"missing"`
+
+	// Epochs of the expected pairs without any interferogram.
+	Isolated []string `json:"isolated"`
+
+	// Epochs of the connected subsets of the pair graph.
+	Subsets [][]string `json:"subsets"`
+}
+
+func (r Report) OK() bool {
+	return len(r.Duplicates) == 0 && len(r.Missing) == 0 &&
+		len(r.Isolated) == 0 && len(r.Subsets) <= 1
+}
+
+// Err returns an error describing the problems, nil if there are none.
+func (r Report) Err() (err error) {
+	if r.OK() {
+		return nil
+	}
+
+	var msgs []string
+
+	if len(r.Duplicates) > 0 {
+		msgs = append(msgs, "duplicated pairs: "+
+			strings.Join(r.Duplicates, ", "))
+	}
+
+	if len(r.Missing) > 0 {
+		msgs = append(msgs, "missing pairs: "+strings.Join(r.Missing, ", "))
+	}
+
+	if len(r.Isolated) > 0 {
+		msgs = append(msgs, "isolated epochs: "+
+			strings.Join(r.Isolated, ", "))
+	}
+
+	if len(r.Subsets) > 1 {
+		sets := make([]string, len(r.Subsets))
+		for ii, s := range r.Subsets {
+			sets[ii] = "[" + strings.Join(s, " ") + "]"
+		}
+
+		msgs = append(msgs, fmt.Sprintf("%d disconnected subsets: %s",
+			len(r.Subsets), strings.Join(sets, ", ")))
+	}
+
+	return fmt.Errorf("interferogram stack is inconsistent, %s",
+		strings.Join(msgs, "; "))
+}
+
+/*
+Check builds the pair graph of the stack and reports duplicated pairs,
+disconnected subsets of epochs and, if the expected pairs are given, the
+missing pairs and epochs.
+*/
+func (s Stack) Check(expected []Pair) (r Report) {
+	count := make(map[Pair]int)
+	for _, e := range s.Entries {
+		if count[e.Pair]++; count[e.Pair] == 2 {
+			r.Duplicates = append(r.Duplicates, e.Pair.ID())
+		}
+	}
+
+	epochs := s.Epochs()
+	covered := make(map[time.Time]bool)
+	for _, t := range epochs {
+		covered[t] = true
+	}
+
+	isolated := make(map[time.Time]bool)
+	for _, p := range expected {
+		p = NewPair(p.Reference, p.Secondary)
+
+		if count[p] == 0 {
+			r.Missing = append(r.Missing, p.ID())
+		}
+
+		for _, t := range []time.Time{p.Reference, p.Secondary} {
+			if !covered[t] && !isolated[t] {
+				isolated[t] = true
+				r.Isolated = append(r.Isolated, date.Short.Format(t))
+			}
+		}
+	}
+	sort.Strings(r.Isolated)
+
+	parent := make(map[time.Time]time.Time)
+	var find func(time.Time) time.Time
+	find = func(t time.Time) time.Time {
+		if p, ok := parent[t]; ok && !p.Equal(t) {
+			parent[t] = find(p)
+			return parent[t]
+		}
+		return t
+	}
+
+	for _, e := range s.Entries {
+		parent[find(e.Pair.Reference)] = find(e.Pair.Secondary)
+	}
+
+	index := make(map[time.Time]int)
+	for _, t := range epochs {
+		root := find(t)
+
+		idx, ok := index[root]
+		if !ok {
+			idx = len(r.Subsets)
+			index[root] = idx
+			r.Subsets = append(r.Subsets, nil)
+		}
+
+		r.Subsets[idx] = append(r.Subsets[idx], date.Short.Format(t))
+	}
+
+	return
+}
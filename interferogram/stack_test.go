@@ -0,0 +1,174 @@
+package interferogram
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bozso/gomma/baseline"
+	"github.com/bozso/gomma/data"
+)
+
+func mustDate(t *testing.T, s string) time.Time {
+	d, err := time.Parse("20060102", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func testPair(t *testing.T, one, two string) Pair {
+	return NewPair(mustDate(t, one), mustDate(t, two))
+}
+
+// testStack creates a stack of the pairs without interferogram files.
+func testStack(t *testing.T, pairs ...[2]string) (s Stack) {
+	for _, p := range pairs {
+		s.Entries = append(s.Entries, Entry{Pair: testPair(t, p[0], p[1])})
+	}
+	s.sort()
+	return
+}
+
+func ids(s Stack) (id []string) {
+	for _, p := range s.Pairs() {
+		id = append(id, p.ID())
+	}
+	return
+}
+
+func TestParsePair(t *testing.T) {
+	for name, expected := range map[string]string{
+		"/data/IFG/20200301_20200313.diff":       "20200301_20200313",
+		"20200313_20200301.diff":                 "20200301_20200313",
+		"S1A_20191220_20200101.VV.diff.diff_par": "20191220_20200101",
+	} {
+		p, err := ParsePair(name)
+		if err != nil {
+			t.Errorf("failed to parse '%s': %s", name, err)
+			continue
+		}
+
+		if id := p.ID(); id != expected {
+			t.Errorf("expected pair '%s' from '%s', got '%s'", expected,
+				name, id)
+		}
+	}
+
+	for _, name := range []string{"20200301.diff", "20201301_20201313.diff"} {
+		if _, err := ParsePair(name); err == nil {
+			t.Errorf("expected an error for '%s'", name)
+		}
+	}
+
+	p := testPair(t, "20200301", "20200313")
+	if d := p.Temporal(); d != 12*24*time.Hour {
+		t.Errorf("expected a temporal baseline of 12 days, got %s", d)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	s := testStack(t,
+		[2]string{"20200113", "20200101"},
+		[2]string{"20200101", "20200113"},
+		[2]string{"20200201", "20200213"},
+	)
+
+	if ids := ids(s); ids[0] != "20200101_20200113" || ids[2] != "20200201_20200213" {
+		t.Errorf("stack is not sorted by date: %v", ids)
+	}
+
+	r := s.Check([]Pair{
+		testPair(t, "20200101", "20200113"),
+		testPair(t, "20200125", "20200113"),
+	})
+
+	expected := Report{
+		Duplicates: []string{"20200101_20200113"},
+		Missing:    []string{"20200113_20200125"},
+		Isolated:   []string{"20200125"},
+		Subsets: [][]string{
+			{"20200101", "20200113"},
+			{"20200201", "20200213"},
+		},
+	}
+
+	if !reflect.DeepEqual(r, expected) {
+		t.Errorf("expected report %+v, got %+v", expected, r)
+	}
+
+	if r.OK() || r.Err() == nil {
+		t.Errorf("expected the report to fail")
+	}
+
+	r = testStack(t,
+		[2]string{"20200101", "20200113"},
+		[2]string{"20200113", "20200125"},
+	).Check(nil)
+
+	if !r.OK() || r.Err() != nil {
+		t.Errorf("expected a consistent stack, got %+v", r)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	s := testStack(t,
+		[2]string{"20200101", "20200113"},
+		[2]string{"20200113", "20200125"},
+		[2]string{"20200101", "20200125"},
+		[2]string{"20200125", "20200206"},
+	)
+
+	// the stop day is included even if the time of day is later
+	sub := s.Between(mustDate(t, "20200101"),
+		mustDate(t, "20200125").Add(6*time.Hour))
+
+	expected := []string{"20200101_20200113", "20200101_20200125",
+		"20200113_20200125"}
+	if got := ids(sub); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected pairs %v between the dates, got %v", expected, got)
+	}
+
+	sub = s.MaxTemporal(12 * 24 * time.Hour)
+	if sub.Len() != 3 {
+		t.Errorf("expected 3 pairs of 12 days, got %v", ids(sub))
+	}
+
+	table := baseline.Table{Baselines: []baseline.Baseline{
+		{Date: mustDate(t, "20200101").Add(5 * time.Hour), Perpendicular: 0},
+		{Date: mustDate(t, "20200113").Add(5 * time.Hour), Perpendicular: 80},
+		{Date: mustDate(t, "20200125").Add(5 * time.Hour), Perpendicular: -50},
+	}}
+
+	// 20200206 is missing from the table so its pair is dropped
+	sub = s.MaxPerpendicular(table, 100)
+
+	expected = []string{"20200101_20200113", "20200101_20200125"}
+	if got := ids(sub); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected pairs %v within 100 m, got %v", expected, got)
+	}
+}
+
+func TestGeometry(t *testing.T) {
+	g := Geometry{
+		Shape:        data.RngAzi{Rng: 1000, Azi: 500},
+		RangeLooks:   4,
+		AzimuthLooks: 1,
+	}
+
+	if err := g.MustSame(g); err != nil {
+		t.Error(err)
+	}
+
+	other := g
+	other.Shape.Azi = 501
+	if err := g.MustSame(other); err == nil {
+		t.Errorf("expected an error for different shapes")
+	}
+
+	other = g
+	other.RangeLooks = 5
+	if err := g.MustSame(other); err == nil {
+		t.Errorf("expected an error for different multi-looking factors")
+	}
+}
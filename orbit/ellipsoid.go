@@ -0,0 +1,133 @@
+package orbit
+
+import (
+	"fmt"
+	"math"
+)
+
+// Vector is a cartesian vector in the Earth-centred, Earth-fixed frame.
+type Vector [3]float64
+
+func (v Vector) Add(w Vector) Vector {
+	return Vector{v[0] + w[0], v[1] + w[1], v[2] + w[2]}
+}
+
+func (v Vector) Sub(w Vector) Vector {
+	return Vector{v[0] - w[0], v[1] - w[1], v[2] - w[2]}
+}
+
+func (v Vector) Scale(f float64) Vector {
+	return Vector{f * v[0], f * v[1], f * v[2]}
+}
+
+func (v Vector) Dot(w Vector) float64 {
+	return v[0]*w[0] + v[1]*w[1] + v[2]*w[2]
+}
+
+func (v Vector) Cross(w Vector) Vector {
+	return Vector{
+		v[1]*w[2] - v[2]*w[1],
+		v[2]*w[0] - v[0]*w[2],
+		v[0]*w[1] - v[1]*w[0],
+	}
+}
+
+func (v Vector) Norm() float64 {
+	return math.Sqrt(v.Dot(v))
+}
+
+func (v Vector) Unit() Vector {
+	return v.Scale(1.0 / v.Norm())
+}
+
+func (v Vector) String() string {
+	return fmt.Sprintf("(%.4f, %.4f, %.4f)", v[0], v[1], v[2])
+}
+
+// Geodetic is a position given with geodetic latitude and longitude in
+// degrees and the height above the ellipsoid in metres.
+type Geodetic struct {
+	Lat    float64 `json:"lat"`
+	Lon    float64 `json:"lon"`
+	Height float64 `json:"height"`
+}
+
+func (g Geodetic) String() string {
+	return fmt.Sprintf("%.7f,%.7f,%.3f", g.Lat, g.Lon, g.Height)
+}
+
+// Ellipsoid is a reference ellipsoid given by its semi axes in metres.
+type Ellipsoid struct {
+	SemiMajor float64 `json:"semi_major"`
+	SemiMinor float64 `json:"semi_minor"`
+}
+
+var WGS84 = Ellipsoid{
+	SemiMajor: 6378137.0,
+	SemiMinor: 6356752.314245,
+}
+
+// E2 returns the square of the first eccentricity.
+func (e Ellipsoid) E2() float64 {
+	a2 := e.SemiMajor * e.SemiMajor
+	return (a2 - e.SemiMinor*e.SemiMinor) / a2
+}
+
+// ToECEF converts geodetic coordinates to cartesian ones.
+func (e Ellipsoid) ToECEF(g Geodetic) (v Vector) {
+	lat, lon := g.Lat*deg2rad, g.Lon*deg2rad
+	slat, clat := math.Sincos(lat)
+	slon, clon := math.Sincos(lon)
+
+	e2 := e.E2()
+	// prime vertical radius of curvature
+	n := e.SemiMajor / math.Sqrt(1.0-e2*slat*slat)
+
+	return Vector{
+		(n + g.Height) * clat * clon,
+		(n + g.Height) * clat * slon,
+		(n*(1.0-e2) + g.Height) * slat,
+	}
+}
+
+/*
+ToGeodetic converts cartesian coordinates to geodetic ones. The latitude
+is found by fixed point iteration, which converges to below a
+millimetre in a few steps for points near the surface of the Earth.
+*/
+func (e Ellipsoid) ToGeodetic(v Vector) (g Geodetic) {
+	x, y, z := v[0], v[1], v[2]
+	e2 := e.E2()
+	p := math.Hypot(x, y)
+
+	lon := math.Atan2(y, x)
+	lat := math.Atan2(z, p*(1.0-e2))
+
+	var n, h float64
+	for ii := 0; ii < 10; ii++ {
+		slat, clat := math.Sincos(lat)
+		n = e.SemiMajor / math.Sqrt(1.0-e2*slat*slat)
+
+		// stable near the poles as well, unlike p / cos(lat) - n
+		h = p*clat + (z+e2*n*slat)*slat - n
+
+		prev := lat
+		lat = math.Atan2(z, p*(1.0-e2*n/(n+h)))
+
+		if math.Abs(lat-prev) < 1e-12 {
+			break
+		}
+	}
+
+	return Geodetic{
+		Lat:    lat * rad2deg,
+		Lon:    lon * rad2deg,
+		Height: h,
+	}
+}
+
+const (
+	deg2rad      = math.Pi / 180.0
+	rad2deg      = 180.0 / math.Pi
+	SpeedOfLight = 299792458.0
+)
@@ -0,0 +1,277 @@
+package orbit
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+)
+
+// RadarPoint is a position in the radar geometry given in fractional
+// range samples and azimuth lines.
+type RadarPoint struct {
+	Rng float64 `json:"range"`
+	Azi float64 `json:"azimuth"`
+}
+
+func (r RadarPoint) String() string {
+	return fmt.Sprintf("%.3f,%.3f", r.Rng, r.Azi)
+}
+
+const (
+	maxIter       = 50
+	timeTolerance = 1e-9
+	posTolerance  = 1e-4
+)
+
+// Time returns the azimuth time of the line.
+func (s SAR) Time(line float64) float64 {
+	return s.StartTime + line*s.AzimuthLineTime
+}
+
+// Range returns the slant range of the sample.
+func (s SAR) Range(sample float64) float64 {
+	return s.NearRange + sample*s.RangePixelSpacing
+}
+
+// CenterTime returns the azimuth time of the middle of the image.
+func (s SAR) CenterTime() float64 {
+	return s.Time(float64(s.AzimuthLines-1) / 2.0)
+}
+
+/*
+ToRadar returns the pixel of the point with the given geographic
+coordinates. The azimuth time is found with Newton iterations so that
+the Doppler frequency of the point equals the Doppler centroid of the
+image at its slant range.
+*/
+func (s SAR) ToRadar(g Geodetic) (r RadarPoint, err error) {
+	r, _, err = s.toRadar(s.Ellipsoid.ToECEF(g), s.CenterTime())
+	return
+}
+
+// toRadar solves the range-Doppler equations starting from the azimuth
+// time t.
+func (s SAR) toRadar(target Vector, t float64) (r RadarPoint, tout float64, err error) {
+	lambda := s.Wavelength()
+
+	var sv StateVector
+	var rng float64
+
+	for ii := 0; ; ii++ {
+		if ii == maxIter {
+			err = fmt.Errorf("zero Doppler iteration did not converge for "+
+				"point %s", target)
+			return
+		}
+
+		if sv, err = s.Orbit.Interpolate(t); err != nil {
+			return
+		}
+
+		d := target.Sub(sv.Position)
+		rng = d.Norm()
+
+		f := sv.Velocity.Dot(d) - 0.5*lambda*s.DopplerAt(rng)*rng
+		dt := f / sv.Velocity.Dot(sv.Velocity)
+		t += dt
+
+		if math.Abs(dt) < timeTolerance {
+			break
+		}
+	}
+
+	r = RadarPoint{
+		Rng: (rng - s.NearRange) / s.RangePixelSpacing,
+		Azi: (t - s.StartTime) / s.AzimuthLineTime,
+	}
+
+	return r, t, nil
+}
+
+// solve3 solves the linear system m x = b with Cramer's rule.
+func solve3(m [3]Vector, b Vector) (x Vector, ok bool) {
+	det := m[0].Dot(m[1].Cross(m[2]))
+	if math.Abs(det) < 1e-300 {
+		return x, false
+	}
+
+	// columns of the matrix
+	c := [3]Vector{
+		{m[0][0], m[1][0], m[2][0]},
+		{m[0][1], m[1][1], m[2][1]},
+		{m[0][2], m[1][2], m[2][2]},
+	}
+
+	x[0] = b.Dot(c[1].Cross(c[2])) / det
+	x[1] = c[0].Dot(b.Cross(c[2])) / det
+	x[2] = c[0].Dot(c[1].Cross(b)) / det
+
+	return x, true
+}
+
+/*
+ToGeo returns the geographic coordinates of the pixel at the given
+height above the ellipsoid. The target is the intersection of the range
+sphere, the Doppler cone and the ellipsoid inflated by the height, found
+with Newton iterations starting from a point on the right side of the
+ground track.
+*/
+func (s SAR) ToGeo(r RadarPoint, height float64) (g Geodetic, err error) {
+	sv, err := s.Orbit.Interpolate(s.Time(r.Azi))
+	if err != nil {
+		return
+	}
+
+	rng := s.Range(r.Rng)
+	pos, vel := sv.Position, sv.Velocity
+	doppler := 0.5 * s.Wavelength() * s.DopplerAt(rng) * rng
+
+	// initial guess: move from the nadir to the right of the track by
+	// the ground range of a spherical Earth
+	up := pos.Unit()
+	nadir := s.Ellipsoid.ToGeodetic(pos)
+	nadir.Height = height
+	target := s.Ellipsoid.ToECEF(nadir)
+
+	if h := pos.Sub(target).Norm(); rng > h {
+		right := vel.Cross(up).Unit()
+		target = target.Add(right.Scale(math.Sqrt(rng*rng - h*h)))
+	}
+
+	// the height above the inflated ellipsoid differs slightly from the
+	// geodetic height, so it is corrected in a few outer iterations
+	dh := height
+
+	for outer := 0; outer < 5; outer++ {
+		a := s.Ellipsoid.SemiMajor + dh
+		b := s.Ellipsoid.SemiMinor + dh
+		a2, b2 := a*a, b*b
+
+		for ii := 0; ; ii++ {
+			if ii == maxIter {
+				err = fmt.Errorf("geolocation of pixel %s did not converge", r)
+				return
+			}
+
+			d := target.Sub(pos)
+			f := Vector{
+				d.Dot(d) - rng*rng,
+				vel.Dot(d) - doppler,
+				(target[0]*target[0]+target[1]*target[1])/a2 +
+					target[2]*target[2]/b2 - 1.0,
+			}
+
+			jac := [3]Vector{
+				d.Scale(2.0),
+				vel,
+				{2.0 * target[0] / a2, 2.0 * target[1] / a2, 2.0 * target[2] / b2},
+			}
+
+			step, ok := solve3(jac, f)
+			if !ok {
+				err = fmt.Errorf("singular geometry at pixel %s", r)
+				return
+			}
+
+			target = target.Sub(step)
+
+			if step.Norm() < posTolerance {
+				break
+			}
+		}
+
+		g = s.Ellipsoid.ToGeodetic(target)
+		diff := height - g.Height
+
+		if math.Abs(diff) < 1e-3 {
+			break
+		}
+		dh += diff
+	}
+
+	return g, nil
+}
+
+// chunks calls fn with consecutive ranges of n items in parallel.
+func chunks(n int, fn func(first, last int) error) (err error) {
+	workers := runtime.NumCPU()
+	if workers > n {
+		workers = n
+	}
+
+	if workers == 0 {
+		return nil
+	}
+
+	size := (n + workers - 1) / workers
+	errs := make([]error, workers)
+
+	var wg sync.WaitGroup
+	for ii := 0; ii < workers; ii++ {
+		first, last := ii*size, (ii+1)*size
+		if last > n {
+			last = n
+		}
+
+		wg.Add(1)
+		go func(ii, first, last int) {
+			defer wg.Done()
+			errs[ii] = fn(first, last)
+		}(ii, first, last)
+	}
+	wg.Wait()
+
+	for _, err = range errs {
+		if err != nil {
+			return
+		}
+	}
+
+	return nil
+}
+
+/*
+ToRadarBatch transforms the points in parallel. Neighbouring points
+usually have similar azimuth times, so the solution of each point is
+used as the starting value of the next one, which saves most of the
+iterations.
+*/
+func (s SAR) ToRadarBatch(pts []Geodetic) (r []RadarPoint, err error) {
+	r = make([]RadarPoint, len(pts))
+
+	err = chunks(len(pts), func(first, last int) (err error) {
+		t := s.CenterTime()
+
+		for ii := first; ii < last; ii++ {
+			r[ii], t, err = s.toRadar(s.Ellipsoid.ToECEF(pts[ii]), t)
+			if err != nil {
+				return
+			}
+		}
+		return nil
+	})
+
+	return
+}
+
+// ToGeoBatch transforms the pixels with their heights in parallel.
+func (s SAR) ToGeoBatch(pts []RadarPoint, heights []float64) (g []Geodetic, err error) {
+	if len(pts) != len(heights) {
+		return nil, fmt.Errorf("got %d pixels but %d heights", len(pts),
+			len(heights))
+	}
+
+	g = make([]Geodetic, len(pts))
+
+	err = chunks(len(pts), func(first, last int) (err error) {
+		for ii := first; ii < last; ii++ {
+			if g[ii], err = s.ToGeo(pts[ii], heights[ii]); err != nil {
+				return
+			}
+		}
+		return nil
+	})
+
+	return
+}
@@ -0,0 +1,168 @@
+package orbit
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+
+	"github.com/bozso/gotoolbox/errors"
+)
+
+// Pass is the direction of the satellite along its orbit.
+type Pass int
+
+const (
+	Ascending Pass = iota
+	Descending
+)
+
+func (p *Pass) Set(s string) (err error) {
+	const mode errors.Mode = "orbit direction"
+
+	switch strings.ToLower(s) {
+	case "ascending", "asc", "a":
+		*p = Ascending
+	case "descending", "desc", "d":
+		*p = Descending
+	default:
+		err = mode.Error(s)
+	}
+
+	return
+}
+
+func (p Pass) String() (s string) {
+	switch p {
+	case Ascending:
+		s = "ascending"
+	case Descending:
+		s = "descending"
+	default:
+		s = "unknown"
+	}
+	return
+}
+
+func (p Pass) MarshalJSON() (b []byte, err error) {
+	return json.Marshal(p.String())
+}
+
+func (p *Pass) UnmarshalJSON(b []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(b, &s); err != nil {
+		return
+	}
+
+	return p.Set(s)
+}
+
+// Up returns the normal of the ellipsoid at the point.
+func (g Geodetic) Up() Vector {
+	slat, clat := math.Sincos(g.Lat * deg2rad)
+	slon, clon := math.Sincos(g.Lon * deg2rad)
+
+	return Vector{clat * clon, clat * slon, slat}
+}
+
+// North returns the unit vector pointing north in the local horizontal
+// plane.
+func (g Geodetic) North() Vector {
+	slat, clat := math.Sincos(g.Lat * deg2rad)
+	slon, clon := math.Sincos(g.Lon * deg2rad)
+
+	return Vector{-slat * clon, -slat * slon, clat}
+}
+
+// East returns the unit vector pointing east in the local horizontal
+// plane.
+func (g Geodetic) East() Vector {
+	slon, clon := math.Sincos(g.Lon * deg2rad)
+
+	return Vector{-slon, clon, 0.0}
+}
+
+func angle(v, w Vector) float64 {
+	c := v.Dot(w) / (v.Norm() * w.Norm())
+	return math.Acos(math.Max(-1.0, math.Min(1.0, c))) * rad2deg
+}
+
+/*
+LookAngle returns the off-nadir angle of the line of sight from the
+satellite to the target in degrees. Nadir is the direction of the
+ellipsoid normal below the satellite.
+*/
+func (e Ellipsoid) LookAngle(sat, target Vector) float64 {
+	down := e.ToGeodetic(sat).Up().Scale(-1.0)
+	return angle(target.Sub(sat), down)
+}
+
+// IncidenceAngle returns the angle between the ellipsoid normal at the
+// target and the direction towards the satellite in degrees.
+func (e Ellipsoid) IncidenceAngle(sat, target Vector) float64 {
+	return angle(sat.Sub(target), e.ToGeodetic(target).Up())
+}
+
+/*
+Heading returns the direction of the ground track of the state vector
+in degrees measured clockwise from north, between -180 and 180. It is
+computed from the velocity projected onto the horizontal plane below the
+satellite.
+*/
+func (e Ellipsoid) Heading(sv StateVector) float64 {
+	g := e.ToGeodetic(sv.Position)
+
+	return math.Atan2(sv.Velocity.Dot(g.East()),
+		sv.Velocity.Dot(g.North())) * rad2deg
+}
+
+// Pass returns whether the satellite moves north or south.
+func (e Ellipsoid) Pass(sv StateVector) Pass {
+	g := e.ToGeodetic(sv.Position)
+
+	if sv.Velocity.Dot(g.North()) >= 0.0 {
+		return Ascending
+	}
+	return Descending
+}
+
+// Heading returns the heading of the satellite in the middle of the
+// image.
+func (s SAR) Heading() (h float64, err error) {
+	sv, err := s.Orbit.Interpolate(s.CenterTime())
+	if err != nil {
+		return
+	}
+
+	return s.Ellipsoid.Heading(sv), nil
+}
+
+// Pass returns the direction of the orbit in the middle of the image.
+func (s SAR) Pass() (p Pass, err error) {
+	sv, err := s.Orbit.Interpolate(s.CenterTime())
+	if err != nil {
+		return
+	}
+
+	return s.Ellipsoid.Pass(sv), nil
+}
+
+// Angles returns the look and incidence angles of the pixel at the given
+// height in degrees.
+func (s SAR) Angles(r RadarPoint, height float64) (look, inc float64, err error) {
+	sv, err := s.Orbit.Interpolate(s.Time(r.Azi))
+	if err != nil {
+		return
+	}
+
+	g, err := s.ToGeo(r, height)
+	if err != nil {
+		return
+	}
+
+	target := s.Ellipsoid.ToECEF(g)
+
+	look = s.Ellipsoid.LookAngle(sv.Position, target)
+	inc = s.Ellipsoid.IncidenceAngle(sv.Position, target)
+
+	return look, inc, nil
+}
@@ -0,0 +1,236 @@
+/*
+Package orbit implements the satellite geometry needed for native
+geolocation: reading and interpolating orbit state vectors of GAMMA
+parameter files, the viewing geometry of the satellite and converting
+points between the radar and geographic coordinates with a range-Doppler
+solver.
+*/
+package orbit
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bozso/gotoolbox/errors"
+)
+
+// StateVector is the position and velocity of the satellite at a time
+// given in seconds since the start of the day of the acquisition.
+type StateVector struct {
+	Time     float64 `json:"time"`
+	Position Vector  `json:"position"`
+	Velocity Vector  `json:"velocity"`
+}
+
+// Number of state vectors used for Lagrange interpolation.
+const DefaultOrder = 8
+
+// Interpolation selects the method of state vector interpolation.
+type Interpolation int
+
+const (
+	// Lagrange polynomials fitted to the nearest positions and velocities.
+	Lagrange Interpolation = iota
+	// Cubic Hermite splines of the positions and velocities of the two
+	// enclosing state vectors.
+	Hermite
+)
+
+func (i *Interpolation) Set(s string) (err error) {
+	const mode errors.Mode = "orbit interpolation"
+
+	switch strings.ToLower(s) {
+	case "lagrange":
+		*i = Lagrange
+	case "hermite":
+		*i = Hermite
+	default:
+		err = mode.Error(s)
+	}
+
+	return
+}
+
+func (i Interpolation) String() (s string) {
+	switch i {
+	case Lagrange:
+		s = "lagrange"
+	case Hermite:
+		s = "hermite"
+	default:
+		s = "unknown"
+	}
+	return
+}
+
+func (i Interpolation) MarshalJSON() (b []byte, err error) {
+	return json.Marshal(i.String())
+}
+
+func (i *Interpolation) UnmarshalJSON(b []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(b, &s); err != nil {
+		return
+	}
+
+	return i.Set(s)
+}
+
+// Orbit holds state vectors sorted by their time.
+type Orbit struct {
+	Vectors []StateVector `json:"state_vectors"`
+
+	Method Interpolation `json:"method"`
+
+	// Number of points used for Lagrange interpolation, defaults to
+	// DefaultOrder.
+	Order int `json:"order"`
+}
+
+func New(sv []StateVector) (o Orbit, err error) {
+	if len(sv) < 2 {
+		return o, fmt.Errorf("at least 2 state vectors are needed, got %d",
+			len(sv))
+	}
+
+	o.Vectors = make([]StateVector, len(sv))
+	copy(o.Vectors, sv)
+
+	sort.Slice(o.Vectors, func(ii, jj int) bool {
+		return o.Vectors[ii].Time < o.Vectors[jj].Time
+	})
+
+	o.Order = DefaultOrder
+	return o, nil
+}
+
+func (o Orbit) Start() float64 {
+	return o.Vectors[0].Time
+}
+
+func (o Orbit) Stop() float64 {
+	return o.Vectors[len(o.Vectors)-1].Time
+}
+
+// window returns the range of the state vectors closest to t used for
+// interpolation.
+func (o Orbit) window(t float64) (first, last int) {
+	n := len(o.Vectors)
+	order := o.Order
+	if order <= 0 {
+		order = DefaultOrder
+	}
+
+	if order > n {
+		order = n
+	}
+
+	// index of the first vector after t
+	idx := sort.Search(n, func(ii int) bool {
+		return o.Vectors[ii].Time > t
+	})
+
+	first = idx - order/2
+	if first < 0 {
+		first = 0
+	}
+
+	if first+order > n {
+		first = n - order
+	}
+
+	return first, first + order
+}
+
+/*
+Interpolate returns the position and velocity of the satellite at time t
+with the interpolation method of the orbit. Times outside of the orbit
+are refused, as the interpolating polynomials quickly diverge there.
+*/
+func (o Orbit) Interpolate(t float64) (sv StateVector, err error) {
+	if t < o.Start() || t > o.Stop() {
+		err = fmt.Errorf("time %.6f s is outside of the orbit [%.6f, %.6f]",
+			t, o.Start(), o.Stop())
+		return
+	}
+
+	switch o.Method {
+	case Lagrange:
+		sv = o.lagrange(t)
+	case Hermite:
+		sv = o.hermite(t)
+	default:
+		err = fmt.Errorf("unknown interpolation method %d", o.Method)
+	}
+
+	return
+}
+
+// lagrange fits polynomials to the nearest state vectors.
+func (o Orbit) lagrange(t float64) (sv StateVector) {
+	first, last := o.window(t)
+	vec := o.Vectors[first:last]
+
+	sv.Time = t
+
+	for ii, vi := range vec {
+		w := 1.0
+		for jj, vj := range vec {
+			if ii != jj {
+				w *= (t - vj.Time) / (vi.Time - vj.Time)
+			}
+		}
+
+		sv.Position = sv.Position.Add(vi.Position.Scale(w))
+		sv.Velocity = sv.Velocity.Add(vi.Velocity.Scale(w))
+	}
+
+	return
+}
+
+/*
+hermite interpolates the position with a cubic Hermite spline between
+the enclosing state vectors, which matches both of their positions and
+velocities. The velocity is the derivative of the spline.
+*/
+func (o Orbit) hermite(t float64) (sv StateVector) {
+	n := len(o.Vectors)
+	idx := sort.Search(n, func(ii int) bool {
+		return o.Vectors[ii].Time > t
+	})
+
+	if idx == 0 {
+		idx = 1
+	}
+
+	if idx >= n {
+		idx = n - 1
+	}
+
+	v0, v1 := o.Vectors[idx-1], o.Vectors[idx]
+	dt := v1.Time - v0.Time
+	x := (t - v0.Time) / dt
+	x2, x3 := x*x, x*x*x
+
+	// basis functions and their derivatives
+	h00, h10 := 2*x3-3*x2+1, x3-2*x2+x
+	h01, h11 := -2*x3+3*x2, x3-x2
+
+	d00, d10 := (6*x2-6*x)/dt, 3*x2-4*x+1
+	d01, d11 := (-6*x2+6*x)/dt, 3*x2-2*x
+
+	sv.Time = t
+	sv.Position = v0.Position.Scale(h00).
+		Add(v0.Velocity.Scale(h10 * dt)).
+		Add(v1.Position.Scale(h01)).
+		Add(v1.Velocity.Scale(h11 * dt))
+
+	sv.Velocity = v0.Position.Scale(d00).
+		Add(v0.Velocity.Scale(d10)).
+		Add(v1.Position.Scale(d01)).
+		Add(v1.Velocity.Scale(d11))
+
+	return
+}
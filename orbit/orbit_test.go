@@ -0,0 +1,180 @@
+package orbit
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/bozso/gomma/utils/params"
+)
+
+const (
+	testRadius = 7071000.0
+	// angular velocity of the test orbit
+	testOmega = 2.0 * math.Pi / 6000.0
+)
+
+// circular polar orbit in the x-z plane
+func testState(t float64) (pos, vel Vector) {
+	s, c := math.Sincos(testOmega * t)
+	pos = Vector{testRadius * c, 0.0, testRadius * s}
+	vel = Vector{-testRadius * testOmega * s, 0.0, testRadius * testOmega * c}
+	return
+}
+
+func testPar() string {
+	sb := &strings.Builder{}
+
+	fmt.Fprintf(sb, "start_time:              100.000000   s\n")
+	fmt.Fprintf(sb, "azimuth_line_time:       2.0000000e-03   s\n")
+	fmt.Fprintf(sb, "range_samples:           1000\n")
+	fmt.Fprintf(sb, "azimuth_lines:           5000\n")
+	fmt.Fprintf(sb, "range_pixel_spacing:     10.000000   m\n")
+	fmt.Fprintf(sb, "near_range_slc:          800000.0000  m\n")
+	fmt.Fprintf(sb, "center_range_slc:        805000.0000  m\n")
+	fmt.Fprintf(sb, "radar_frequency:         5.4050005e+09   Hz\n")
+	fmt.Fprintf(sb, "doppler_polynomial:      5.00000e+01  1.00000e-04  0.00000e+00  0.00000e+00  Hz Hz/m Hz/m^2 Hz/m^3\n")
+	fmt.Fprintf(sb, "earth_semi_major_axis:   6378137.0000   m\n")
+	fmt.Fprintf(sb, "earth_semi_minor_axis:   6356752.3141   m\n")
+	fmt.Fprintf(sb, "number_of_state_vectors:      12\n")
+	fmt.Fprintf(sb, "time_of_first_state_vector:   60.000000   s\n")
+	fmt.Fprintf(sb, "state_vector_interval:        10.000000   s\n")
+
+	for ii := 0; ii < 12; ii++ {
+		pos, vel := testState(60.0 + 10.0*float64(ii))
+		fmt.Fprintf(sb, "state_vector_position_%d:  %.4f  %.4f  %.4f   m   m   m\n",
+			ii+1, pos[0], pos[1], pos[2])
+		fmt.Fprintf(sb, "state_vector_velocity_%d:  %.5f  %.5f  %.5f   m/s m/s m/s\n",
+			ii+1, vel[0], vel[1], vel[2])
+	}
+
+	return sb.String()
+}
+
+func testSAR(t *testing.T) (s SAR) {
+	s, err := ParseSAR(params.FromString(testPar(), ":"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestInterpolate(t *testing.T) {
+	s := testSAR(t)
+
+	if n := len(s.Orbit.Vectors); n != 12 {
+		t.Fatalf("expected 12 state vectors, got %d", n)
+	}
+
+	for _, method := range []Interpolation{Lagrange, Hermite} {
+		s.Orbit.Method = method
+
+		for _, tt := range []float64{60.0, 83.7, 111.1, 170.0} {
+			sv, err := s.Orbit.Interpolate(tt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pos, vel := testState(tt)
+			if d := sv.Position.Sub(pos).Norm(); d > 1e-2 {
+				t.Errorf("%s position error at %.1f s is %g m", method, tt, d)
+			}
+
+			if d := sv.Velocity.Sub(vel).Norm(); d > 1e-3 {
+				t.Errorf("%s velocity error at %.1f s is %g m/s", method, tt, d)
+			}
+		}
+	}
+
+	if _, err := s.Orbit.Interpolate(200.0); err == nil {
+		t.Errorf("expected an error outside of the orbit")
+	}
+}
+
+func TestGeodetic(t *testing.T) {
+	for _, g := range []Geodetic{
+		{47.5, 19.0, 120.0},
+		{-33.9, 151.2, -20.0},
+		{89.9, -45.0, 3000.0},
+	} {
+		back := WGS84.ToGeodetic(WGS84.ToECEF(g))
+
+		if math.Abs(back.Lat-g.Lat) > 1e-9 || math.Abs(back.Lon-g.Lon) > 1e-9 ||
+			math.Abs(back.Height-g.Height) > 1e-4 {
+			t.Errorf("expected %s, got %s", g, back)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	s := testSAR(t)
+
+	pixels := []RadarPoint{{0, 0}, {500.5, 2500}, {999, 4999}, {250, 1200}}
+	heights := []float64{0.0, 250.0, 1500.0, -50.0}
+
+	geo, err := s.ToGeoBatch(pixels, heights)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for ii, g := range geo {
+		if math.Abs(g.Height-heights[ii]) > 1e-2 {
+			t.Errorf("expected height %g, got %g", heights[ii], g.Height)
+		}
+	}
+
+	back, err := s.ToRadarBatch(geo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for ii, r := range back {
+		p := pixels[ii]
+		if math.Abs(r.Rng-p.Rng) > 1e-3 || math.Abs(r.Azi-p.Azi) > 1e-3 {
+			t.Errorf("expected pixel %s, got %s", p, r)
+		}
+	}
+}
+
+func TestGeometry(t *testing.T) {
+	s := testSAR(t)
+
+	pass, err := s.Pass()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pass != Ascending {
+		t.Errorf("expected ascending pass, got %s", pass)
+	}
+
+	heading, err := s.Heading()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if math.Abs(heading) > 1e-6 {
+		t.Errorf("expected heading 0, got %g", heading)
+	}
+
+	r := RadarPoint{Rng: 500, Azi: 2500}
+	look, inc, err := s.Angles(r, 0.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if look < 25.0 || look > 40.0 || inc <= look {
+		t.Errorf("unexpected look angle %g and incidence angle %g", look, inc)
+	}
+
+	// the satellite looks to the right, east of the ascending track
+	g, err := s.ToGeo(r, 0.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g.Lon <= 0.0 {
+		t.Errorf("expected target east of the track, got %s", g)
+	}
+}
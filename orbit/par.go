@@ -0,0 +1,212 @@
+package orbit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bozso/gotoolbox/errors"
+	"github.com/bozso/gotoolbox/path"
+
+	"github.com/bozso/gomma/utils/params"
+)
+
+// floats returns the first n numbers of the value of the parameter,
+// skipping the units following them.
+func floats(p params.Retreiver, key string, n int) (f []float64, err error) {
+	// the separator is included, so that keys that are prefixes of other
+	// keys, like state_vector_position_1 and _10, are not mixed up
+	s, err := p.Param(key + ":")
+	if err != nil {
+		return
+	}
+
+	fields := strings.Fields(s)
+	if len(fields) < n {
+		return nil, fmt.Errorf("expected %d values for parameter '%s', got "+
+			"'%s'", n, key, s)
+	}
+
+	f = make([]float64, n)
+	for ii := range f {
+		if f[ii], err = strconv.ParseFloat(fields[ii], 64); err != nil {
+			return nil, errors.WrapFmt(err, "failed to parse parameter '%s'",
+				key)
+		}
+	}
+
+	return f, nil
+}
+
+func float(p params.Retreiver, key string) (f float64, err error) {
+	fs, err := floats(p, key, 1)
+	if err != nil {
+		return
+	}
+	return fs[0], nil
+}
+
+func integer(p params.Retreiver, key string) (ii int, err error) {
+	f, err := float(p, key)
+	return int(f), err
+}
+
+func vector(p params.Retreiver, key string) (v Vector, err error) {
+	f, err := floats(p, key, 3)
+	if err != nil {
+		return
+	}
+
+	copy(v[:], f)
+	return v, nil
+}
+
+// ParseOrbit reads the state vectors of an ISP/MLI parameter file.
+func ParseOrbit(p params.Retreiver) (o Orbit, err error) {
+	n, err := integer(p, "number_of_state_vectors")
+	if err != nil {
+		return
+	}
+
+	t0, err := float(p, "time_of_first_state_vector")
+	if err != nil {
+		return
+	}
+
+	dt, err := float(p, "state_vector_interval")
+	if err != nil {
+		return
+	}
+
+	sv := make([]StateVector, n)
+	for ii := range sv {
+		sv[ii].Time = t0 + float64(ii)*dt
+
+		key := fmt.Sprintf("state_vector_position_%d", ii+1)
+		if sv[ii].Position, err = vector(p, key); err != nil {
+			return
+		}
+
+		key = fmt.Sprintf("state_vector_velocity_%d", ii+1)
+		if sv[ii].Velocity, err = vector(p, key); err != nil {
+			return
+		}
+	}
+
+	return New(sv)
+}
+
+/*
+SAR holds the imaging geometry of a SLC or MLI image. Times are given in
+seconds since the start of the day of the acquisition, like in the
+parameter files.
+*/
+type SAR struct {
+	Orbit     Orbit     `json:"orbit"`
+	Ellipsoid Ellipsoid `json:"ellipsoid"`
+
+	RangeSamples int `json:"range_samples"`
+	AzimuthLines int `json:"azimuth_lines"`
+
+	StartTime       float64 `json:"start_time"`
+	AzimuthLineTime float64 `json:"azimuth_line_time"`
+
+	NearRange         float64 `json:"near_range"`
+	CenterRange       float64 `json:"center_range"`
+	RangePixelSpacing float64 `json:"range_pixel_spacing"`
+
+	RadarFrequency float64 `json:"radar_frequency"`
+
+	// Doppler centroid polynomial in the slant range relative to the
+	// center range.
+	Doppler [4]float64 `json:"doppler"`
+}
+
+// ParseSAR reads the imaging geometry from an ISP/MLI parameter file.
+func ParseSAR(p params.Retreiver) (s SAR, err error) {
+	if s.Orbit, err = ParseOrbit(p); err != nil {
+		return
+	}
+
+	for _, f := range []struct {
+		key string
+		val *float64
+	}{
+		{"start_time", &s.StartTime},
+		{"azimuth_line_time", &s.AzimuthLineTime},
+		{"near_range_slc", &s.NearRange},
+		{"center_range_slc", &s.CenterRange},
+		{"range_pixel_spacing", &s.RangePixelSpacing},
+		{"radar_frequency", &s.RadarFrequency},
+	} {
+		if *f.val, err = float(p, f.key); err != nil {
+			return
+		}
+	}
+
+	if s.RangeSamples, err = integer(p, "range_samples"); err != nil {
+		return
+	}
+
+	if s.AzimuthLines, err = integer(p, "azimuth_lines"); err != nil {
+		return
+	}
+
+	dop, err := floats(p, "doppler_polynomial", 4)
+	if err != nil {
+		return
+	}
+	copy(s.Doppler[:], dop)
+
+	// older parameter files may lack the ellipsoid
+	s.Ellipsoid = WGS84
+	if a, err := float(p, "earth_semi_major_axis"); err == nil {
+		s.Ellipsoid.SemiMajor = a
+	}
+
+	if b, err := float(p, "earth_semi_minor_axis"); err == nil {
+		s.Ellipsoid.SemiMinor = b
+	}
+
+	return s, s.Validate()
+}
+
+func ReadSAR(file path.ValidFile) (s SAR, err error) {
+	p, err := params.FromFile(file, ":")
+	if err != nil {
+		return
+	}
+
+	if s, err = ParseSAR(p); err != nil {
+		err = errors.WrapFmt(err, "failed to parse parameter file '%s'",
+			file)
+	}
+	return
+}
+
+func (s SAR) Validate() (err error) {
+	if s.AzimuthLineTime <= 0.0 || s.RangePixelSpacing <= 0.0 {
+		return fmt.Errorf("azimuth line time and range pixel spacing must " +
+			"be positive")
+	}
+
+	if s.RadarFrequency <= 0.0 {
+		return fmt.Errorf("radar frequency must be positive")
+	}
+
+	return nil
+}
+
+func (s SAR) Wavelength() float64 {
+	return SpeedOfLight / s.RadarFrequency
+}
+
+// DopplerAt returns the Doppler centroid at the slant range in Hz.
+func (s SAR) DopplerAt(r float64) (fd float64) {
+	dr := r - s.CenterRange
+
+	for ii := len(s.Doppler) - 1; ii >= 0; ii-- {
+		fd = fd*dr + s.Doppler[ii]
+	}
+	return
+}
@@ -0,0 +1,360 @@
+package sentinel1
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paulmach/orb"
+
+	"github.com/bozso/gomma/common"
+	"github.com/bozso/gomma/geometry"
+)
+
+// Format of the timestamps found in the XML annotation files.
+const annotationTimeFmt = "2006-01-02T15:04:05.999999"
+
+type AnnotationTime struct {
+	time.Time
+}
+
+func (at *AnnotationTime) UnmarshalText(b []byte) (err error) {
+	at.Time, err = time.Parse(annotationTimeFmt, strings.TrimSpace(string(b)))
+	return
+}
+
+type GridPoint struct {
+	AzimuthTime    AnnotationTime `xml:"azimuthTime"`
+	SlantRangeTime float64        `xml:"slantRangeTime"`
+	Line           int            `xml:"line"`
+	Pixel          int            `xml:"pixel"`
+	Latitude       float64        `xml:"latitude"`
+	Longitude      float64        `xml:"longitude"`
+	Height         float64        `xml:"height"`
+	IncidenceAngle float64        `xml:"incidenceAngle"`
+	ElevationAngle float64        `xml:"elevationAngle"`
+}
+
+type Vector3 struct {
+	X float64 `xml:"x"`
+	Y float64 `xml:"y"`
+	Z float64 `xml:"z"`
+}
+
+// OrbitVector is a state vector of the orbit annotation.
+type OrbitVector struct {
+	Time     AnnotationTime `xml:"time"`
+	Position Vector3        `xml:"position"`
+	Velocity Vector3        `xml:"velocity"`
+}
+
+// DopplerEstimate holds a Doppler centroid polynomial of the slant range
+// time (in seconds) relative to T0.
+type DopplerEstimate struct {
+	AzimuthTime AnnotationTime `xml:"azimuthTime"`
+	T0          float64        `xml:"t0"`
+	Polynomial  floatList      `xml:"dataDcPolynomial"`
+}
+
+type BurstInfo struct {
+	AzimuthTime      AnnotationTime `xml:"azimuthTime"`
+	AzimuthAnxTime   float64        `xml:"azimuthAnxTime"`
+	ByteOffset       int64          `xml:"byteOffset"`
+	FirstValidSample floatList      `xml:"firstValidSample"`
+	LastValidSample  floatList      `xml:"lastValidSample"`
+}
+
+// Annotation holds the parts of a Sentinel-1 product annotation file
+// that are used by gomma.
+type Annotation struct {
+	Header struct {
+		Mission       string         `xml:"missionId"`
+		ProductType   string         `xml:"productType"`
+		Polarisation  string         `xml:"polarisation"`
+		Mode          string         `xml:"mode"`
+		Swath         string         `xml:"swath"`
+		StartTime     AnnotationTime `xml:"startTime"`
+		StopTime      AnnotationTime `xml:"stopTime"`
+		AbsoluteOrbit int            `xml:"absoluteOrbitNumber"`
+	} `xml:"adsHeader"`
+
+	ProductInformation struct {
+		Pass                string  `xml:"pass"`
+		RadarFrequency      float64 `xml:"radarFrequency"`
+		RangeSamplingRate   float64 `xml:"rangeSamplingRate"`
+		AzimuthSteeringRate float64 `xml:"azimuthSteeringRate"`
+	} `xml:"generalAnnotation>productInformation"`
+
+	Downlink []struct {
+		PRF float64 `xml:"prf"`
+	} `xml:"generalAnnotation>downlinkInformationList>downlinkInformation"`
+
+	Orbits []OrbitVector `xml:"generalAnnotation>orbitList>orbit"`
+
+	ImageInformation struct {
+		FirstLineTime          AnnotationTime `xml:"productFirstLineUtcTime"`
+		LastLineTime           AnnotationTime `xml:"productLastLineUtcTime"`
+		SlantRangeTime         float64        `xml:"slantRangeTime"`
+		RangePixelSpacing      float64        `xml:"rangePixelSpacing"`
+		AzimuthPixelSpacing    float64        `xml:"azimuthPixelSpacing"`
+		AzimuthTimeInterval    float64        `xml:"azimuthTimeInterval"`
+		IncidenceAngleMidSwath float64        `xml:"incidenceAngleMidSwath"`
+		NumberOfSamples        int            `xml:"numberOfSamples"`
+		NumberOfLines          int            `xml:"numberOfLines"`
+	} `xml:"imageAnnotation>imageInformation"`
+
+	ProcessingParams []struct {
+		AzimuthBandwidth float64 `xml:"azimuthProcessing>processingBandwidth"`
+		RangeBandwidth   float64 `xml:"rangeProcessing>processingBandwidth"`
+	} `xml:"imageAnnotation>processingInformation>swathProcParamsList>swathProcParams"`
+
+	Doppler []DopplerEstimate `xml:"dopplerCentroid>dcEstimateList>dcEstimate"`
+
+	SwathTiming struct {
+		LinesPerBurst   int         `xml:"linesPerBurst"`
+		SamplesPerBurst int         `xml:"samplesPerBurst"`
+		Bursts          []BurstInfo `xml:"burstList>burst"`
+	} `xml:"swathTiming"`
+
+	GeolocationGrid []GridPoint `xml:"geolocationGrid>geolocationGridPointList>geolocationGridPoint"`
+}
+
+func ParseAnnotation(r io.Reader) (a Annotation, err error) {
+	err = xml.NewDecoder(r).Decode(&a)
+	return
+}
+
+// SwathNumber returns the number of the subswath, e.g. 2 for IW2.
+func (a Annotation) SwathNumber() (n int, err error) {
+	s := a.Header.Swath
+	if len(s) < 3 {
+		return 0, fmt.Errorf("invalid swath identifier '%s'", s)
+	}
+
+	return strconv.Atoi(s[2:])
+}
+
+type Burst struct {
+	// Number of the subswath, starting from 1.
+	IW int `json:"iw"`
+
+	// Index of the burst inside the subswath, starting from 1.
+	Index int `json:"index"`
+
+	AzimuthTime time.Time `json:"azimuth_time"`
+
+	// Azimuth time of the burst since the ascending node crossing in
+	// seconds, GAMMA identifies bursts with this in the burst tables.
+	AnxTime float64 `json:"anx_time"`
+
+	// Duration of the burst in seconds.
+	Duration float64 `json:"duration"`
+
+	// Approximate footprint derived from the geolocation grid, points
+	// are in (longitude, latitude) order.
+	Footprint orb.Ring `json:"footprint"`
+}
+
+// Hull returns the convex hull of the footprint of the burst.
+func (b Burst) Hull() (r orb.Ring) {
+	return geometry.ConvexHull(b.Footprint)
+}
+
+/*
+Bursts computes the footprints of the bursts of the subswath. The
+geolocation grid is interpolated linearly in the line direction at the
+first and last line of every burst.
+*/
+func (a Annotation) Bursts() (b []Burst, err error) {
+	iw, err := a.SwathNumber()
+	if err != nil {
+		return
+	}
+
+	lpb := a.SwathTiming.LinesPerBurst
+	if lpb <= 0 {
+		return nil, fmt.Errorf("invalid number of lines per burst %d", lpb)
+	}
+
+	grid, err := newGeoGrid(a.GeolocationGrid)
+	if err != nil {
+		return
+	}
+
+	bursts := a.SwathTiming.Bursts
+	b = make([]Burst, len(bursts))
+
+	duration := float64(lpb) * a.ImageInformation.AzimuthTimeInterval
+
+	for ii, burst := range bursts {
+		first, last := float64(ii*lpb), float64((ii+1)*lpb-1)
+
+		top, bottom := grid.atLine(first), grid.atLine(last)
+
+		ring := make(orb.Ring, 0, len(top)+len(bottom)+1)
+		ring = append(ring, top...)
+
+		for jj := len(bottom) - 1; jj >= 0; jj-- {
+			ring = append(ring, bottom[jj])
+		}
+		ring = append(ring, top[0])
+
+		b[ii] = Burst{
+			IW:          iw,
+			Index:       ii + 1,
+			AzimuthTime: burst.AzimuthTime.Time,
+			AnxTime:     burst.AzimuthAnxTime,
+			Duration:    duration,
+			Footprint:   ring,
+		}
+	}
+
+	return b, nil
+}
+
+// geoGrid holds the rows of the geolocation grid sorted by line, and
+// the points of every row sorted by pixel.
+type geoGrid struct {
+	lines []float64
+	rows  [][]GridPoint
+}
+
+func newGeoGrid(points []GridPoint) (g geoGrid, err error) {
+	byLine := map[int][]GridPoint{}
+
+	for _, p := range points {
+		byLine[p.Line] = append(byLine[p.Line], p)
+	}
+
+	if len(byLine) < 2 {
+		return g, fmt.Errorf(
+			"geolocation grid needs at least two rows, got %d", len(byLine))
+	}
+
+	lines := make([]int, 0, len(byLine))
+	for line := range byLine {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+
+	ncol := len(byLine[lines[0]])
+
+	for _, line := range lines {
+		row := byLine[line]
+
+		if len(row) != ncol {
+			return g, fmt.Errorf("rows of the geolocation grid have " +
+				"different number of points")
+		}
+
+		sort.Slice(row, func(i, j int) bool {
+			return row[i].Pixel < row[j].Pixel
+		})
+
+		g.lines = append(g.lines, float64(line))
+		g.rows = append(g.rows, row)
+	}
+
+	return g, nil
+}
+
+// atLine interpolates (or extrapolates near the edges) the grid
+// linearly at the given line.
+func (g geoGrid) atLine(line float64) (pts []orb.Point) {
+	n := len(g.lines)
+
+	idx := sort.SearchFloat64s(g.lines, line)
+	if idx == 0 {
+		idx = 1
+	} else if idx >= n {
+		idx = n - 1
+	}
+
+	l0, l1 := g.lines[idx-1], g.lines[idx]
+	r0, r1 := g.rows[idx-1], g.rows[idx]
+	t := (line - l0) / (l1 - l0)
+
+	pts = make([]orb.Point, len(r0))
+	for ii := range r0 {
+		pts[ii] = orb.Point{
+			r0[ii].Longitude + t*(r1[ii].Longitude-r0[ii].Longitude),
+			r0[ii].Latitude + t*(r1[ii].Latitude-r0[ii].Latitude),
+		}
+	}
+
+	return
+}
+
+// Annotations parses the annotation files of the requested subswaths
+// directly from the zipfile, without extracting them.
+func (s1 Zip) Annotations(pol common.Pol, swaths ...int) (as []Annotation, err error) {
+	if pol == common.AllPolarisation {
+		pol = s1.pol
+	}
+
+	if len(swaths) == 0 {
+		swaths = s1.SwathMode.Swaths()
+	}
+
+	rc, err := zip.OpenReader(s1.Path.GetPath())
+	if err != nil {
+		return
+	}
+	defer rc.Close()
+
+	for _, iw := range swaths {
+		tpl := s1.Templates[annot].Render(iw, pol)
+
+		file := findEntry(rc.File, tpl)
+		if file == nil {
+			return nil, fmt.Errorf(
+				"annotation file for %s%d not found in zipfile '%s'",
+				s1.SwathMode, iw, s1.Path)
+		}
+
+		a, err := parseAnnotationEntry(file)
+		if err != nil {
+			return nil, common.ParseFail(s1.Path, err).
+				ToRetreive(fmt.Sprintf("annotation of %s%d", s1.SwathMode,
+					iw))
+		}
+
+		as = append(as, a)
+	}
+
+	return
+}
+
+func parseAnnotationEntry(file *zip.File) (a Annotation, err error) {
+	r, err := file.Open()
+	if err != nil {
+		return
+	}
+	defer r.Close()
+
+	return ParseAnnotation(r)
+}
+
+// Bursts returns the bursts of all subswaths of the zipfile.
+func (s1 Zip) Bursts(pol common.Pol) (b []Burst, err error) {
+	as, err := s1.Annotations(pol)
+	if err != nil {
+		return
+	}
+
+	for _, a := range as {
+		bursts, err := a.Bursts()
+		if err != nil {
+			return nil, err
+		}
+
+		b = append(b, bursts...)
+	}
+
+	return
+}
@@ -0,0 +1,167 @@
+package sentinel1
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/bozso/gotoolbox/errors"
+	"github.com/paulmach/orb"
+
+	"github.com/bozso/gomma/geometry"
+)
+
+type CoverMode int
+
+const (
+	// The selected bursts have to cover the whole area of interest.
+	FullyCovers CoverMode = iota
+	// The selected bursts have to cover at least a given percentage of
+	// the area of interest.
+	Intersects
+)
+
+func (c *CoverMode) Set(s string) (err error) {
+	const mode errors.Mode = "cover mode"
+
+	switch strings.ToLower(s) {
+	case "covers", "fully_covers":
+		*c = FullyCovers
+	case "intersects":
+		*c = Intersects
+	default:
+		err = mode.Error(s)
+	}
+	return
+}
+
+func (c CoverMode) String() (s string) {
+	switch c {
+	case FullyCovers:
+		s = "covers"
+	case Intersects:
+		s = "intersects"
+	default:
+		s = "unknown"
+	}
+	return
+}
+
+func (c CoverMode) MarshalJSON() (b []byte, err error) {
+	return json.Marshal(c.String())
+}
+
+func (c *CoverMode) UnmarshalJSON(b []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(b, &s); err != nil {
+		return
+	}
+
+	return c.Set(s)
+}
+
+// AOISelect selects bursts by intersecting their footprints with a
+// polygon area of interest.
+type AOISelect struct {
+	AOI  geometry.AreaOfInterest `json:"aoi"`
+	Mode CoverMode               `json:"mode"`
+
+	// Minimum percentage of the area of interest that has to be covered
+	// in "intersects" mode. Any intersection is accepted if it is zero.
+	MinCoverage float64 `json:"min_coverage_percent"`
+}
+
+// SwathSelection holds a contiguous range of bursts of one subswath.
+type SwathSelection struct {
+	IW int `json:"iw"`
+
+	// Indices of the first and last selected burst, starting from 1.
+	First int `json:"first_burst"`
+	Last  int `json:"last_burst"`
+
+	Bursts []Burst `json:"bursts"`
+}
+
+func (s SwathSelection) NumBursts() (n int) {
+	return s.Last - s.First + 1
+}
+
+type Selection struct {
+	// Percentage of the area of interest covered by the selected bursts.
+	Coverage float64          `json:"coverage_percent"`
+	Swaths   []SwathSelection `json:"swaths"`
+}
+
+func (s Selection) Footprints() (r []orb.Ring) {
+	for _, swath := range s.Swaths {
+		for _, burst := range swath.Bursts {
+			r = append(r, burst.Hull())
+		}
+	}
+	return
+}
+
+/*
+Select returns the minimal set of bursts per subswath that intersect
+the area of interest. Bursts are kept contiguous inside a subswath, so
+the range between the first and last intersecting burst is selected.
+The returned flag reports whether the selection satisfies the cover
+mode.
+*/
+func (a AOISelect) Select(bursts []Burst) (sel Selection, ok bool) {
+	bySwath := map[int][]Burst{}
+	for _, b := range bursts {
+		bySwath[b.IW] = append(bySwath[b.IW], b)
+	}
+
+	iws := make([]int, 0, len(bySwath))
+	for iw := range bySwath {
+		iws = append(iws, iw)
+	}
+	sort.Ints(iws)
+
+	for _, iw := range iws {
+		swath := bySwath[iw]
+
+		sort.Slice(swath, func(i, j int) bool {
+			return swath[i].Index < swath[j].Index
+		})
+
+		first, last := -1, -1
+
+		for ii, b := range swath {
+			if a.AOI.IntersectionArea(b.Hull()) > 0.0 {
+				if first < 0 {
+					first = ii
+				}
+				last = ii
+			}
+		}
+
+		if first < 0 {
+			continue
+		}
+
+		sel.Swaths = append(sel.Swaths, SwathSelection{
+			IW:     iw,
+			First:  swath[first].Index,
+			Last:   swath[last].Index,
+			Bursts: swath[first : last+1],
+		})
+	}
+
+	if len(sel.Swaths) == 0 {
+		return sel, false
+	}
+
+	sel.Coverage = 100.0 * a.AOI.Coverage(sel.Footprints())
+
+	switch a.Mode {
+	case FullyCovers:
+		ok = sel.Coverage >= 100.0
+	case Intersects:
+		ok = sel.Coverage > 0.0 && sel.Coverage >= a.MinCoverage
+	}
+
+	return sel, ok
+}
@@ -0,0 +1,44 @@
+package sentinel1
+
+import (
+	"strings"
+
+	"github.com/bozso/gomma/baseline"
+	"github.com/bozso/gomma/common"
+	"github.com/bozso/gomma/date"
+	"github.com/bozso/gomma/utils/params"
+)
+
+/*
+Scene returns the acquisition geometry of the zipfile for baseline
+computations. The parameters of the middle subswath are derived from its
+annotation in the same way as for the native import, so the zipfile does
+not need to be extracted or imported.
+*/
+func (s1 Zip) Scene(pol common.Pol) (s baseline.Scene, err error) {
+	swaths := s1.SwathMode.Swaths()
+
+	as, err := s1.Annotations(pol, swaths[len(swaths)/2])
+	if err != nil {
+		return
+	}
+
+	a := as[0]
+	slice := swathSlice{a: a, first: 0, num: len(a.SwathTiming.Bursts)}
+
+	if pol == common.AllPolarisation {
+		pol = s1.pol
+	}
+
+	sb := &strings.Builder{}
+	if err = slice.WriteISPPar(sb, pol.String()); err != nil {
+		return
+	}
+
+	s, err = baseline.ParseScene(date.Short.Format(s1.Date()),
+		params.FromString(sb.String(), ":"))
+	if err != nil {
+		err = common.ParseFail(s1.Path, err).ToRetreive("scene geometry")
+	}
+	return
+}
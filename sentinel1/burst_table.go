@@ -0,0 +1,186 @@
+package sentinel1
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bozso/gotoolbox/path"
+
+	"github.com/bozso/gomma/common"
+)
+
+// SwathBursts holds the range of bursts selected from one subswath.
+type SwathBursts struct {
+	IW int `json:"iw"`
+
+	// Indices of the first and last burst, starting from 1. Only used
+	// for reviewing the table, GAMMA identifies the bursts by their
+	// azimuth time since the ascending node crossing.
+	FirstIndex int `json:"first_index"`
+	LastIndex  int `json:"last_index"`
+
+	FirstBurst float64 `json:"first_burst"`
+	LastBurst  float64 `json:"last_burst"`
+}
+
+func (s SwathBursts) NumBursts() (n int) {
+	return s.LastIndex - s.FirstIndex + 1
+}
+
+/*
+BurstTable describes the bursts to be imported from the zipfiles. It can
+be saved as JSON for reviewing (and editing) before the import is run
+and written in the format of the burst_number_table file expected by
+S1_import_SLC_from_zipfiles.
+*/
+type BurstTable struct {
+	Zipfile string          `json:"zipfile"`
+	Mode    AcquisitionMode `json:"mode"`
+
+	// Percentage of the area of interest covered by the selected bursts.
+	Coverage float64       `json:"coverage_percent"`
+	Swaths   []SwathBursts `json:"swaths"`
+}
+
+/*
+NewBurstTable selects the bursts of the master zipfile that cover the
+area of interest and creates a burst table from them. An error is
+returned if the bursts do not satisfy the cover mode of the selection.
+*/
+func (a AOISelect) NewBurstTable(master *Zip, pol common.Pol) (bt BurstTable, err error) {
+	bursts, err := master.Bursts(pol)
+	if err != nil {
+		return
+	}
+
+	sel, ok := a.Select(bursts)
+	if !ok {
+		return bt, fmt.Errorf("bursts of master zipfile '%s' cover %.1f%% "+
+			"of the area of interest, which is not enough in '%s' mode",
+			master.Path, sel.Coverage, a.Mode)
+	}
+
+	bt.Zipfile, bt.Coverage = master.Path.String(), sel.Coverage
+	bt.Mode = master.SwathMode
+
+	for _, swath := range sel.Swaths {
+		first, last := swath.Bursts[0], swath.Bursts[len(swath.Bursts)-1]
+
+		bt.Swaths = append(bt.Swaths, SwathBursts{
+			IW:         swath.IW,
+			FirstIndex: swath.First,
+			LastIndex:  swath.Last,
+			FirstBurst: first.AnxTime,
+			LastBurst:  last.AnxTime,
+		})
+	}
+
+	return bt, nil
+}
+
+func (bt BurstTable) Validate() (err error) {
+	if len(bt.Swaths) == 0 {
+		return fmt.Errorf("burst table does not contain any subswaths")
+	}
+
+	seen := map[int]bool{}
+
+	for _, s := range bt.Swaths {
+		if !bt.Mode.ValidSwath(s.IW) {
+			return fmt.Errorf("invalid subswath number %d for %s mode in "+
+				"burst table", s.IW, bt.Mode)
+		}
+
+		if seen[s.IW] {
+			return fmt.Errorf("%s%d is listed more than once in burst table",
+				bt.Mode, s.IW)
+		}
+		seen[s.IW] = true
+
+		if s.NumBursts() < 1 || s.LastBurst < s.FirstBurst {
+			return fmt.Errorf(
+				"number of bursts for %s%d is not positive, did you mix up "+
+					"first and last burst numbers?", bt.Mode, s.IW)
+		}
+
+		if s.FirstIndex < 1 {
+			return fmt.Errorf("first burst index %d of %s%d is not positive",
+				s.FirstIndex, bt.Mode, s.IW)
+		}
+	}
+
+	return nil
+}
+
+/*
+ValidateBursts validates the table and checks that the burst ranges are
+inside the subswaths of the master, bursts holding every burst of the
+master zipfile.
+*/
+func (bt BurstTable) ValidateBursts(bursts []Burst) (err error) {
+	if err = bt.Validate(); err != nil {
+		return
+	}
+
+	count := map[int]int{}
+	for _, b := range bursts {
+		count[b.IW]++
+	}
+
+	for _, s := range bt.Swaths {
+		if n := count[s.IW]; s.LastIndex > n {
+			return fmt.Errorf("last burst index %d of %s%d is out of range, "+
+				"the master has %d bursts in the subswath", s.LastIndex,
+				bt.Mode, s.IW, n)
+		}
+	}
+
+	return nil
+}
+
+// Swath returns the burst range of the given subswath, if it is
+// selected.
+func (bt BurstTable) Swath(iw int) (s SwathBursts, ok bool) {
+	for _, s = range bt.Swaths {
+		if s.IW == iw {
+			return s, true
+		}
+	}
+	return s, false
+}
+
+func (bt BurstTable) WriteTo(w io.Writer) (n int64, err error) {
+	nn, err := fmt.Fprintf(w, "zipfile: %s\n", bt.Zipfile)
+	n += int64(nn)
+	if err != nil {
+		return
+	}
+
+	const tpl = "%s%d_number_of_bursts: %d\n%s%d_first_burst: %f\n%s%d_last_burst: %f\n"
+
+	pre := bt.Mode.SwathPrefix()
+
+	for _, s := range bt.Swaths {
+		nn, err = fmt.Fprintf(w, tpl, pre, s.IW, s.NumBursts(), pre, s.IW,
+			s.FirstBurst, pre, s.IW, s.LastBurst)
+		n += int64(nn)
+
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// Save writes the table in the format of GAMMA burst_number_table files.
+func (bt BurstTable) Save(file path.File) (err error) {
+	f, err := file.Create()
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	_, err = bt.WriteTo(f)
+	return
+}
@@ -0,0 +1,51 @@
+package sentinel1
+
+import (
+	"testing"
+)
+
+func TestBurstTableValidate(t *testing.T) {
+	// three IW subswaths with 9 bursts each
+	var bursts []Burst
+	for iw := 1; iw <= 3; iw++ {
+		for ii := 1; ii <= 9; ii++ {
+			bursts = append(bursts, Burst{IW: iw, Index: ii,
+				AnxTime: float64(ii) * 2.758})
+		}
+	}
+
+	swath := func(iw, first, last int) SwathBursts {
+		return SwathBursts{
+			IW:         iw,
+			FirstIndex: first,
+			LastIndex:  last,
+			FirstBurst: float64(first) * 2.758,
+			LastBurst:  float64(last) * 2.758,
+		}
+	}
+
+	for _, c := range []struct {
+		name   string
+		mode   AcquisitionMode
+		swaths []SwathBursts
+		valid  bool
+	}{
+		{"valid", IWMode, []SwathBursts{swath(1, 2, 4), swath(2, 1, 9)}, true},
+		{"no subswaths", IWMode, nil, false},
+		{"subswath of another mode", IWMode, []SwathBursts{swath(4, 1, 2)}, false},
+		{"duplicated subswath", IWMode, []SwathBursts{swath(2, 1, 2), swath(2, 3, 4)}, false},
+		{"first after last", IWMode, []SwathBursts{swath(1, 5, 3)}, false},
+		{"first index not positive", IWMode, []SwathBursts{swath(1, 0, 3)}, false},
+		{"last out of range", IWMode, []SwathBursts{swath(3, 7, 10)}, false},
+	} {
+		bt := BurstTable{Mode: c.mode, Swaths: c.swaths}
+
+		err := bt.ValidateBursts(bursts)
+		if c.valid && err != nil {
+			t.Errorf("%s: expected valid burst table, got error: %s", c.name,
+				err)
+		} else if !c.valid && err == nil {
+			t.Errorf("%s: expected an error", c.name)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package sentinel1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bozso/gotoolbox/path"
+)
+
+// Bump this whenever the layout of IWInfo changes, so stale caches
+// are discarded instead of being misinterpreted.
+const infoCacheVersion = 1
+
+type infoCacheEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modification_time"`
+	IWs     IWInfos   `json:"iw_infos"`
+}
+
+func (e infoCacheEntry) matches(fi os.FileInfo) (b bool) {
+	return e.Size == fi.Size() && e.ModTime.Equal(fi.ModTime())
+}
+
+type infoCachePayload struct {
+	Version int                       `json:"version"`
+	Entries map[string]infoCacheEntry `json:"entries"`
+}
+
+/*
+InfoCache stores the parsed IW information of Sentinel-1 zipfiles.
+Entries are keyed by the absolute path of the zipfile and are only
+considered valid while the size and modification time of the zipfile
+are unchanged. It is safe for concurrent use.
+*/
+type InfoCache struct {
+	mutex   sync.RWMutex
+	file    path.File
+	entries map[string]infoCacheEntry
+}
+
+// LoadInfoCache loads the cache saved in file. A missing or corrupt file
+// results in an empty cache, which replaces the file on Save.
+func LoadInfoCache(file path.File) (ic *InfoCache, err error) {
+	ic = &InfoCache{
+		file:    file,
+		entries: make(map[string]infoCacheEntry),
+	}
+
+	b, err := ioutil.ReadFile(file.GetPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+
+	// a corrupt cache only costs a rescan, it is overwritten on Save
+	var payload infoCachePayload
+	if err = json.Unmarshal(b, &payload); err != nil {
+		log.Printf("Discarding corrupt IW information cache '%s': %s",
+			file, err)
+		return ic, nil
+	}
+
+	if payload.Version == infoCacheVersion && payload.Entries != nil {
+		ic.entries = payload.Entries
+	}
+
+	return ic, nil
+}
+
+func cacheKey(zip path.ValidFile) (key string, fi os.FileInfo, err error) {
+	if key, err = filepath.Abs(zip.GetPath()); err != nil {
+		return
+	}
+
+	fi, err = os.Stat(key)
+	return
+}
+
+func (ic *InfoCache) Get(zip path.ValidFile) (iws IWInfos, ok bool) {
+	key, fi, err := cacheKey(zip)
+	if err != nil {
+		return
+	}
+
+	ic.mutex.RLock()
+	entry, ok := ic.entries[key]
+	ic.mutex.RUnlock()
+
+	if !ok || !entry.matches(fi) {
+		return iws, false
+	}
+
+	return entry.IWs, true
+}
+
+func (ic *InfoCache) Put(zip path.ValidFile, iws IWInfos) (err error) {
+	key, fi, err := cacheKey(zip)
+	if err != nil {
+		return
+	}
+
+	ic.mutex.Lock()
+	ic.entries[key] = infoCacheEntry{
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+		IWs:     iws,
+	}
+	ic.mutex.Unlock()
+
+	return nil
+}
+
+func (ic *InfoCache) Invalidate(zip path.ValidFile) {
+	key, err := filepath.Abs(zip.GetPath())
+	if err != nil {
+		return
+	}
+
+	ic.mutex.Lock()
+	delete(ic.entries, key)
+	ic.mutex.Unlock()
+}
+
+// Prune removes the entries of zipfiles that no longer exist or
+// were modified since they were cached. Returns the number of removed
+// entries.
+func (ic *InfoCache) Prune() (n int) {
+	ic.mutex.Lock()
+	defer ic.mutex.Unlock()
+
+	for key, entry := range ic.entries {
+		fi, err := os.Stat(key)
+
+		if err != nil || !entry.matches(fi) {
+			delete(ic.entries, key)
+			n++
+		}
+	}
+
+	return
+}
+
+func (ic *InfoCache) Len() (n int) {
+	ic.mutex.RLock()
+	n = len(ic.entries)
+	ic.mutex.RUnlock()
+	return
+}
+
+// Save writes the cache into a temporary file first and renames it, so
+// an interrupted save does not corrupt the previous state.
+func (ic *InfoCache) Save() (err error) {
+	ic.mutex.RLock()
+	b, err := json.Marshal(infoCachePayload{
+		Version: infoCacheVersion,
+		Entries: ic.entries,
+	})
+	ic.mutex.RUnlock()
+
+	if err != nil {
+		return CacheError{file: ic.file, err: err}
+	}
+
+	dst := ic.file.GetPath()
+	tmp := dst + ".tmp"
+
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return CacheError{file: ic.file, err: err}
+	}
+
+	if err = os.Rename(tmp, dst); err != nil {
+		return CacheError{file: ic.file, err: err}
+	}
+
+	return nil
+}
+
+type cachedFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+/*
+LimitCacheDir removes the least recently modified files from the
+directory of extracted files until their total size is not larger
+than limit bytes. The files in keep, like the IW information cache,
+are never removed and do not count towards the limit. Returns the
+number of freed bytes.
+*/
+func LimitCacheDir(dir path.Dir, limit int64, keep ...path.File) (freed int64, err error) {
+	var (
+		files []cachedFile
+		total int64
+	)
+
+	kept := make(map[string]bool, len(keep))
+	for _, file := range keep {
+		abs, err := filepath.Abs(file.GetPath())
+		if err != nil {
+			return 0, err
+		}
+		kept[abs] = true
+	}
+
+	err = filepath.Walk(dir.GetPath(),
+		func(p string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			abs, err := filepath.Abs(p)
+			if err != nil {
+				return err
+			}
+
+			if fi.Mode().IsRegular() && !kept[abs] {
+				files = append(files, cachedFile{
+					path:    p,
+					size:    fi.Size(),
+					modTime: fi.ModTime(),
+				})
+				total += fi.Size()
+			}
+
+			return nil
+		})
+
+	if err != nil {
+		return
+	}
+
+	if total <= limit {
+		return 0, nil
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	for _, file := range files {
+		if total-freed <= limit {
+			break
+		}
+
+		if err = os.Remove(file.path); err != nil {
+			return
+		}
+
+		freed += file.size
+	}
+
+	return freed, nil
+}
+
+type CacheError struct {
+	file path.File
+	err  error
+}
+
+func (e CacheError) Error() string {
+	return fmt.Sprintf("failure while using IW information cache file '%s'",
+		e.file)
+}
+
+func (e CacheError) Unwrap() error {
+	return e.err
+}
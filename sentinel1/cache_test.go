@@ -0,0 +1,111 @@
+package sentinel1
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bozso/gotoolbox/path"
+)
+
+func TestLimitCacheDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the cache file is the oldest one, so it would be removed first
+	names := []string{"iw_info_cache.json", "old.xml", "new.xml"}
+	now := time.Now()
+
+	for ii, name := range names {
+		p := filepath.Join(dir, name)
+		if err = ioutil.WriteFile(p, make([]byte, 100), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		mod := now.Add(time.Duration(ii-len(names)) * time.Hour)
+		if err = os.Chtimes(p, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d, err := path.New(dir).ToDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keep, err := d.Join(names[0]).ToFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	freed, err := LimitCacheDir(d, 100, keep)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if freed != 100 {
+		t.Errorf("expected 100 bytes to be freed, got %d", freed)
+	}
+
+	for name, exists := range map[string]bool{
+		names[0]: true, names[1]: false, names[2]: true,
+	} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if (err == nil) != exists {
+			t.Errorf("expected '%s' to exist: %v", name, exists)
+		}
+	}
+}
+
+func TestLoadCorruptInfoCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, "iw_info_cache.json")
+	if err = ioutil.WriteFile(p, []byte(`{"version": 1, "entr`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := path.New(p).ToFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ic, err := LoadInfoCache(file)
+	if err != nil {
+		t.Fatalf("expected a corrupt cache to be discarded, got error: %s",
+			err)
+	}
+
+	if n := len(ic.entries); n != 0 {
+		t.Errorf("expected an empty cache, got %d entries", n)
+	}
+
+	if err = ic.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the corrupt file is overwritten with a valid one
+	if _, err = LoadInfoCache(file); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var payload infoCachePayload
+	if err = json.Unmarshal(b, &payload); err != nil {
+		t.Errorf("expected a valid cache file after saving, got: %s", b)
+	}
+}
@@ -0,0 +1,367 @@
+package sentinel1
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bozso/gotoolbox/errors"
+
+	"github.com/bozso/gomma/common"
+)
+
+// floatList is a whitespace separated list of numbers as found in the
+// calibration and noise annotation files.
+type floatList []float64
+
+func (f *floatList) UnmarshalText(b []byte) (err error) {
+	fields := strings.Fields(string(b))
+	l := make(floatList, len(fields))
+
+	for ii, field := range fields {
+		if l[ii], err = strconv.ParseFloat(field, 64); err != nil {
+			return
+		}
+	}
+
+	*f = l
+	return nil
+}
+
+// CalibrationLUT selects one of the look up tables of the calibration
+// annotation.
+type CalibrationLUT int
+
+const (
+	SigmaNought CalibrationLUT = iota
+	BetaNought
+	GammaNought
+	DigitalNumber
+)
+
+func (c *CalibrationLUT) Set(s string) (err error) {
+	const mode errors.Mode = "calibration look up table"
+
+	switch strings.ToLower(s) {
+	case "sigma0", "sigmanought":
+		*c = SigmaNought
+	case "beta0", "betanought":
+		*c = BetaNought
+	case "gamma0", "gamma":
+		*c = GammaNought
+	case "dn":
+		*c = DigitalNumber
+	default:
+		err = mode.Error(s)
+	}
+	return
+}
+
+func (c CalibrationLUT) String() (s string) {
+	switch c {
+	case SigmaNought:
+		s = "sigma0"
+	case BetaNought:
+		s = "beta0"
+	case GammaNought:
+		s = "gamma0"
+	case DigitalNumber:
+		s = "dn"
+	default:
+		s = "unknown"
+	}
+	return
+}
+
+func (c CalibrationLUT) MarshalJSON() (b []byte, err error) {
+	return json.Marshal(c.String())
+}
+
+func (c *CalibrationLUT) UnmarshalJSON(b []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(b, &s); err != nil {
+		return
+	}
+
+	return c.Set(s)
+}
+
+// CalibrationVector holds the look up table values of one line.
+type CalibrationVector struct {
+	AzimuthTime AnnotationTime `xml:"azimuthTime"`
+	Line        int            `xml:"line"`
+	Pixel       floatList      `xml:"pixel"`
+	SigmaNought floatList      `xml:"sigmaNought"`
+	BetaNought  floatList      `xml:"betaNought"`
+	Gamma       floatList      `xml:"gamma"`
+	DN          floatList      `xml:"dn"`
+}
+
+func (cv CalibrationVector) values(kind CalibrationLUT) (f floatList) {
+	switch kind {
+	case SigmaNought:
+		f = cv.SigmaNought
+	case BetaNought:
+		f = cv.BetaNought
+	case GammaNought:
+		f = cv.Gamma
+	case DigitalNumber:
+		f = cv.DN
+	}
+	return
+}
+
+// Calibration holds the contents of a calibration annotation file.
+type Calibration struct {
+	AbsoluteConstant float64 `xml:"calibrationInformation>absoluteCalibrationConstant"`
+
+	Vectors []CalibrationVector `xml:"calibrationVectorList>calibrationVector"`
+}
+
+func ParseCalibration(r io.Reader) (c Calibration, err error) {
+	err = xml.NewDecoder(r).Decode(&c)
+	return
+}
+
+// LUT returns the selected look up table of the calibration.
+func (c Calibration) LUT(kind CalibrationLUT) (l LUT, err error) {
+	for _, v := range c.Vectors {
+		if err = l.add(float64(v.Line), v.Pixel, v.values(kind)); err != nil {
+			err = errors.WrapFmt(err,
+				"invalid %s calibration vector at line %d", kind, v.Line)
+			return
+		}
+	}
+
+	err = l.check()
+	return
+}
+
+/*
+LUT is a look up table sampled on a (possibly irregular) grid of lines
+and pixels as found in the calibration and noise annotation files. Every
+line can have its own pixel positions.
+*/
+type LUT struct {
+	lines  []float64
+	pixels [][]float64
+	values [][]float64
+}
+
+func (l *LUT) add(line float64, pixels, values []float64) (err error) {
+	if len(pixels) != len(values) {
+		return fmt.Errorf("number of pixels (%d) and values (%d) differ",
+			len(pixels), len(values))
+	}
+
+	if len(pixels) == 0 {
+		return fmt.Errorf("empty vector")
+	}
+
+	if n := len(l.lines); n > 0 && line <= l.lines[n-1] {
+		return fmt.Errorf("lines are not increasing")
+	}
+
+	l.lines = append(l.lines, line)
+	l.pixels = append(l.pixels, pixels)
+	l.values = append(l.values, values)
+
+	return nil
+}
+
+func (l LUT) check() (err error) {
+	if len(l.lines) == 0 {
+		return fmt.Errorf("look up table is empty")
+	}
+	return nil
+}
+
+// interpolate returns the index of the lower neighbour of x in xs and
+// the weight of the upper neighbour. Points outside of xs are clamped to
+// the edges.
+func interpolate(xs []float64, x float64) (idx int, t float64) {
+	n := len(xs)
+	if n == 1 || x <= xs[0] {
+		return 0, 0.0
+	}
+
+	if x >= xs[n-1] {
+		return n - 2, 1.0
+	}
+
+	idx = sort.SearchFloat64s(xs, x) - 1
+	if idx < 0 {
+		idx = 0
+	}
+
+	return idx, (x - xs[idx]) / (xs[idx+1] - xs[idx])
+}
+
+func lerp(xs, ys []float64, x float64) float64 {
+	if len(ys) == 1 {
+		return ys[0]
+	}
+
+	idx, t := interpolate(xs, x)
+	return ys[idx] + t*(ys[idx+1]-ys[idx])
+}
+
+// At interpolates the table bilinearly at the line and pixel.
+func (l LUT) At(line, pixel float64) float64 {
+	if len(l.lines) == 1 {
+		return lerp(l.pixels[0], l.values[0], pixel)
+	}
+
+	idx, t := interpolate(l.lines, line)
+
+	v0 := lerp(l.pixels[idx], l.values[idx], pixel)
+	v1 := lerp(l.pixels[idx+1], l.values[idx+1], pixel)
+
+	return v0 + t*(v1-v0)
+}
+
+// Line interpolates the table at len(dst) consecutive pixels of a line
+// starting at firstPixel.
+func (l LUT) Line(line, firstPixel int, dst []float64) {
+	for ii := range dst {
+		dst[ii] = l.At(float64(line), float64(firstPixel+ii))
+	}
+}
+
+/*
+Calibrator converts the power of the measurement data (|DN|^2) into
+calibrated intensity:
+
+	value = (|DN|^2 - noise) / A^2
+
+where A is the selected calibration look up table and noise is the
+thermal noise estimated from the noise annotation (zero if thermal noise
+removal is not requested). Negative values resulting from the noise
+subtraction are set to zero.
+*/
+type Calibrator struct {
+	lut   LUT
+	noise *NoiseModel
+}
+
+func NewCalibrator(c Calibration, kind CalibrationLUT, noise *NoiseAnnotation) (cal Calibrator, err error) {
+	if cal.lut, err = c.LUT(kind); err != nil {
+		return
+	}
+
+	if noise != nil {
+		nm, err := noise.Model()
+		if err != nil {
+			return cal, err
+		}
+		cal.noise = &nm
+	}
+
+	return cal, nil
+}
+
+func (c Calibrator) noiseAt(line, pixel float64) float64 {
+	if c.noise == nil {
+		return 0.0
+	}
+	return c.noise.At(line, pixel)
+}
+
+// Intensity returns the calibrated intensity of a sample with the given
+// power.
+func (c Calibrator) Intensity(line, pixel int, power float64) (f float64) {
+	l, p := float64(line), float64(pixel)
+	a := c.lut.At(l, p)
+
+	f = (power - c.noiseAt(l, p)) / (a * a)
+	if f < 0.0 {
+		f = 0.0
+	}
+	return
+}
+
+// Calibrate calibrates consecutive samples of a line starting at
+// firstPixel. power and dst can be the same slice.
+func (c Calibrator) Calibrate(line, firstPixel int, power, dst []float32) {
+	for ii, p := range power {
+		dst[ii] = float32(c.Intensity(line, firstPixel+ii, float64(p)))
+	}
+}
+
+// NESZ returns the noise equivalent sigma zero (or beta/gamma zero
+// depending on the look up table) in linear scale. It is zero when no
+// noise annotation was given.
+func (c Calibrator) NESZ(line, pixel int) float64 {
+	l, p := float64(line), float64(pixel)
+	a := c.lut.At(l, p)
+
+	return c.noiseAt(l, p) / (a * a)
+}
+
+// NESZLine computes the NESZ of len(dst) consecutive pixels of a line.
+func (c Calibrator) NESZLine(line, firstPixel int, dst []float32) {
+	for ii := range dst {
+		dst[ii] = float32(c.NESZ(line, firstPixel+ii))
+	}
+}
+
+func (s1 Zip) openEntry(rc *zip.ReadCloser, mode tplType, pol common.Pol, iw int) (r io.ReadCloser, err error) {
+	if pol == common.AllPolarisation {
+		pol = s1.pol
+	}
+
+	file := findEntry(rc.File, s1.Templates[mode].Render(iw, pol))
+	if file == nil {
+		return nil, fmt.Errorf("file matching '%s' not found in zipfile '%s'",
+			s1.Templates[mode].Render(iw, pol), s1.Path)
+	}
+
+	return file.Open()
+}
+
+// Calibration parses the calibration annotation of the subswath directly
+// from the zipfile. The subswath number is ignored for GRD products.
+func (s1 Zip) Calibration(pol common.Pol, iw int) (c Calibration, err error) {
+	rc, err := zip.OpenReader(s1.Path.GetPath())
+	if err != nil {
+		return
+	}
+	defer rc.Close()
+
+	r, err := s1.openEntry(rc, calib, pol, iw)
+	if err != nil {
+		return
+	}
+	defer r.Close()
+
+	if c, err = ParseCalibration(r); err != nil {
+		err = common.ParseFail(s1.Path, err).ToRetreive("calibration")
+	}
+	return
+}
+
+// Noise parses the noise annotation of the subswath directly from the
+// zipfile. The subswath number is ignored for GRD products.
+func (s1 Zip) Noise(pol common.Pol, iw int) (n NoiseAnnotation, err error) {
+	rc, err := zip.OpenReader(s1.Path.GetPath())
+	if err != nil {
+		return
+	}
+	defer rc.Close()
+
+	r, err := s1.openEntry(rc, noise, pol, iw)
+	if err != nil {
+		return
+	}
+	defer r.Close()
+
+	if n, err = ParseNoise(r); err != nil {
+		err = common.ParseFail(s1.Path, err).ToRetreive("noise vectors")
+	}
+	return
+}
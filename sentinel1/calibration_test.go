@@ -0,0 +1,88 @@
+package sentinel1
+
+import (
+	"os"
+	"testing"
+)
+
+func loadTestCalibration(t *testing.T) (c Calibration) {
+	f, err := os.Open("testdata/s1a-iw1-calibration-vv.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if c, err = ParseCalibration(f); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+type lutPoint struct {
+	line, pixel, expected float64
+}
+
+func TestCalibrationLUT(t *testing.T) {
+	c := loadTestCalibration(t)
+
+	if len(c.Vectors) != 2 {
+		t.Fatalf("expected 2 calibration vectors, got %d", len(c.Vectors))
+	}
+
+	for kind, points := range map[CalibrationLUT][]lutPoint{
+		SigmaNought: {
+			// vector nodes, the lines have different pixel positions
+			{0, 0, 400}, {0, 40, 500}, {0, 80, 600},
+			{100, 50, 540}, {100, 100, 640},
+			// between the nodes
+			{0, 20, 450}, {100, 25, 490}, {50, 20, 465}, {50, 40, 510},
+			// outside of the table
+			{0, 200, 600}, {150, 0, 440}, {-10, 40, 500},
+		},
+		BetaNought: {
+			{0, 40, 200}, {100, 100, 220}, {50, 60, 210}, {25, 0, 205},
+		},
+	} {
+		lut, err := c.LUT(kind)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, p := range points {
+			if got := lut.At(p.line, p.pixel); !closeTo(got, p.expected) {
+				t.Errorf("%s: expected %g at line %g pixel %g, got %g", kind,
+					p.expected, p.line, p.pixel, got)
+			}
+		}
+	}
+}
+
+func TestCalibrator(t *testing.T) {
+	c := loadTestCalibration(t)
+
+	for _, tc := range []struct {
+		kind              CalibrationLUT
+		line, pixel       int
+		power, calibrated float64
+	}{
+		{SigmaNought, 0, 40, 500 * 500 * 0.1, 0.1},
+		{SigmaNought, 50, 20, 465 * 465 * 0.2, 0.2},
+		{BetaNought, 50, 60, 210 * 210 * 3.0, 3.0},
+	} {
+		cal, err := NewCalibrator(c, tc.kind, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := cal.Intensity(tc.line, tc.pixel, tc.power)
+		if !closeTo(got, tc.calibrated) {
+			t.Errorf("%s: expected %g at line %d pixel %d, got %g", tc.kind,
+				tc.calibrated, tc.line, tc.pixel, got)
+		}
+
+		if nesz := cal.NESZ(tc.line, tc.pixel); nesz != 0.0 {
+			t.Errorf("expected zero NESZ without noise annotation, got %g",
+				nesz)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package sentinel1
+
+import (
+	"archive/zip"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bozso/gotoolbox/path"
+
+	"github.com/bozso/gomma/common"
+)
+
+type Problem int
+
+const (
+	NoProblem Problem = iota
+	CorruptArchive
+	CorruptEntry
+	MissingFile
+	SizeMismatch
+	ChecksumMismatch
+	NoData
+)
+
+func (p Problem) String() (s string) {
+	switch p {
+	case NoProblem:
+		s = "ok"
+	case CorruptArchive:
+		s = "corrupt_archive"
+	case CorruptEntry:
+		s = "corrupt_entry"
+	case MissingFile:
+		s = "missing_file"
+	case SizeMismatch:
+		s = "size_mismatch"
+	case ChecksumMismatch:
+		s = "checksum_mismatch"
+	case NoData:
+		s = "no_data"
+	default:
+		s = "unknown"
+	}
+	return
+}
+
+func (p Problem) MarshalText() (b []byte, err error) {
+	return []byte(p.String()), nil
+}
+
+// FileCheck holds the outcome of the verification of a single file
+// inside a Sentinel-1 zipfile.
+type FileCheck struct {
+	Name    string  `json:"name"`
+	Problem Problem `json:"problem"`
+	Detail  string  `json:"detail,omitempty"`
+}
+
+func (f FileCheck) OK() (b bool) {
+	return f.Problem == NoProblem
+}
+
+type ZipCheck struct {
+	Path  path.ValidFile `json:"path"`
+	Files []FileCheck    `json:"files"`
+}
+
+func (z ZipCheck) OK() (b bool) {
+	for _, file := range z.Files {
+		if !file.OK() {
+			return false
+		}
+	}
+	return true
+}
+
+func (z ZipCheck) Failed() (fc []FileCheck) {
+	for _, file := range z.Files {
+		if !file.OK() {
+			fc = append(fc, file)
+		}
+	}
+	return
+}
+
+func (z *ZipCheck) add(name string, p Problem, format string, args ...interface{}) {
+	z.Files = append(z.Files, FileCheck{
+		Name:    name,
+		Problem: p,
+		Detail:  fmt.Sprintf(format, args...),
+	})
+}
+
+type CheckOptions struct {
+	// Polarization of the files that must be present in the zipfile,
+	// defaults to all polarizations of the zipfile.
+	Pol common.Pol `json:"polarization"`
+
+	// Subswaths that must be present, defaults to all of them.
+	Swaths []int `json:"swaths"`
+
+	// Whether to validate the MD5 checksums listed in manifest.safe.
+	VerifyMD5 bool `json:"verify_md5"`
+
+	// Maximum allowed fraction of zero samples in the measurement files.
+	// The measurement files are read directly from the zipfile if it is
+	// set.
+	MaxZeroFraction float64 `json:"max_zero_fraction"`
+}
+
+// Only every zeroCheckStep-th line is read when looking for missing
+// measurement data.
+const zeroCheckStep = 100
+
+// templates of the files required for the import of an IW
+var required = [...]tplType{tiff, annot, calib, noise}
+
+const manifestFile = "manifest.safe"
+
+/*
+Check opens the zipfile and verifies the CRC of every entry. It also
+checks whether manifest.safe and every measurement, annotation,
+calibration and noise file needed for the requested polarization and
+subswaths are present. Problems are reported per file in the returned
+ZipCheck; the error is reserved for failures that are not related to
+the contents of the zipfile.
+*/
+func (s1 Zip) Check(opt CheckOptions) (zc ZipCheck, err error) {
+	zc.Path = s1.Path
+
+	rc, Err := zip.OpenReader(s1.Path.GetPath())
+	if Err != nil {
+		zc.add(s1.Path.String(), CorruptArchive, "%s", Err)
+		return zc, nil
+	}
+	defer rc.Close()
+
+	pols := []common.Pol{opt.Pol}
+	if opt.Pol == common.AllPolarisation {
+		pols = s1.pols
+	}
+
+	swaths := opt.Swaths
+	if len(swaths) == 0 {
+		swaths = s1.SwathMode.Swaths()
+	}
+
+	manifestName := s1.Safe.Join(manifestFile).GetPath()
+	var mf *zip.File
+
+	for _, file := range rc.File {
+		if file.Name == manifestName {
+			mf = file
+			break
+		}
+	}
+
+	if mf == nil {
+		zc.add(manifestName, MissingFile, "manifest not found in zipfile")
+	}
+
+	for _, pol := range pols {
+		for _, iw := range swaths {
+			for _, mode := range required {
+				tpl := s1.Templates[mode].Render(iw, pol)
+
+				if findEntry(rc.File, tpl) == nil {
+					zc.add(tpl, MissingFile,
+						"no file matches template for %s%d %s", s1.SwathMode,
+						iw, pol)
+				}
+			}
+
+			if opt.MaxZeroFraction > 0.0 {
+				s1.checkMeasurement(&zc, pol, iw, opt.MaxZeroFraction)
+			}
+		}
+	}
+
+	checksums := map[string]manifestEntry{}
+
+	if mf != nil && opt.VerifyMD5 {
+		if checksums, err = s1.readChecksums(mf); err != nil {
+			zc.add(mf.Name, CorruptEntry, "%s", err)
+			err = nil
+		}
+	}
+
+	for _, file := range rc.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		entry, hasSum := checksums[file.Name]
+
+		var h hash.Hash
+		if hasSum {
+			h = md5.New()
+		}
+
+		n, Err := readEntry(file, h)
+		if Err != nil {
+			zc.add(file.Name, CorruptEntry, "%s", Err)
+			continue
+		}
+
+		if !hasSum {
+			zc.add(file.Name, NoProblem, "")
+			continue
+		}
+
+		if entry.Size != 0 && entry.Size != n {
+			zc.add(file.Name, SizeMismatch,
+				"expected %d bytes, got %d bytes", entry.Size, n)
+			continue
+		}
+
+		if sum := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(sum, entry.MD5) {
+			zc.add(file.Name, ChecksumMismatch,
+				"expected MD5 '%s', got '%s'", entry.MD5, sum)
+			continue
+		}
+
+		zc.add(file.Name, NoProblem, "")
+	}
+
+	return zc, nil
+}
+
+func (s1 Zip) checkMeasurement(zc *ZipCheck, pol common.Pol, iw int, max float64) {
+	name := s1.Templates[tiff].Render(iw, pol)
+
+	ra, err := s1.Measurement(pol, iw)
+	if err != nil {
+		zc.add(name, CorruptEntry, "%s", err)
+		return
+	}
+	defer ra.Close()
+
+	frac, err := ra.ZeroFraction(zeroCheckStep)
+	if err != nil {
+		zc.add(name, CorruptEntry, "%s", err)
+		return
+	}
+
+	if frac > max {
+		zc.add(name, NoData, "%.1f%% of the samples are zero",
+			100.0*frac)
+	}
+}
+
+// matchTemplate reports whether the whole name of a zipped file matches
+// the template regexp.
+func matchTemplate(tpl, name string) (b bool, err error) {
+	return regexp.MatchString("^"+tpl+"$", name)
+}
+
+// findEntry returns the first file inside the zipfile that matches
+// the template or nil if none of them does.
+func findEntry(files []*zip.File, tpl string) (f *zip.File) {
+	for _, file := range files {
+		if ok, err := matchTemplate(tpl, file.Name); err == nil && ok {
+			return file
+		}
+	}
+	return nil
+}
+
+// readEntry reads the whole entry so the CRC32 stored in the zipfile
+// gets verified by archive/zip. The content is also fed into h if it
+// is not nil.
+func readEntry(file *zip.File, h hash.Hash) (n int64, err error) {
+	in, err := file.Open()
+	if err != nil {
+		return
+	}
+	defer in.Close()
+
+	var w io.Writer = ioutil.Discard
+	if h != nil {
+		w = h
+	}
+
+	return io.Copy(w, in)
+}
+
+type manifestEntry struct {
+	Size int64
+	MD5  string
+}
+
+type xmlManifest struct {
+	DataObjects []struct {
+		ID         string `xml:"ID,attr"`
+		ByteStream struct {
+			Size         int64 `xml:"size,attr"`
+			FileLocation struct {
+				Href string `xml:"href,attr"`
+			} `xml:"fileLocation"`
+			Checksum struct {
+				Name  string `xml:"checksumName,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"checksum"`
+		} `xml:"byteStream"`
+	} `xml:"dataObjectSection>dataObject"`
+}
+
+// readChecksums parses the MD5 checksums of the data objects listed in
+// manifest.safe, keyed by their path inside the zipfile.
+func (s1 Zip) readChecksums(mf *zip.File) (m map[string]manifestEntry, err error) {
+	in, err := mf.Open()
+	if err != nil {
+		return
+	}
+	defer in.Close()
+
+	var manifest xmlManifest
+	if err = xml.NewDecoder(in).Decode(&manifest); err != nil {
+		err = fmt.Errorf("failed to parse manifest: %w", err)
+		return
+	}
+
+	m = make(map[string]manifestEntry, len(manifest.DataObjects))
+
+	for _, do := range manifest.DataObjects {
+		bs := do.ByteStream
+
+		if !strings.EqualFold(bs.Checksum.Name, "MD5") {
+			continue
+		}
+
+		href := filepath.Clean(strings.TrimPrefix(bs.FileLocation.Href, "./"))
+		name := s1.Safe.Join(filepath.ToSlash(href)).GetPath()
+
+		m[name] = manifestEntry{
+			Size: bs.Size,
+			MD5:  strings.TrimSpace(bs.Checksum.Value),
+		}
+	}
+
+	return m, nil
+}
@@ -0,0 +1,43 @@
+package sentinel1
+
+import (
+	"testing"
+
+	"github.com/bozso/gotoolbox/path"
+
+	"github.com/bozso/gomma/common"
+)
+
+func TestMatchTemplate(t *testing.T) {
+	const safe = "S1A_IW_SLC__1SDV_20200301T165010_20200301T165037_031476_039F5C_6A2B.SAFE"
+
+	f, err := path.New(safe).ToFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the template of NewZip for S1A IW SLC products
+	tpls := newTemplates(f, "s1a-iw%d-slc-%s-.*")
+
+	annotation := tpls[annot].Render(1, common.VV)
+
+	for name, expected := range map[string]bool{
+		safe + "/annotation/s1a-iw1-slc-vv-20200301t165010-20200301t165037-031476-039f5c-004.xml": true,
+		// directory entries whose names are prefixes of the template
+		safe + "/":            false,
+		safe + "/annotation/": false,
+		safe + "/annotation/s1a-iw1-slc-vh-20200301t165010-20200301t165037-031476-039f5c-001.xml":                         false,
+		safe + "/annotation/s1a-iw2-slc-vv-20200301t165010-20200301t165037-031476-039f5c-005.xml":                         false,
+		safe + "/annotation/calibration/calibration-s1a-iw1-slc-vv-20200301t165010-20200301t165037-031476-039f5c-004.xml": false,
+	} {
+		matched, err := matchTemplate(annotation, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if matched != expected {
+			t.Errorf("expected %v for '%s' with template '%s', got %v",
+				expected, name, annotation, matched)
+		}
+	}
+}
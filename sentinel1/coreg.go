@@ -3,7 +3,7 @@ package sentinel1
 import (
 	"fmt"
 	"log"
-	"os"
+	"path/filepath"
 
 	"github.com/bozso/emath/limits"
 	"github.com/bozso/gotoolbox/errors"
@@ -21,6 +21,17 @@ type CoregOut struct {
 	RSLC slc.SLC
 	Rslc SLC
 	Ifg  ifg.File
+
+	// Resampled cross-polarized SLCs.
+	Cross map[common.Pol]SLC
+}
+
+// CrossPol is a cross-polarized SLC of the scene being coregistered. It
+// is resampled with the lookup table and offsets estimated from the
+// co-polarized channel.
+type CrossPol struct {
+	Pol common.Pol
+	SLC *SLC
 }
 
 type BurstOverlapThresholds struct {
@@ -51,6 +62,10 @@ type Common struct {
 	BurstOverlapThresh BurstOverlapThresholds `json:"burst_overlap_thresh"`
 
 	OutPaths OutPaths `json:"out_paths"`
+
+	// Directory where the scratch workspaces of the coregistrations are
+	// created, defaults to the RSLC output directory.
+	Workspace path.Dir `json:"workspace"`
 }
 
 type MasterFilePaths struct {
@@ -117,10 +132,22 @@ type CoregOpt struct {
 
 var coregFun = common.Must("S1_coreg_TOPS")
 
-func (c *CoregOpt) Coreg(Slc, ref *SLC) (co CoregOut, err error) {
+/*
+Coreg coregisters Slc to the master SLC, optionally using ref as the
+reference RSLC for the spectral diversity estimation. S1_coreg_TOPS
+runs inside its own workspace created in the Workspace directory (or
+in the RSLC output directory if it is not set). The RSLC and the
+interferogram are moved into their output directories and the
+workspace is removed afterwards. If the coregistration fails, the
+workspace is kept for inspection unless cleaning is requested.
+*/
+func (c *CoregOpt) Coreg(Slc, ref *SLC, cross ...CrossPol) (co CoregOut, err error) {
 	cleaning, flag1 := 0, 0
 
-	if c.Clean {
+	// the cross-polarized channels are resampled with the lookup table
+	// and offsets S1_coreg_TOPS leaves behind, so it must not clean them
+	// up; they are removed with the workspace anyway
+	if c.Clean && len(cross) == 0 {
 		cleaning = 1
 	}
 
@@ -130,13 +157,66 @@ func (c *CoregOpt) Coreg(Slc, ref *SLC) (co CoregOut, err error) {
 
 	m := c.Master.SLC
 
-	slc1Tab, slc1ID := m.Tab, date.Short.Format(m.Time)
-	slc2Tab, slc2ID := Slc.Tab, date.Short.Format(Slc.Time)
+	slc1ID, slc2ID := date.Short.Format(m.Time), date.Short.Format(Slc.Time)
+
+	parent := c.Workspace
+	if len(parent.GetPath()) == 0 {
+		parent = c.OutPaths.RSLC.Dir
+	}
+
+	ws, err := NewWorkspace(parent,
+		fmt.Sprintf("coreg_%s_%s_", slc1ID, slc2ID))
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if err != nil && !c.Clean {
+			log.Printf("Coregistration failed, workspace '%s' is kept.", ws)
+			return
+		}
+
+		if Err := ws.Remove(); Err != nil && err == nil {
+			err = errors.WrapFmt(Err, "failed to remove workspace '%s'", ws)
+		}
+	}()
+
+	slc1Tab, err := ws.AbsTabfile(m.Tab)
+	if err != nil {
+		return
+	}
+
+	slc2Tab, err := ws.AbsTabfile(Slc.Tab)
+	if err != nil {
+		return
+	}
 
 	// TODO: parse opt.hgt
 	hgt := c.Master.Height
 
-	rslc, err := Slc.RSLC(c.OutPaths.RSLC.Dir)
+	// S1_coreg_TOPS runs inside the workspace, so the inputs given with
+	// relative paths are resolved here
+	hgt.DataFile.DataFile, err = filepath.Abs(hgt.DataFile.DataFile)
+	if err != nil {
+		return
+	}
+
+	poly1, err := absFile(c.Poly1)
+	if err != nil {
+		return
+	}
+
+	poly2, err := absFile(c.Poly2)
+	if err != nil {
+		return
+	}
+
+	wsDir, err := ws.Dir()
+	if err != nil {
+		return
+	}
+
+	rslc, err := Slc.RSLC(wsDir)
 	if err != nil {
 		return
 	}
@@ -145,33 +225,39 @@ func (c *CoregOpt) Coreg(Slc, ref *SLC) (co CoregOut, err error) {
 
 	args := []interface{}{
 		slc1Tab, slc1ID, slc2Tab, slc2ID, rslc.Tab, hgt,
-		c.Looks.Rng, c.Looks.Azi, c.Poly1, c.Poly2,
+		c.Looks.Rng, c.Looks.Azi, poly1, poly2,
 		bot.Coherence, bot.MinCoherentPixFraction, bot.MaxPhaseStdev,
 		cleaning, flag1,
 	}
 
 	if ref == nil {
-		log.Printf("Coregistering: '%s'.", slc2Tab)
+		log.Printf("Coregistering: '%s'.", Slc.Tab)
 	} else {
-		rslcRefTab, rslcRefID := ref.Tab, date.Short.Format(ref.Time)
+		rslcRefTab, err := ws.AbsTabfile(ref.Tab)
+		if err != nil {
+			return co, err
+		}
 
-		log.Printf(" Reference: '%s'.\n", rslcRefTab)
+		log.Printf(" Reference: '%s'.\n", ref.Tab)
 
-		args = append(args, rslcRefTab, rslcRefID)
+		args = append(args, rslcRefTab, date.Short.Format(ref.Time))
 	}
 
-	_, err = coregFun.Call(args...)
-	if err != nil {
+	if _, err = ws.Run(coregFun, args...); err != nil {
 		return
 	}
 
 	co.RSLC, err = slc.New(
-		path.New(slc2ID).AddExt("rslc").ToFile()).Load()
+		ws.Join(slc2ID).AddExt("rslc").ToFile()).Load()
 	if err != nil {
 		return
 	}
 
-	ID := path.New(fmt.Sprintf("%s_%s", slc1ID, slc2ID))
+	if co.Rslc, err = rslc.Load(); err != nil {
+		return
+	}
+
+	ID := ws.Join(fmt.Sprintf("%s_%s", slc1ID, slc2ID))
 
 	loader := ifg.New(ID.AddExt("diff")).
 		WithParFile(ID.AddExt("off")).
@@ -184,10 +270,30 @@ func (c *CoregOpt) Coreg(Slc, ref *SLC) (co CoregOut, err error) {
 		return
 	}
 
+	co.Cross = make(map[common.Pol]SLC, len(cross))
+
+	for _, cp := range cross {
+		rslc, err := resampleCrossPol(ws, cp, slc1Tab, slc1ID, slc2ID)
+		if err != nil {
+			return co, errors.WrapFmt(err,
+				"failed to resample %s channel of '%s'", cp.Pol, Slc.Tab)
+		}
+
+		co.Cross[cp.Pol] = rslc
+	}
+
+	// outputs are moved with renames, so they appear in the output
+	// directories only when they are complete
 	if co.Rslc, err = co.Rslc.Move(c.OutPaths.RSLC.Dir); err != nil {
 		return
 	}
 
+	co.RSLC.ComplexWithPar.File, err = co.RSLC.ComplexWithPar.Move(
+		c.OutPaths.RSLC.Dir)
+	if err != nil {
+		return
+	}
+
 	if co.Ifg, err = co.Ifg.Move(c.OutPaths.Ifg.Dir); err != nil {
 		err = errors.WrapFmt(err,
 			"failed to move interferogram '%s' to IFG directory",
@@ -195,39 +301,90 @@ func (c *CoregOpt) Coreg(Slc, ref *SLC) (co CoregOut, err error) {
 		return
 	}
 
-	if c.Clean {
-		glob, Err := path.New(slc1ID + "*").Glob()
-
-		if Err != nil {
-			err = errors.WrapFmt(Err,
-				"globbing for leftover files from coregistration failed")
-			return
+	// cross-polarized RSLCs are grouped into per polarization
+	// subdirectories of the RSLC directory
+	for pol, rslc := range co.Cross {
+		dir, err := c.OutPaths.RSLC.Dir.Join(pol.String()).Mkdir()
+		if err != nil {
+			return co, err
 		}
 
-		for _, file := range glob {
-			if Err = os.Remove(file.String()); err != nil {
-				err = errors.WrapFmt(Err, "failed to remove file '%s'",
-					file)
-				return
-			}
+		if co.Cross[pol], err = rslc.Move(dir); err != nil {
+			return co, err
 		}
+	}
 
-		glob, Err = path.New(slc2ID + "*").Glob()
+	return
+}
+
+var interpFun = common.Must("SLC_interp_lt_ScanSAR")
+
+/*
+resampleCrossPol resamples a cross-polarized SLC into the geometry of
+the master using the lookup table and the refined offsets left in the
+workspace by S1_coreg_TOPS. Outputs are written into a subdirectory of
+the workspace named after the polarization.
+*/
+func resampleCrossPol(ws Workspace, cp CrossPol, masterTab path.ValidFile, slc1ID, slc2ID string) (rslc SLC, err error) {
+	pol := cp.Pol.String()
+
+	dir, err := ws.Join(pol).Mkdir()
+	if err != nil {
+		return
+	}
+
+	tab, err := ws.AbsTabfile(cp.SLC.Tab)
+	if err != nil {
+		return
+	}
 
-		if Err != nil {
-			err = errors.WrapFmt(Err,
-				"globbing for leftover files from coregistration failed")
+	out, err := cp.SLC.RSLC(dir)
+	if err != nil {
+		return
+	}
+
+	ID := fmt.Sprintf("%s_%s", slc1ID, slc2ID)
+
+	for _, name := range []string{ID + ".lt", ID + ".off",
+		slc1ID + ".slc.par", slc2ID + ".slc.par",
+		slc1ID + ".mli.par", slc2ID + ".mli.par"} {
+		if _, err = ws.Join(name).ToValidFile(); err != nil {
+			err = errors.WrapFmt(err, "intermediate file '%s' of "+
+				"S1_coreg_TOPS is missing from workspace '%s'", name, ws)
 			return
 		}
+	}
 
-		for _, file := range glob {
-			if err = os.Remove(file.String()); err != nil {
-				err = errors.WrapFmt(err, "failed to remove file '%s'",
-					file)
-				return
-			}
-		}
+	_, err = ws.Run(interpFun,
+		tab, ws.Join(slc2ID+".slc.par"),
+		masterTab, ws.Join(slc1ID+".slc.par"),
+		ws.Join(ID+".lt"),
+		ws.Join(slc1ID+".mli.par"), ws.Join(slc2ID+".mli.par"),
+		ws.Join(ID+".off"),
+		out.Tab, ws.Join(pol, slc2ID+".rslc"),
+		ws.Join(pol, slc2ID+".rslc.par"))
+	if err != nil {
+		return
 	}
 
-	return
+	return out.Load()
+}
+
+// absFile returns the file with an absolute path, nil if it is not set.
+func absFile(f *path.ValidFile) (p *path.ValidFile, err error) {
+	if f == nil {
+		return nil, nil
+	}
+
+	abs, err := filepath.Abs(f.GetPath())
+	if err != nil {
+		return
+	}
+
+	vf, err := path.New(abs).ToValidFile()
+	if err != nil {
+		return
+	}
+
+	return &vf, nil
 }
@@ -0,0 +1,282 @@
+package sentinel1
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bozso/gotoolbox/path"
+)
+
+// IterationOffset holds the offset estimated in one iteration of the
+// coregistration, in pixels.
+type IterationOffset struct {
+	Iteration int     `json:"iteration"`
+	Azimuth   float64 `json:"azimuth"`
+	Range     float64 `json:"range"`
+}
+
+// OverlapCorrection holds the spectral diversity estimate of one burst
+// overlap.
+type OverlapCorrection struct {
+	IW      int `json:"iw"`
+	Overlap int `json:"overlap"`
+
+	// Azimuth offset correction in pixels.
+	AzimuthOffset float64 `json:"azimuth_offset"`
+
+	// Fraction of pixels above the coherence threshold.
+	CoherentFraction float64 `json:"coherent_fraction"`
+
+	// Standard deviation of the double difference phase in radians.
+	PhaseStdev float64 `json:"phase_stdev"`
+}
+
+/*
+CoregQuality summarizes the results of a S1_coreg_TOPS run. It is
+parsed from the .results (or .coreg_quality) file written by the GAMMA
+scripts. The following lines are recognized:
+
+	matching_iteration_<n>: <daz> <dr> ...
+	az_ovr_iteration_<n>: <daz> ...
+	azimuth_pixel_offset <daz> ...
+	IW<n> <overlap> <daz> <coherent fraction> <phase stdev> ...
+
+everything else is ignored.
+*/
+type CoregQuality struct {
+	Scene string `json:"scene"`
+
+	// Offsets estimated by matching in every iteration.
+	Matching []IterationOffset `json:"matching_iterations"`
+
+	// Azimuth corrections estimated with spectral diversity in every
+	// iteration.
+	SpectralDiversity []IterationOffset `json:"spectral_diversity_iterations"`
+
+	Overlaps []OverlapCorrection `json:"overlaps"`
+
+	// Sum of the azimuth_pixel_offset entries.
+	TotalAzimuthOffset float64 `json:"total_azimuth_offset"`
+
+	Passed   bool     `json:"passed"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+var (
+	iterationRex = regexp.MustCompile(
+		`^(matching|az_ovr)_iteration_(\d+):?\s+(.*)$`)
+	overlapRex = regexp.MustCompile(`^IW(\d)\s+(\d+)\s+(.*)$`)
+)
+
+func parseFloats(s string) (f []float64) {
+	for _, field := range strings.Fields(s) {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			break
+		}
+		f = append(f, v)
+	}
+	return
+}
+
+func ParseCoregQuality(r io.Reader) (cq CoregQuality, err error) {
+	scan := bufio.NewScanner(r)
+
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+
+		if len(line) == 0 {
+			continue
+		}
+
+		if m := iterationRex.FindStringSubmatch(line); m != nil {
+			iter, _ := strconv.Atoi(m[2])
+			vals := parseFloats(m[3])
+
+			if len(vals) == 0 {
+				return cq, fmt.Errorf("no offsets found in line '%s'", line)
+			}
+
+			off := IterationOffset{Iteration: iter, Azimuth: vals[0]}
+
+			if m[1] == "matching" {
+				if len(vals) > 1 {
+					off.Range = vals[1]
+				}
+				cq.Matching = append(cq.Matching, off)
+			} else {
+				cq.SpectralDiversity = append(cq.SpectralDiversity, off)
+			}
+			continue
+		}
+
+		if m := overlapRex.FindStringSubmatch(line); m != nil {
+			vals := parseFloats(m[3])
+
+			if len(vals) < 3 {
+				return cq, fmt.Errorf("expected at least 3 values in burst "+
+					"overlap line '%s'", line)
+			}
+
+			iw, _ := strconv.Atoi(m[1])
+			overlap, _ := strconv.Atoi(m[2])
+
+			cq.Overlaps = append(cq.Overlaps, OverlapCorrection{
+				IW:               iw,
+				Overlap:          overlap,
+				AzimuthOffset:    vals[0],
+				CoherentFraction: vals[1],
+				PhaseStdev:       vals[2],
+			})
+			continue
+		}
+
+		if fields := strings.Fields(line); fields[0] == "azimuth_pixel_offset" {
+			vals := parseFloats(strings.Join(fields[1:], " "))
+
+			if len(vals) == 0 {
+				return cq, fmt.Errorf("no offset found in line '%s'", line)
+			}
+
+			cq.TotalAzimuthOffset += vals[0]
+		}
+	}
+
+	err = scan.Err()
+	return
+}
+
+func LoadCoregQuality(file path.ValidFile) (cq CoregQuality, err error) {
+	f, err := os.Open(file.GetPath())
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	cq, err = ParseCoregQuality(f)
+	return
+}
+
+// FinalSpectralDiversity returns the azimuth correction estimated in the
+// last spectral diversity iteration.
+func (cq CoregQuality) FinalSpectralDiversity() (f float64) {
+	if n := len(cq.SpectralDiversity); n > 0 {
+		f = cq.SpectralDiversity[n-1].Azimuth
+	}
+	return
+}
+
+// MinCoherentFraction returns the lowest coherent pixel fraction of the
+// burst overlaps.
+func (cq CoregQuality) MinCoherentFraction() (f float64) {
+	if len(cq.Overlaps) == 0 {
+		return 0.0
+	}
+
+	f = math.Inf(1)
+	for _, o := range cq.Overlaps {
+		f = math.Min(f, o.CoherentFraction)
+	}
+	return
+}
+
+// MaxPhaseStdev returns the highest phase standard deviation of the
+// burst overlaps.
+func (cq CoregQuality) MaxPhaseStdev() (f float64) {
+	for _, o := range cq.Overlaps {
+		f = math.Max(f, o.PhaseStdev)
+	}
+	return
+}
+
+/*
+Evaluate checks the burst overlaps against the thresholds and sets the
+Passed flag and the list of problems. A coregistration without any
+spectral diversity or burst overlap estimates is considered failed.
+*/
+func (cq *CoregQuality) Evaluate(bot BurstOverlapThresholds) {
+	cq.Problems = nil
+
+	if len(cq.SpectralDiversity) == 0 && len(cq.Overlaps) == 0 {
+		cq.Problems = append(cq.Problems,
+			"no spectral diversity estimates found")
+	}
+
+	minFrac := float64(bot.MinCoherentPixFraction)
+
+	for _, o := range cq.Overlaps {
+		if o.CoherentFraction < minFrac {
+			cq.Problems = append(cq.Problems, fmt.Sprintf(
+				"IW%d overlap %d: coherent fraction %.3f is below %.3f",
+				o.IW, o.Overlap, o.CoherentFraction, minFrac))
+		}
+
+		if max := bot.MaxPhaseStdev; max > 0.0 && o.PhaseStdev > max {
+			cq.Problems = append(cq.Problems, fmt.Sprintf(
+				"IW%d overlap %d: phase stdev %.3f is above %.3f",
+				o.IW, o.Overlap, o.PhaseStdev, max))
+		}
+	}
+
+	cq.Passed = len(cq.Problems) == 0
+}
+
+// StackQuality collects the coregistration reports of a stack.
+type StackQuality []CoregQuality
+
+func (sq StackQuality) Sort() {
+	sort.Slice(sq, func(i, j int) bool {
+		return sq[i].Scene < sq[j].Scene
+	})
+}
+
+// Failed returns the scenes that need to be reprocessed.
+func (sq StackQuality) Failed() (scenes []string) {
+	for _, cq := range sq {
+		if !cq.Passed {
+			scenes = append(scenes, cq.Scene)
+		}
+	}
+	return
+}
+
+// WriteTable writes a summary table with one row for every scene.
+func (sq StackQuality) WriteTable(w io.Writer) (err error) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	_, err = fmt.Fprintln(tw, "scene\titerations\tfinal_daz\tsd_daz\t"+
+		"min_coh_frac\tmax_phase_std\tstatus")
+	if err != nil {
+		return
+	}
+
+	for _, cq := range sq {
+		finalDaz := 0.0
+		if n := len(cq.Matching); n > 0 {
+			finalDaz = cq.Matching[n-1].Azimuth
+		}
+
+		status := "ok"
+		if !cq.Passed {
+			status = "reprocess"
+		}
+
+		_, err = fmt.Fprintf(tw, "%s\t%d\t%.6f\t%.6f\t%.3f\t%.3f\t%s\n",
+			cq.Scene, len(cq.Matching), finalDaz,
+			cq.FinalSpectralDiversity(), cq.MinCoherentFraction(),
+			cq.MaxPhaseStdev(), status)
+		if err != nil {
+			return
+		}
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,143 @@
+package sentinel1
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func loadTestCoregQuality(t *testing.T) (cq CoregQuality) {
+	f, err := os.Open("testdata/20200313_20200301.results")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if cq, err = ParseCoregQuality(f); err != nil {
+		t.Fatal(err)
+	}
+
+	cq.Scene = "20200313"
+	return
+}
+
+func TestParseCoregQuality(t *testing.T) {
+	cq := loadTestCoregQuality(t)
+
+	if n := len(cq.Matching); n != 2 {
+		t.Fatalf("expected 2 matching iterations, got %d", n)
+	}
+
+	if m := cq.Matching[1]; m.Iteration != 2 || !closeTo(m.Azimuth, 0.000541) ||
+		!closeTo(m.Range, 0.000119) {
+		t.Errorf("unexpected offsets of the last matching iteration: %+v", m)
+	}
+
+	if sd := cq.FinalSpectralDiversity(); !closeTo(sd, 0.000018) {
+		t.Errorf("expected final spectral diversity correction 0.000018, "+
+			"got %g", sd)
+	}
+
+	if !closeTo(cq.TotalAzimuthOffset, 0.032856) {
+		t.Errorf("expected total azimuth offset 0.032856, got %g",
+			cq.TotalAzimuthOffset)
+	}
+
+	if n := len(cq.Overlaps); n != 5 {
+		t.Fatalf("expected 5 burst overlaps, got %d", n)
+	}
+
+	if o := cq.Overlaps[3]; o.IW != 2 || o.Overlap != 2 ||
+		!closeTo(o.CoherentFraction, 0.5512) {
+		t.Errorf("unexpected burst overlap: %+v", o)
+	}
+
+	if f := cq.MinCoherentFraction(); !closeTo(f, 0.5512) {
+		t.Errorf("expected minimum coherent fraction 0.5512, got %g", f)
+	}
+
+	if s := cq.MaxPhaseStdev(); !closeTo(s, 0.9418) {
+		t.Errorf("expected maximum phase stdev 0.9418, got %g", s)
+	}
+}
+
+func TestCoregQualityReport(t *testing.T) {
+	for _, c := range []struct {
+		bot      BurstOverlapThresholds
+		passed   bool
+		problems int
+	}{
+		{BurstOverlapThresholds{MinCoherentPixFraction: 0.5}, true, 0},
+		// IW2 overlap 2 has too few coherent pixels
+		{BurstOverlapThresholds{MinCoherentPixFraction: 0.6}, false, 1},
+		// ... and too high phase stdev
+		{BurstOverlapThresholds{MinCoherentPixFraction: 0.6,
+			MaxPhaseStdev: 0.8}, false, 2},
+	} {
+		cq := loadTestCoregQuality(t)
+		cq.Evaluate(c.bot)
+
+		b, err := json.Marshal(cq)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var report struct {
+			Scene    string              `json:"scene"`
+			Passed   bool                `json:"passed"`
+			Problems []string            `json:"problems"`
+			Overlaps []OverlapCorrection `json:"overlaps"`
+		}
+
+		if err = json.Unmarshal(b, &report); err != nil {
+			t.Fatal(err)
+		}
+
+		if report.Scene != "20200313" || report.Passed != c.passed ||
+			len(report.Problems) != c.problems || len(report.Overlaps) != 5 {
+			t.Errorf("unexpected report with thresholds %+v: %s", c.bot, b)
+		}
+	}
+}
+
+func TestStackQualityTable(t *testing.T) {
+	good := loadTestCoregQuality(t)
+	good.Scene = "20200301"
+	good.Evaluate(BurstOverlapThresholds{MinCoherentPixFraction: 0.5})
+
+	bad := loadTestCoregQuality(t)
+	bad.Evaluate(BurstOverlapThresholds{MinCoherentPixFraction: 0.6})
+
+	sq := StackQuality{bad, good}
+	sq.Sort()
+
+	if failed := sq.Failed(); len(failed) != 1 || failed[0] != "20200313" {
+		t.Errorf("expected scene 20200313 to need reprocessing, got %v",
+			failed)
+	}
+
+	var buf bytes.Buffer
+	if err := sq.WriteTable(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and 2 rows, got:\n%s", buf.String())
+	}
+
+	for ii, expected := range [][]string{
+		{"scene", "iterations", "final_daz", "sd_daz", "min_coh_frac",
+			"max_phase_std", "status"},
+		{"20200301", "2", "0.000541", "0.000018", "0.551", "0.942", "ok"},
+		{"20200313", "2", "0.000541", "0.000018", "0.551", "0.942",
+			"reprocess"},
+	} {
+		if got := strings.Fields(lines[ii]); strings.Join(got, " ") !=
+			strings.Join(expected, " ") {
+			t.Errorf("expected row %q, got %q", expected, got)
+		}
+	}
+}
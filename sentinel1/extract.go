@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"regexp"
 
 	"github.com/bozso/gotoolbox/errors"
 	"github.com/bozso/gotoolbox/path"
@@ -65,7 +64,7 @@ func (ex *Extractor) extract(template string) (vf path.ValidFile) {
 	for _, zipfile := range ex.ReadCloser.File {
 		name := zipfile.Name
 
-		matched, err := regexp.MatchString(name, template)
+		matched, err := matchTemplate(template, name)
 		if err != nil {
 			err = errors.WrapFmt(err,
 				"failed to check whether zipped file '%s' matches templates",
@@ -0,0 +1,155 @@
+package sentinel1
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/paulmach/orb"
+
+	"github.com/bozso/gomma/common"
+	"github.com/bozso/gomma/geometry"
+)
+
+type xmlFootprint struct {
+	Coordinates []string `xml:"metadataSection>metadataObject>metadataWrap>xmlData>frameSet>frame>footPrint>coordinates"`
+}
+
+/*
+parseGMLCoordinates parses the content of a gml:coordinates element of
+manifest.safe, which holds "lat,lon" pairs separated by whitespace. The
+returned ring is closed and holds the points in (longitude, latitude)
+order.
+*/
+func parseGMLCoordinates(s string) (r orb.Ring, err error) {
+	for _, pair := range strings.Fields(s) {
+		ll := strings.Split(pair, ",")
+		if len(ll) != 2 {
+			return nil, fmt.Errorf("invalid coordinate pair '%s'", pair)
+		}
+
+		lat, err := strconv.ParseFloat(ll[0], 64)
+		if err != nil {
+			return nil, err
+		}
+
+		lon, err := strconv.ParseFloat(ll[1], 64)
+		if err != nil {
+			return nil, err
+		}
+
+		r = append(r, orb.Point{lon, lat})
+	}
+
+	if len(r) < 3 {
+		return nil, fmt.Errorf("footprint needs at least 3 points, got %d",
+			len(r))
+	}
+
+	if !r.Closed() {
+		r = append(r, r[0])
+	}
+
+	return r, nil
+}
+
+func parseManifestFootprint(r io.Reader) (ring orb.Ring, err error) {
+	var fp xmlFootprint
+	if err = xml.NewDecoder(r).Decode(&fp); err != nil {
+		return
+	}
+
+	if len(fp.Coordinates) == 0 {
+		return nil, fmt.Errorf("no footprint found in manifest")
+	}
+
+	return parseGMLCoordinates(fp.Coordinates[0])
+}
+
+// GridHull returns the convex hull of the geolocation grid points.
+func (a Annotation) GridHull() (r orb.Ring) {
+	pts := make(orb.Ring, len(a.GeolocationGrid))
+	for ii, p := range a.GeolocationGrid {
+		pts[ii] = orb.Point{p.Longitude, p.Latitude}
+	}
+
+	return geometry.ConvexHull(pts)
+}
+
+/*
+Footprint returns the footprint of the product. It is read from the
+frame footprint of manifest.safe; if that is missing, it is the convex
+hull of the geolocation grids of all subswaths.
+*/
+func (s1 Zip) Footprint() (r orb.Ring, err error) {
+	rc, err := zip.OpenReader(s1.Path.GetPath())
+	if err != nil {
+		return
+	}
+	defer rc.Close()
+
+	manifest := s1.Safe.Join(manifestFile).GetPath()
+
+	for _, file := range rc.File {
+		if file.Name != manifest {
+			continue
+		}
+
+		in, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		r, err = parseManifestFootprint(in)
+		in.Close()
+
+		if err == nil {
+			return r, nil
+		}
+		break
+	}
+
+	swaths := s1.SwathMode.Swaths()
+	if s1.IsGRD() {
+		swaths = []int{0}
+	}
+
+	as, err := s1.Annotations(common.AllPolarisation, swaths...)
+	if err != nil {
+		return
+	}
+
+	var pts orb.Ring
+	for _, a := range as {
+		pts = append(pts, a.GridHull()...)
+	}
+
+	return geometry.ConvexHull(pts), nil
+}
+
+// Constants of the Sentinel-1 burst ID definition.
+const (
+	// repeat cycle of 12 days divided into 175 orbits
+	orbitPeriod = 12.0 * 24.0 * 3600.0 / 175.0
+	// burst cycle
+	burstCycle = 2.758273
+	// time of the preamble
+	burstPreamble = 2.299849
+)
+
+/*
+ID returns the ESA burst ID of the burst (e.g. "12345_IW1"), which is
+the same for the bursts of every acquisition of the relative orbit
+covering the same area. The ID is defined by the time of the centre of
+the burst, while AnxTime is the time of its first line.
+*/
+func (b Burst) ID(relativeOrbit int, mode AcquisitionMode) string {
+	t := b.AnxTime + b.Duration/2.0 + float64(relativeOrbit-1)*orbitPeriod
+	id := 1 + int(math.Floor((t-burstPreamble)/burstCycle))
+
+	return fmt.Sprintf("%d_%s%d", id, mode, b.IW)
+}
@@ -0,0 +1,60 @@
+package sentinel1
+
+import (
+	"testing"
+
+	"github.com/paulmach/orb"
+)
+
+func TestBurstID(t *testing.T) {
+	cases := []struct {
+		burst    Burst
+		orbit    int
+		expected string
+	}{
+		// the burst starts before the preamble but its centre is after
+		// it, so it is the first burst of the first relative orbit
+		{Burst{IW: 1, AnxTime: 1.0, Duration: 2.7}, 1, "1_IW1"},
+		// the last burst of the last relative orbit, 375887 is the
+		// highest ID of the ESA burst ID map
+		{Burst{IW: 3, AnxTime: orbitPeriod - 2.0, Duration: 2.7}, 175,
+			"375887_IW3"},
+	}
+
+	for _, c := range cases {
+		if id := c.burst.ID(c.orbit, IWMode); id != c.expected {
+			t.Errorf("expected burst ID '%s', got '%s'", c.expected, id)
+		}
+	}
+}
+
+func TestParseGMLCoordinates(t *testing.T) {
+	expected := orb.Ring{{20.5, 46.1}, {23.8, 46.5}, {24.2, 44.8},
+		{21.0, 44.4}, {20.5, 46.1}}
+
+	for _, s := range []string{
+		"46.1,20.5 46.5,23.8 44.8,24.2 44.4,21.0",
+		" 46.1,20.5\n46.5,23.8 44.8,24.2 44.4,21.0 46.1,20.5 ",
+	} {
+		r, err := parseGMLCoordinates(s)
+		if err != nil {
+			t.Errorf("failed to parse '%s': %s", s, err)
+			continue
+		}
+
+		if !r.Equal(expected) {
+			t.Errorf("expected ring %v from '%s', got %v", expected, s, r)
+		}
+	}
+
+	for _, s := range []string{
+		"46.1,20.5 46.5,23.8",
+		"46.1,20.5 46.5 44.8,24.2",
+		"46.1,20.5 46.5,a 44.8,24.2",
+		"",
+	} {
+		if _, err := parseGMLCoordinates(s); err == nil {
+			t.Errorf("expected an error for '%s'", s)
+		}
+	}
+}
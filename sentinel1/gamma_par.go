@@ -0,0 +1,365 @@
+package sentinel1
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	speedOfLight = 299792458.0
+
+	// WGS84 ellipsoid
+	semiMajor = 6378137.0
+	semiMinor = 6356752.3141
+)
+
+// parWriter writes "key: value" lines in the layout of GAMMA parameter
+// files. The first error is kept and returned by Err.
+type parWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (p *parWriter) line(s string) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintln(p.w, s)
+	}
+}
+
+func (p *parWriter) set(key, format string, args ...interface{}) {
+	p.line(fmt.Sprintf("%-27s ", key+":") + fmt.Sprintf(format, args...))
+}
+
+func (p *parWriter) Err() error {
+	return p.err
+}
+
+func secondsOfDay(t time.Time) float64 {
+	t = t.UTC()
+	y, m, d := t.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+
+	return t.Sub(midnight).Seconds()
+}
+
+func addSeconds(t time.Time, s float64) time.Time {
+	return t.Add(time.Duration(s * float64(time.Second)))
+}
+
+/*
+shiftPolynomial re-expands the polynomial p(x) = sum c_k x^k around
+x = d, i.e. it returns the coefficients of q(y) = p(y + d).
+*/
+func shiftPolynomial(c []float64, d float64) (q []float64) {
+	q = make([]float64, len(c))
+
+	for k, ck := range c {
+		// binomial expansion of ck (y + d)^k
+		binom := 1.0
+		for j := 0; j <= k; j++ {
+			q[j] += ck * binom * math.Pow(d, float64(k-j))
+			binom = binom * float64(k-j) / float64(j+1)
+		}
+	}
+	return
+}
+
+// geocentricRadius returns the distance of the WGS84 ellipsoid from the
+// center of the Earth at the geodetic latitude (in degrees).
+func geocentricRadius(lat float64) float64 {
+	phi := lat * math.Pi / 180.0
+	a, b := semiMajor, semiMinor
+	ca, sb := a*math.Cos(phi), b*math.Sin(phi)
+
+	return math.Sqrt((a*ca*a*ca + b*sb*b*sb) / (ca*ca + sb*sb))
+}
+
+// swathSlice is a range of bursts of a subswath that is imported.
+type swathSlice struct {
+	a          Annotation
+	first, num int
+}
+
+func (s swathSlice) lines() int {
+	return s.num * s.a.SwathTiming.LinesPerBurst
+}
+
+func (s swathSlice) startTime() time.Time {
+	return s.a.SwathTiming.Bursts[s.first].AzimuthTime.Time
+}
+
+// firstLine returns the first line of the slice in the measurement file.
+func (s swathSlice) firstLine() int {
+	return s.first * s.a.SwathTiming.LinesPerBurst
+}
+
+// center returns the approximate latitude, longitude and heading at the
+// center of the slice.
+func (s swathSlice) center() (lat, lon, heading float64, err error) {
+	grid, err := newGeoGrid(s.a.GeolocationGrid)
+	if err != nil {
+		return
+	}
+
+	first := float64(s.firstLine())
+	last := first + float64(s.lines()-1)
+
+	mid := grid.atLine((first + last) / 2.0)
+	top, bottom := grid.atLine(first), grid.atLine(last)
+
+	c := len(mid) / 2
+	lon, lat = mid[c][0], mid[c][1]
+
+	dlat := bottom[c][1] - top[c][1]
+	dlon := (bottom[c][0] - top[c][0]) * math.Cos(lat*math.Pi/180.0)
+
+	heading = math.Atan2(dlon, dlat) * 180.0 / math.Pi
+	return
+}
+
+// doppler returns the Doppler centroid estimate closest to the time.
+func (a Annotation) doppler(t time.Time) (d DopplerEstimate, ok bool) {
+	best := math.Inf(1)
+
+	for _, est := range a.Doppler {
+		if dt := math.Abs(est.AzimuthTime.Sub(t).Seconds()); dt < best {
+			best, d, ok = dt, est, true
+		}
+	}
+	return
+}
+
+/*
+slantRange converts the polynomial from two-way slant range time
+relative to T0 (Hz/s^k) to slant range relative to r (Hz/m^k), the form
+of the doppler_polynomial of ISP parameter files. Terms above the cubic
+one are dropped.
+*/
+func (d DopplerEstimate) slantRange(r float64) (dop []float64) {
+	dop = make([]float64, 4)
+
+	// t - T0 = 2 (r - r0) / c
+	scale, r0 := 1.0, d.T0*speedOfLight/2.0
+	for ii := 0; ii < len(d.Polynomial) && ii < len(dop); ii++ {
+		dop[ii] = d.Polynomial[ii] / scale
+		scale *= speedOfLight / 2.0
+	}
+
+	return shiftPolynomial(dop, r-r0)
+}
+
+/*
+WriteISPPar writes the ISP image parameter file of the slice in the
+format written by par_S1_SLC. The Doppler polynomial is converted from
+slant range time to slant range and referenced to the center range.
+*/
+func (s swathSlice) WriteISPPar(w io.Writer, pol string) (err error) {
+	a, info := s.a, s.a.ImageInformation
+	h := a.Header
+
+	lines, samples := s.lines(), info.NumberOfSamples
+	dt := info.AzimuthTimeInterval
+
+	start := s.startTime()
+	stop := addSeconds(start, float64(lines-1)*dt)
+	center := addSeconds(start, float64(lines-1)*dt/2.0)
+
+	near := info.SlantRangeTime * speedOfLight / 2.0
+	far := near + float64(samples-1)*info.RangePixelSpacing
+	mid := (near + far) / 2.0
+
+	lat, lon, heading, err := s.center()
+	if err != nil {
+		return
+	}
+
+	prf, azbw, rngbw := 0.0, 0.0, 0.0
+	if len(a.Downlink) > 0 {
+		prf = a.Downlink[0].PRF
+	}
+	if len(a.ProcessingParams) > 0 {
+		azbw = a.ProcessingParams[0].AzimuthBandwidth
+		rngbw = a.ProcessingParams[0].RangeBandwidth
+	}
+
+	dop := make([]float64, 4)
+	if est, ok := a.doppler(center); ok {
+		dop = est.slantRange(mid)
+	}
+
+	p := parWriter{w: w}
+
+	p.line("Gamma Interferometric SAR Processor (ISP) - Image Parameter File")
+	p.line("")
+	p.set("title", "%s-%s-%s-%s-%s", strings.ToLower(h.Mission),
+		strings.ToLower(h.Mode), strings.ToLower(h.Swath), pol,
+		start.UTC().Format("20060102t150405"))
+	p.set("sensor", "%s %s %s %s", h.Mission, h.Mode, h.Swath,
+		strings.ToUpper(pol))
+	p.set("date", "%s", start.UTC().Format("2006 01 02"))
+	p.set("start_time", "%.6f   s", secondsOfDay(start))
+	p.set("center_time", "%.6f   s", secondsOfDay(center))
+	p.set("end_time", "%.6f   s", secondsOfDay(stop))
+	p.set("azimuth_line_time", "%.7e   s", dt)
+	p.set("line_header_size", "%d", 0)
+	p.set("range_samples", "%d", samples)
+	p.set("azimuth_lines", "%d", lines)
+	p.set("range_looks", "%d", 1)
+	p.set("azimuth_looks", "%d", 1)
+	p.set("image_format", "%s", "SCOMPLEX")
+	p.set("image_geometry", "%s", "SLANT_RANGE")
+	p.set("range_scale_factor", "%.7e", 1.0)
+	p.set("azimuth_scale_factor", "%.7e", 1.0)
+	p.set("center_latitude", "%.7f   degrees", lat)
+	p.set("center_longitude", "%.7f   degrees", lon)
+	p.set("heading", "%.7f   degrees", heading)
+	p.set("range_pixel_spacing", "%.6f   m", info.RangePixelSpacing)
+	p.set("azimuth_pixel_spacing", "%.6f   m", info.AzimuthPixelSpacing)
+	p.set("near_range_slc", "%.4f  m", near)
+	p.set("center_range_slc", "%.4f  m", mid)
+	p.set("far_range_slc", "%.4f  m", far)
+
+	for _, key := range []string{"first", "center", "last"} {
+		p.set(key+"_slant_range_polynomial", "%s", strings.Repeat(
+			"0.00000e+00  ", 6)+"s m 1 m^-1 m^-2 m^-3")
+	}
+
+	p.set("incidence_angle", "%.4f   degrees", info.IncidenceAngleMidSwath)
+	p.set("azimuth_deskew", "%s", "ON")
+	p.set("azimuth_angle", "%.4f   degrees", 90.0)
+	p.set("radar_frequency", "%.7e   Hz", a.ProductInformation.RadarFrequency)
+	p.set("adc_sampling_rate", "%.7e   Hz",
+		a.ProductInformation.RangeSamplingRate)
+	p.set("chirp_bandwidth", "%.7e   Hz", rngbw)
+	p.set("prf", "%.7f   Hz", prf)
+	p.set("azimuth_proc_bandwidth", "%.5f   Hz", azbw)
+	p.set("doppler_polynomial", "%.5e %.5e %.5e %.5e  Hz Hz/m Hz/m^2 Hz/m^3",
+		dop[0], dop[1], dop[2], dop[3])
+	p.set("doppler_poly_dot", "%s", strings.Repeat("0.00000e+00 ", 4)+
+		" Hz/s Hz/s/m Hz/s/m^2 Hz/s/m^3")
+	p.set("doppler_poly_ddot", "%s", strings.Repeat("0.00000e+00 ", 4)+
+		" Hz/s^2 Hz/s^2/m Hz/s^2/m^2 Hz/s^2/m^3")
+	p.set("receiver_gain", "%.4f   dB", 0.0)
+	p.set("calibration_gain", "%.4f   dB", 0.0)
+
+	if sv, ok := s.centerPosition(center); ok {
+		p.set("sar_to_earth_center", "%.4f   m", sv)
+	}
+	p.set("earth_radius_below_sensor", "%.4f   m", geocentricRadius(lat))
+	p.set("earth_semi_major_axis", "%.4f   m", semiMajor)
+	p.set("earth_semi_minor_axis", "%.4f   m", semiMinor)
+
+	orbits := a.Orbits
+	p.set("number_of_state_vectors", "%d", len(orbits))
+
+	if len(orbits) > 0 {
+		p.set("time_of_first_state_vector", "%.6f   s",
+			secondsOfDay(orbits[0].Time.Time))
+	}
+
+	if len(orbits) > 1 {
+		p.set("state_vector_interval", "%.6f   s",
+			orbits[1].Time.Sub(orbits[0].Time.Time).Seconds())
+	}
+
+	for ii, o := range orbits {
+		pos, vel := o.Position, o.Velocity
+		p.set(fmt.Sprintf("state_vector_position_%d", ii+1),
+			"%.4f  %.4f  %.4f   m   m   m", pos.X, pos.Y, pos.Z)
+		p.set(fmt.Sprintf("state_vector_velocity_%d", ii+1),
+			"%.5f  %.5f  %.5f   m/s m/s m/s", vel.X, vel.Y, vel.Z)
+	}
+
+	return p.Err()
+}
+
+// centerPosition returns the distance of the sensor from the center of
+// the Earth at the time, interpolated linearly between state vectors.
+func (s swathSlice) centerPosition(t time.Time) (r float64, ok bool) {
+	orbits := s.a.Orbits
+
+	for ii := 1; ii < len(orbits); ii++ {
+		t0, t1 := orbits[ii-1].Time.Time, orbits[ii].Time.Time
+		if t.Before(t0) || t.After(t1) {
+			continue
+		}
+
+		w := t.Sub(t0).Seconds() / t1.Sub(t0).Seconds()
+		p0, p1 := orbits[ii-1].Position, orbits[ii].Position
+
+		x := p0.X + w*(p1.X-p0.X)
+		y := p0.Y + w*(p1.Y-p0.Y)
+		z := p0.Z + w*(p1.Z-p0.Z)
+
+		return math.Sqrt(x*x + y*y + z*z), true
+	}
+
+	return 0.0, false
+}
+
+// validRange returns the first and last index of the non-negative
+// entries of the list, -1 if there are none.
+func validRange(l floatList) (first, last int) {
+	first, last = -1, -1
+	for ii, v := range l {
+		if v < 0 {
+			continue
+		}
+		if first < 0 {
+			first = ii
+		}
+		last = ii
+	}
+	return
+}
+
+/*
+WriteTOPSPar writes the TOPS parameters of the slice: the burst timing,
+the azimuth steering rate and the valid lines and samples of every
+burst. Lines and samples are counted from the start of the burst and
+from the first sample of the line, respectively.
+*/
+func (s swathSlice) WriteTOPSPar(w io.Writer) (err error) {
+	a := s.a
+	lpb := a.SwathTiming.LinesPerBurst
+
+	p := parWriter{w: w}
+
+	p.set("number_of_bursts", "%d", s.num)
+	p.set("lines_per_burst", "%d", lpb)
+	p.set("az_steering_rate", "%.7f   degrees/s",
+		a.ProductInformation.AzimuthSteeringRate)
+
+	for ii := 0; ii < s.num; ii++ {
+		b := a.SwathTiming.Bursts[s.first+ii]
+		n := ii + 1
+
+		firstLine, lastLine := validRange(b.FirstValidSample)
+
+		firstSample, lastSample := -1.0, -1.0
+		for _, v := range b.FirstValidSample {
+			if v >= 0 && (firstSample < 0 || v < firstSample) {
+				firstSample = v
+			}
+		}
+		for _, v := range b.LastValidSample {
+			if v > lastSample {
+				lastSample = v
+			}
+		}
+
+		p.set(fmt.Sprintf("burst_asc_node_%d", n), "%.6f   s",
+			b.AzimuthAnxTime)
+		p.set(fmt.Sprintf("burst_start_time_%d", n), "%.6f   s",
+			secondsOfDay(b.AzimuthTime.Time))
+		p.set(fmt.Sprintf("first_valid_line_%d", n), "%d", firstLine)
+		p.set(fmt.Sprintf("last_valid_line_%d", n), "%d", lastLine)
+		p.set(fmt.Sprintf("first_valid_sample_%d", n), "%d", int(firstSample))
+		p.set(fmt.Sprintf("last_valid_sample_%d", n), "%d", int(lastSample))
+	}
+
+	return p.Err()
+}
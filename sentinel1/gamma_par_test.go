@@ -0,0 +1,157 @@
+package sentinel1
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+func loadTestAnnotation(t *testing.T) (a Annotation) {
+	f, err := os.Open("testdata/s1a-iw1-slc-vv.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if a, err = ParseAnnotation(f); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1.0, math.Max(math.Abs(a), math.Abs(b)))
+}
+
+func TestShiftPolynomial(t *testing.T) {
+	// p(x) = 1 + 2x + 3x^2 + 4x^3
+	c := []float64{1, 2, 3, 4}
+	q := shiftPolynomial(c, 2)
+
+	// q(y) = p(y + 2) = 49 + 62y + 27y^2 + 4y^3
+	expected := []float64{49, 62, 27, 4}
+
+	for ii := range expected {
+		if !closeTo(q[ii], expected[ii]) {
+			t.Fatalf("expected coefficients %v, got %v", expected, q)
+		}
+	}
+
+	for _, y := range []float64{-3.5, 0, 1.25} {
+		p := c[0] + c[1]*(y+2) + c[2]*(y+2)*(y+2) + c[3]*math.Pow(y+2, 3)
+		v := q[0] + q[1]*y + q[2]*y*y + q[3]*y*y*y
+
+		if !closeTo(p, v) {
+			t.Errorf("p(%g) = %g differs from q(%g) = %g", y+2, p, y, v)
+		}
+	}
+}
+
+func TestDopplerSlantRange(t *testing.T) {
+	d := DopplerEstimate{T0: 5.336e-3, Polynomial: floatList{10, 2e5, -3e7}}
+
+	r0 := d.T0 * speedOfLight / 2.0
+	r := r0 + 1000.0
+	dop := d.slantRange(r)
+
+	// the polynomials agree at every slant range
+	for _, dr := range []float64{-2000.0, 0.0, 500.0} {
+		tau := 2.0 * (r + dr) / speedOfLight
+		dt := tau - d.T0
+
+		expected := d.Polynomial[0] + d.Polynomial[1]*dt +
+			d.Polynomial[2]*dt*dt
+		got := dop[0] + dop[1]*dr + dop[2]*dr*dr + dop[3]*dr*dr*dr
+
+		if !closeTo(expected, got) {
+			t.Errorf("expected %g Hz at %g m from the reference, got %g Hz",
+				expected, dr, got)
+		}
+	}
+
+	// without a shift the coefficients are scaled by (2 / c)^k
+	dop = d.slantRange(r0)
+	if expected := 2e5 * 2.0 / speedOfLight; !closeTo(dop[1], expected) {
+		t.Errorf("expected a linear term of %g Hz/m, got %g", expected, dop[1])
+	}
+}
+
+func TestValidRange(t *testing.T) {
+	cases := []struct {
+		l           floatList
+		first, last int
+	}{
+		{floatList{-1, -1, 3, 4, 4, -1}, 2, 4},
+		{floatList{0, 1, -1, 2}, 0, 3},
+		{floatList{-1, -1}, -1, -1},
+		{nil, -1, -1},
+	}
+
+	for _, c := range cases {
+		if first, last := validRange(c.l); first != c.first || last != c.last {
+			t.Errorf("expected valid range [%d, %d] of %v, got [%d, %d]",
+				c.first, c.last, c.l, first, last)
+		}
+	}
+}
+
+func testParFile(t *testing.T, name string, write func(*strings.Builder) error) {
+	expected, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sb := &strings.Builder{}
+	if err = write(sb); err != nil {
+		t.Fatal(err)
+	}
+
+	got := strings.Split(sb.String(), "\n")
+	for ii, line := range strings.Split(string(expected), "\n") {
+		if ii >= len(got) || got[ii] != line {
+			t.Fatalf("%s differs at line %d:\nexpected: %q\ngot:      %q\n",
+				name, ii+1, line, strings.Join(got[ii:], "\n"))
+		}
+	}
+
+	if len(got) != len(strings.Split(string(expected), "\n")) {
+		t.Errorf("%s has %d extra lines", name,
+			len(got)-len(strings.Split(string(expected), "\n")))
+	}
+}
+
+func TestWriteParFiles(t *testing.T) {
+	a := loadTestAnnotation(t)
+	slice := swathSlice{a: a, first: 0, num: 2}
+
+	testParFile(t, "s1a-iw1-slc-vv.par", func(sb *strings.Builder) error {
+		return slice.WriteISPPar(sb, "vv")
+	})
+
+	testParFile(t, "s1a-iw1-slc-vv.TOPS_par", func(sb *strings.Builder) error {
+		return slice.WriteTOPSPar(sb)
+	})
+
+	// the valid lines and samples of the second burst on its own
+	slice = swathSlice{a: a, first: 1, num: 1}
+
+	sb := &strings.Builder{}
+	if err := slice.WriteTOPSPar(sb); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{
+		"number_of_bursts:           1",
+		"burst_asc_node_1:           1234.508000   s",
+		"first_valid_line_1:         1",
+		"last_valid_line_1:          3",
+		"first_valid_sample_1:       1",
+		"last_valid_sample_1:        9",
+	} {
+		if !strings.Contains(sb.String(), line+"\n") {
+			t.Errorf("expected '%s' in the TOPS parameters:\n%s", line, sb)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package sentinel1
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/bozso/gotoolbox/errors"
+	"github.com/bozso/gotoolbox/path"
+
+	"github.com/bozso/gomma/common"
+	"github.com/bozso/gomma/data"
+	"github.com/bozso/gomma/date"
+	"github.com/bozso/gomma/geo"
+	"github.com/bozso/gomma/mli"
+	"github.com/bozso/gomma/utils/params"
+)
+
+// Backscatter selects the radiometric normalization of GRD products.
+type Backscatter int
+
+const (
+	// Sigma0 backscatter, normalized with the ellipsoid area.
+	Sigma0 Backscatter = iota
+	// Gamma0 backscatter, normalized with the terrain pixel area.
+	Gamma0
+)
+
+func (b *Backscatter) Set(s string) (err error) {
+	const mode errors.Mode = "backscatter normalization"
+
+	switch strings.ToLower(s) {
+	case "sigma0":
+		*b = Sigma0
+	case "gamma0":
+		*b = Gamma0
+	default:
+		err = mode.Error(s)
+	}
+	return
+}
+
+func (b Backscatter) String() (s string) {
+	switch b {
+	case Sigma0:
+		s = "sigma0"
+	case Gamma0:
+		s = "gamma0"
+	default:
+		s = "unknown"
+	}
+	return
+}
+
+func (b Backscatter) MarshalJSON() (by []byte, err error) {
+	return json.Marshal(b.String())
+}
+
+func (b *Backscatter) UnmarshalJSON(by []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(by, &s); err != nil {
+		return
+	}
+
+	return b.Set(s)
+}
+
+// GRDOptions configures the processing of a GRD product.
+type GRDOptions struct {
+	// Channel to process, defaults to the co-polarized channel.
+	Pol *common.Pol `json:"polarization"`
+
+	// Subtract the thermal noise using the noise annotation.
+	NoiseRemoval bool `json:"noise_removal"`
+
+	// Number of looks applied to the calibrated image before
+	// terrain-correction.
+	Looks common.RngAzi `json:"looks"`
+
+	Backscatter Backscatter `json:"backscatter"`
+
+	// Options of the lookup table calculation. The master MLI is set to
+	// the multi-looked image of the product.
+	Geocode geo.CodeOpt `json:"geocode"`
+
+	// Keep the workspaces of the intermediate files for inspection.
+	KeepWorkspace bool `json:"keep_workspace"`
+}
+
+// removeWorkspace removes the workspace unless it should be kept. A
+// failure to remove it does not fail the processing, it is only logged.
+func (opt GRDOptions) removeWorkspace(ws Workspace) {
+	if opt.KeepWorkspace {
+		log.Printf("Workspace '%s' is kept.", ws)
+		return
+	}
+
+	if err := ws.Remove(); err != nil {
+		log.Printf("failed to remove workspace '%s': %s", ws, err)
+	}
+}
+
+// GRDOut holds the results of the GRD processing.
+type GRDOut struct {
+	// Calibrated intensity in radar geometry.
+	MLI mli.MLI `json:"mli"`
+
+	// Terrain-corrected backscatter in map geometry.
+	Geocoded path.ValidFile `json:"geocoded"`
+}
+
+var (
+	parS1GRD     = common.Must("par_S1_GRD")
+	multiLookMLI = common.Must("multi_look_MLI")
+	ratio        = common.Must("ratio")
+	geocodeBack  = common.Must("geocode_back")
+)
+
+// grdName identifies the product by its start time, since consecutive
+// frames of a pass are acquired on the same day.
+func (s1 Zip) grdName(pol common.Pol) string {
+	return fmt.Sprintf("%s_%s_%s", s1.mission,
+		date.Long.Format(s1.Start()), pol)
+}
+
+// grdDir returns the directory of the outputs of the product inside dst,
+// every product has its own lookup table there.
+func (s1 Zip) grdDir(dst path.Dir, pol common.Pol) (d path.Dir, err error) {
+	return dst.Join(s1.grdName(pol)).Mkdir()
+}
+
+/*
+ImportGRD imports a GRD product into its own directory inside dst and
+converts it into terrain-corrected backscatter. The detected image is
+calibrated to sigma0 (with optional thermal noise removal) by
+par_S1_GRD, multi-looked, and geocoded with the lookup table created by
+geo.CodeOpt in the geo subdirectory of the product directory. For
+gamma0 the calibrated image is normalized with the simulated pixel area
+before geocoding. Intermediate files are written into workspaces inside
+dst, which are removed when the processing ends unless KeepWorkspace is
+set.
+*/
+func (s1 Zip) ImportGRD(dst path.Dir, opt GRDOptions) (out GRDOut, err error) {
+	if !s1.IsGRD() {
+		return out, fmt.Errorf("zipfile '%s' is not a GRD product", s1.Path)
+	}
+
+	pol := s1.pol
+	if opt.Pol != nil {
+		pol = *opt.Pol
+	}
+
+	ext := s1.newExtractor(dst)
+	if err = ext.Err(); err != nil {
+		return
+	}
+	defer ext.Close()
+
+	ext.pol = pol
+
+	Tiff := ext.Extract(tiff, 0)
+	Annot := ext.Extract(annot, 0)
+	Calib := ext.Extract(calib, 0)
+
+	var Noise *path.ValidFile
+	if opt.NoiseRemoval {
+		n := ext.Extract(noise, 0)
+		Noise = &n
+	}
+
+	if err = ext.Err(); err != nil {
+		return
+	}
+
+	name := s1.grdName(pol)
+
+	if dst, err = s1.grdDir(dst, pol); err != nil {
+		return
+	}
+
+	ws, err := NewWorkspace(dst, "grd")
+	if err != nil {
+		return
+	}
+	defer opt.removeWorkspace(ws)
+
+	par, dat := name+".mli.par", name+".mli"
+
+	noiseFlag := 0
+	if opt.NoiseRemoval {
+		noiseFlag = 1
+	}
+
+	// the programs write only the files named in their arguments, so
+	// they do not have to run inside the workspace
+	_, err = parS1GRD.Call(Tiff, Annot, Calib, Noise,
+		ws.Join(par), ws.Join(dat), nil, nil, nil, nil, noiseFlag)
+	if err != nil {
+		return
+	}
+
+	looks := opt.Looks
+	if looks.Rng > 1 || looks.Azi > 1 {
+		if looks.Rng < 1 {
+			looks.Rng = 1
+		}
+		if looks.Azi < 1 {
+			looks.Azi = 1
+		}
+
+		mpar, mdat := name+".ml.mli.par", name+".ml.mli"
+
+		_, err = multiLookMLI.Call(ws.Join(dat), ws.Join(par),
+			ws.Join(mdat), ws.Join(mpar), looks.Rng, looks.Azi)
+		if err != nil {
+			return
+		}
+
+		par, dat = mpar, mdat
+	}
+
+	for _, file := range []string{par, dat} {
+		if err = os.Rename(ws.Join(file).GetPath(),
+			dst.Join(file).GetPath()); err != nil {
+			return
+		}
+	}
+
+	parFile, err := dst.Join(par).ToValidFile()
+	if err != nil {
+		return
+	}
+
+	r, err := data.LoadReal(data.DefaultLoader(),
+		data.New(dst.Join(dat).GetPath()).WithParFile(parFile.GetPath()))
+	if err != nil {
+		return
+	}
+
+	out.MLI = mli.MLI{
+		FileWithPar: data.FileWithPar{
+			Parameter: data.Parameter{ParFile: parFile},
+			File:      r.File,
+		},
+	}
+
+	opt.Geocode.MasterMLI = out.MLI
+	if err = opt.Geocode.Run(dst); err != nil {
+		err = errors.WrapFmt(err,
+			"failed to calculate lookup table for GRD '%s'", s1.Path)
+		return
+	}
+
+	out.Geocoded, err = s1.geocodeGRD(dst, name, out.MLI, opt)
+	return
+}
+
+func (s1 Zip) geocodeGRD(dst path.Dir, name string, m mli.MLI, opt GRDOptions) (vf path.ValidFile, err error) {
+	geodir := dst.Join("geo")
+	b := opt.Backscatter
+
+	ws, err := NewWorkspace(dst, "geocode")
+	if err != nil {
+		return
+	}
+	defer opt.removeWorkspace(ws)
+
+	width := m.Rng()
+	in := path.New(m.File.DataFile.DataFile)
+
+	if b == Gamma0 {
+		// terrain flattening (Small, 2011) of the ellipsoid sigma0
+		normed := ws.Join(name + ".gamma0")
+
+		_, err = ratio.Call(in, geodir.Join("gamma0"), normed,
+			width, 0, 0)
+		if err != nil {
+			return
+		}
+
+		in = normed
+	}
+
+	demPar, err := geodir.Join("dem_seg.dem.par").ToValidFile()
+	if err != nil {
+		return
+	}
+
+	p, err := params.FromFile(demPar, ":")
+	if err != nil {
+		return
+	}
+
+	demWidth, err := p.ToParser().Int("width", 0)
+	if err != nil {
+		return
+	}
+
+	geoName := fmt.Sprintf("%s.%s.geo", name, b)
+
+	// magic numbers: nlines = 0 (all), interp_mode = 1 (bicubic spline),
+	// dtype = 0 (float)
+	_, err = geocodeBack.Call(in, width, geodir.Join("lookup"),
+		ws.Join(geoName), demWidth, 0, 1, 0)
+	if err != nil {
+		return
+	}
+
+	dstFile := geodir.Join(geoName)
+
+	if err = os.Rename(ws.Join(geoName).GetPath(), dstFile.GetPath()); err != nil {
+		return
+	}
+
+	return dstFile.ToValidFile()
+}
@@ -0,0 +1,78 @@
+package sentinel1
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bozso/gotoolbox/path"
+
+	"github.com/bozso/gomma/common"
+)
+
+func TestGRDDir(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "grd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	// consecutive frames of the same pass
+	names := []string{
+		"S1A_IW_GRDH_1SDV_20200301T165010_20200301T165035_031476_039F5C_1A2B.zip",
+		"S1A_IW_GRDH_1SDV_20200301T165035_20200301T165100_031476_039F5C_3C4D.zip",
+	}
+
+	dst, err := path.New(tmp).ToDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dirs := map[string]bool{}
+
+	for _, name := range names {
+		p := filepath.Join(tmp, name)
+		if err = ioutil.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		vf, err := path.New(p).ToValidFile()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s1, err := NewZip(vf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		d, err := s1.grdDir(dst, common.VV)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dirs[d.String()] = true
+
+		// the directory of the same product is reused
+		again, err := s1.grdDir(dst, common.VV)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if again.String() != d.String() {
+			t.Errorf("expected directory '%s' for '%s' again, got '%s'", d,
+				name, again)
+		}
+	}
+
+	if len(dirs) != len(names) {
+		t.Errorf("expected a separate directory for every frame, got %v",
+			dirs)
+	}
+
+	for d := range dirs {
+		if fi, err := os.Stat(d); err != nil || !fi.IsDir() {
+			t.Errorf("expected directory '%s' to be created", d)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package sentinel1
 import (
 	"fmt"
 	"io"
+	"sort"
 	//"bytes"
 
 	"github.com/bozso/gotoolbox/path"
@@ -39,6 +40,47 @@ func (sf SwathFlag) String() (s string) {
 	return
 }
 
+/*
+NewSwathFlag returns the flag selecting the subswaths to be imported.
+Dedicated flags only exist for IW products, for other modes (and for
+combinations without a flag) the subswaths listed in the burst table
+are imported.
+*/
+func NewSwathFlag(mode AcquisitionMode, swaths []int) (sf SwathFlag) {
+	if mode != IWMode {
+		return AsListed
+	}
+
+	sorted := append([]int(nil), swaths...)
+	sort.Ints(sorted)
+
+	switch fmt.Sprint(sorted) {
+	case "[1]":
+		sf = One
+	case "[2]":
+		sf = Two
+	case "[3]":
+		sf = Three
+	case "[1 2]":
+		sf = OneTwo
+	case "[2 3]":
+		sf = TwoThree
+	default:
+		sf = AsListed
+	}
+	return
+}
+
+// SwathFlag returns the flag selecting the subswaths of the table.
+func (bt BurstTable) SwathFlag() (sf SwathFlag) {
+	swaths := make([]int, len(bt.Swaths))
+	for ii, s := range bt.Swaths {
+		swaths[ii] = s.IW
+	}
+
+	return NewSwathFlag(bt.Mode, swaths)
+}
+
 type Noise int
 
 const (
@@ -123,8 +165,8 @@ type Importer struct {
 	burstTable path.ValidFile
 }
 
-func (im Importer) Import(one, two *Zip) (err error) {
-	err = im.WriteZiplist(one, two)
+func (im Importer) Import(zips ...*Zip) (err error) {
+	err = im.WriteZiplist(zips...)
 	if err != nil {
 		return
 	}
@@ -133,35 +175,35 @@ func (im Importer) Import(one, two *Zip) (err error) {
 	return
 }
 
-func (im Importer) WriteZiplist(one, two *Zip) (err error) {
+func (im Importer) WriteZiplist(zips ...*Zip) (err error) {
 	zipList, err := im.ZiplistFile.Create()
 	if err != nil {
 		return
 	}
 	defer zipList.Close()
 
-	err = im.FormatZiplist(zipList, one, two)
+	err = im.FormatZiplist(zipList, zips...)
 	return
 }
 
-func (im Importer) FormatZiplist(w io.Writer, one, two *Zip) (err error) {
-	if two == nil {
-		_, err = fmt.Fprintf(w, "%s\n", one.Path.String())
-	} else {
-		first, second := one, two
+func (im Importer) FormatZiplist(w io.Writer, zips ...*Zip) (err error) {
+	return FormatZiplist(w, zips...)
+}
 
-		after := two.Date().After(one.Date())
+// FormatZiplist writes the paths of the zipfiles ordered by their start
+// time, one path per line.
+func FormatZiplist(w io.Writer, zips ...*Zip) (err error) {
+	ordered := make(Zips, len(zips))
+	copy(ordered, zips)
 
-		if !after {
-			first, second = second, first
-		}
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].Start().Before(ordered[j].Start())
+	})
 
-		_, err = fmt.Fprintf(w, "%s\n", one.Path.String())
-		if err != nil {
+	for _, zip := range ordered {
+		if _, err = fmt.Fprintf(w, "%s\n", zip.Path.String()); err != nil {
 			return
 		}
-
-		_, err = fmt.Fprintf(w, "%s\n", second.Path.String())
 	}
 
 	return
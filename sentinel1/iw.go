@@ -8,14 +8,12 @@ import (
 	"github.com/bozso/gomma/data"
 )
 
-const maxIW = 3
-
 type IW struct {
 	data.ComplexWithPar
 	TOPSPar path.ValidFile
 }
 
-type IWs [maxIW]IW
+type IWs [maxSwath]IW
 
 func (iw IW) Tabline() (s string) {
 	s = fmt.Sprintf("%s %s %s\n", iw.DatFile, iw.ParFile, iw.TOPSPar)
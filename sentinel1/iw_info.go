@@ -1,6 +1,7 @@
 package sentinel1
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 
@@ -12,18 +13,16 @@ import (
 	"github.com/bozso/gomma/utils/params"
 )
 
-const (
-	nMaxBurst = 10
-)
-
 type (
 	IWInfo struct {
 		nburst int
 		extent common.LatLonRegion
-		bursts [nMaxBurst]float64
+		bursts []float64
 	}
 
-	IWInfos [maxIW]IWInfo
+	// IWInfos holds the information of every subswath of a product, its
+	// length depends on the acquisition mode.
+	IWInfos []IWInfo
 )
 
 var (
@@ -31,13 +30,44 @@ var (
 	burstCorners = common.Select("ScanSAR_burst_corners", "SLC_burst_corners")
 )
 
+type iwInfoJSON struct {
+	NBurst int                 `json:"number_of_bursts"`
+	Extent common.LatLonRegion `json:"extent"`
+	Bursts []float64           `json:"burst_asc_node"`
+}
+
+func (iw IWInfo) MarshalJSON() (b []byte, err error) {
+	return json.Marshal(iwInfoJSON{
+		NBurst: iw.nburst,
+		Extent: iw.extent,
+		Bursts: iw.bursts,
+	})
+}
+
+func (iw *IWInfo) UnmarshalJSON(b []byte) (err error) {
+	var info iwInfoJSON
+	if err = json.Unmarshal(b, &info); err != nil {
+		return
+	}
+
+	if info.NBurst != len(info.Bursts) {
+		return fmt.Errorf("invalid number of bursts %d", info.NBurst)
+	}
+
+	iw.nburst, iw.extent, iw.bursts = info.NBurst, info.Extent, info.Bursts
+
+	return nil
+}
+
 func iwInfo(file path.ValidFile) (iw IWInfo, err error) {
 	// num, err := conv.Atoi(str.Split(path, "iw")[1][0]);
 
 	// Check(err, "Failed to retreive IW number from %s", path);
 
-	pPar := path.New("tmp").ToFile()
-	pTOPSPar := pPar.AddExt("TOPS_par").ToFile()
+	// parameter files are written next to the extracted annotation file,
+	// so parsing different zipfiles in parallel does not clash
+	pPar := file.AddExt("par").ToFile()
+	pTOPSPar := file.AddExt("TOPS_par").ToFile()
 
 	_, err = parCmd.Call(nil, file, nil, nil, pPar, nil, pTOPSPar)
 	if err != nil {
@@ -73,7 +103,7 @@ func iwInfo(file path.ValidFile) (iw IWInfo, err error) {
 		return
 	}
 
-	var numbers [nMaxBurst]float64
+	numbers := make([]float64, nburst)
 
 	const burstTpl = "burst_asc_node_%d"
 
@@ -108,7 +138,9 @@ func (s1 Zip) Info(dst path.Dir) (iws IWInfos, err error) {
 	}
 	defer ext.Close()
 
-	for ii := 1; ii < 4; ii++ {
+	iws = make(IWInfos, s1.SwathMode.NumSwaths())
+
+	for ii := 1; ii <= len(iws); ii++ {
 		Annot := ext.Extract(annot, ii)
 
 		if err = ext.Err(); err != nil {
@@ -154,7 +186,11 @@ func diffBurstNum(burst1, burst2 float64) int {
 }
 
 func checkBurstNum(one, two IWInfos) bool {
-	for ii := 0; ii < 3; ii++ {
+	if len(one) != len(two) {
+		return true
+	}
+
+	for ii := range one {
 		if one[ii].nburst != two[ii].nburst {
 			return true
 		}
@@ -163,7 +199,12 @@ func checkBurstNum(one, two IWInfos) bool {
 }
 
 func IWAbsDiff(one, two IWInfos) (sum float64, err error) {
-	for ii := 0; ii < 3; ii++ {
+	if len(one) != len(two) {
+		return 0.0, fmt.Errorf("number of subswaths of the SLCs differ "+
+			"(%d and %d)", len(one), len(two))
+	}
+
+	for ii := range one {
 		nburst1, nburst2 := one[ii].nburst, two[ii].nburst
 		if nburst1 != nburst2 {
 			err = fmt.Errorf(
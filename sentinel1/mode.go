@@ -0,0 +1,93 @@
+package sentinel1
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/bozso/gotoolbox/errors"
+)
+
+// Maximum number of subswaths of the supported acquisition modes.
+const maxSwath = 5
+
+// AcquisitionMode is the TOPS acquisition mode of a Sentinel-1 product.
+type AcquisitionMode int
+
+const (
+	// Interferometric Wide swath mode with 3 subswaths.
+	IWMode AcquisitionMode = iota
+	// Extra Wide swath mode with 5 subswaths.
+	EWMode
+)
+
+func (a *AcquisitionMode) Set(s string) (err error) {
+	const mode errors.Mode = "acquisition mode"
+
+	switch strings.ToLower(s) {
+	case "iw":
+		*a = IWMode
+	case "ew":
+		*a = EWMode
+	default:
+		err = mode.Error(s)
+	}
+	return
+}
+
+func (a AcquisitionMode) String() (s string) {
+	switch a {
+	case IWMode:
+		s = "IW"
+	case EWMode:
+		s = "EW"
+	default:
+		s = "unknown"
+	}
+	return
+}
+
+func (a AcquisitionMode) MarshalJSON() (b []byte, err error) {
+	return json.Marshal(a.String())
+}
+
+func (a *AcquisitionMode) UnmarshalJSON(b []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(b, &s); err != nil {
+		return
+	}
+
+	return a.Set(s)
+}
+
+// NumSwaths returns the number of subswaths of the mode.
+func (a AcquisitionMode) NumSwaths() (n int) {
+	switch a {
+	case IWMode:
+		n = 3
+	case EWMode:
+		n = 5
+	}
+	return
+}
+
+// Swaths returns the numbers of all subswaths, starting from 1.
+func (a AcquisitionMode) Swaths() (s []int) {
+	n := a.NumSwaths()
+	s = make([]int, n)
+
+	for ii := range s {
+		s[ii] = ii + 1
+	}
+	return
+}
+
+// SwathPrefix returns the prefix of the subswath identifiers used in the
+// filenames of the product, e.g. "iw" for IW1.
+func (a AcquisitionMode) SwathPrefix() (s string) {
+	return strings.ToLower(a.String())
+}
+
+// ValidSwath reports whether the mode has a subswath with the number.
+func (a AcquisitionMode) ValidSwath(n int) (b bool) {
+	return n >= 1 && n <= a.NumSwaths()
+}
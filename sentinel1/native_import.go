@@ -0,0 +1,197 @@
+package sentinel1
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/bozso/gotoolbox/path"
+
+	"github.com/bozso/gomma/common"
+	"github.com/bozso/gomma/date"
+)
+
+/*
+Bursts are matched by their azimuth time since the ascending node
+crossing. The times of the same burst differ by a few milliseconds
+between acquisitions, the burst cycle is about 2.75 s.
+*/
+const burstAnxTolerance = 1.0
+
+// selects reports whether the burst with the azimuth time since the
+// ascending node crossing is inside the selected range.
+func (s SwathBursts) selects(anx float64) bool {
+	return anx >= s.FirstBurst-burstAnxTolerance &&
+		anx <= s.LastBurst+burstAnxTolerance
+}
+
+/*
+ImportNative imports the bursts selected by the burst table from the
+zipfile without GAMMA. For every subswath of the table the measurement
+lines of the bursts are converted to big endian SCOMPLEX and the .par and
+.TOPS_par files are synthesized from the annotation. The tabfile
+(<date>.<pol>.SLC_TAB) is written into dst, so the result can be loaded
+with FromTabfile.
+
+Only a single slice is imported, bursts of a datatake split into
+multiple zipfiles need to be imported with S1_import_SLC_from_zipfiles.
+If the table is nil, every burst of every subswath is imported.
+*/
+func (s1 Zip) ImportNative(dst path.Dir, pol common.Pol, table *BurstTable) (tab path.ValidFile, err error) {
+	if pol == common.AllPolarisation {
+		pol = s1.pol
+	}
+
+	var swaths []SwathBursts
+	if table != nil {
+		swaths = table.Swaths
+	} else {
+		for _, iw := range s1.SwathMode.Swaths() {
+			swaths = append(swaths, SwathBursts{
+				IW:         iw,
+				FirstBurst: math.Inf(-1),
+				LastBurst:  math.Inf(1),
+			})
+		}
+	}
+
+	name := date.Short.Format(s1.Date())
+	tabFile := dst.Join(fmt.Sprintf("%s.%s.SLC_TAB", name, pol))
+
+	f, err := os.Create(tabFile.GetPath())
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	for _, sb := range swaths {
+		iw, Err := s1.importSwath(dst, name, pol, sb)
+		if Err != nil {
+			err = fmt.Errorf("failed to import %s%d of zipfile '%s': %w",
+				s1.SwathMode, sb.IW, s1.Path, Err)
+			return
+		}
+
+		if _, err = f.WriteString(iw.Tabline()); err != nil {
+			return
+		}
+	}
+
+	if err = f.Close(); err != nil {
+		return
+	}
+
+	return tabFile.ToValidFile()
+}
+
+func (s1 Zip) importSwath(dst path.Dir, name string, pol common.Pol, sb SwathBursts) (iw IWPath, err error) {
+	as, err := s1.Annotations(pol, sb.IW)
+	if err != nil {
+		return
+	}
+
+	slice := swathSlice{a: as[0], first: -1}
+
+	for ii, b := range slice.a.SwathTiming.Bursts {
+		if !sb.selects(b.AzimuthAnxTime) {
+			continue
+		}
+
+		if slice.first < 0 {
+			slice.first = ii
+		}
+		slice.num++
+	}
+
+	if slice.num == 0 {
+		err = fmt.Errorf("none of the bursts are selected by the burst table")
+		return
+	}
+
+	iw = NewIW(dst.Join(fmt.Sprintf("%s.%s%d.%s.slc", name,
+		s1.SwathMode.SwathPrefix(), sb.IW, pol)).ToFile())
+
+	if err = writeFile(iw.ParFile.GetPath(), func(w *bufio.Writer) error {
+		return slice.WriteISPPar(w, pol.String())
+	}); err != nil {
+		return
+	}
+
+	if err = writeFile(iw.TOPSPar.GetPath(), func(w *bufio.Writer) error {
+		return slice.WriteTOPSPar(w)
+	}); err != nil {
+		return
+	}
+
+	ra, err := s1.Measurement(pol, sb.IW)
+	if err != nil {
+		return
+	}
+	defer ra.Close()
+
+	err = writeFile(iw.DatFile.GetPath(), func(w *bufio.Writer) error {
+		return ra.writeSCOMPLEX(w, slice.firstLine(), slice.lines())
+	})
+
+	return
+}
+
+func writeFile(name string, fn func(*bufio.Writer) error) (err error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+
+	if err = fn(w); err != nil {
+		return
+	}
+
+	if err = w.Flush(); err != nil {
+		return
+	}
+
+	return f.Close()
+}
+
+// Number of lines read from the measurement file at once.
+const importWindow = 256
+
+// writeSCOMPLEX writes the lines of a CInt16 raster in the big endian
+// byte order of GAMMA datafiles.
+func (ra *Raster) writeSCOMPLEX(w *bufio.Writer, first, lines int) (err error) {
+	if ra.Type != CInt16Sample {
+		return fmt.Errorf("expected CInt16 samples, file has %s", ra.Type)
+	}
+
+	size := ra.LineSize()
+	buf := make([]byte, importWindow*size)
+
+	for ii := 0; ii < lines; ii += importWindow {
+		n := importWindow
+		if ii+n > lines {
+			n = lines - ii
+		}
+
+		b := buf[:n*size]
+		if err = ra.ReadLines(first+ii, n, b); err != nil {
+			return
+		}
+
+		// swap the bytes of every int16 of little endian files
+		if ra.order.Uint16([]byte{1, 0}) == 1 {
+			for jj := 0; jj < len(b); jj += 2 {
+				b[jj], b[jj+1] = b[jj+1], b[jj]
+			}
+		}
+
+		if _, err = w.Write(b); err != nil {
+			return
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package sentinel1
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/bozso/gotoolbox/errors"
+)
+
+// NoiseRangeVector holds the thermal noise in the range direction at a
+// line.
+type NoiseRangeVector struct {
+	AzimuthTime AnnotationTime `xml:"azimuthTime"`
+	Line        int            `xml:"line"`
+	Pixel       floatList      `xml:"pixel"`
+
+	// Products processed with IPF 2.9 and newer use noiseRangeLut,
+	// older ones noiseLut.
+	RangeLUT floatList `xml:"noiseRangeLut"`
+	LUT      floatList `xml:"noiseLut"`
+}
+
+func (nv NoiseRangeVector) values() floatList {
+	if len(nv.RangeLUT) > 0 {
+		return nv.RangeLUT
+	}
+	return nv.LUT
+}
+
+/*
+NoiseAzimuthVector holds the azimuth scaling of the thermal noise inside
+a block of the image (introduced with IPF 2.9). The block spans the
+lines and samples between the first and last ones, inclusive.
+*/
+type NoiseAzimuthVector struct {
+	Swath            string    `xml:"swath"`
+	FirstAzimuthLine int       `xml:"firstAzimuthLine"`
+	FirstRangeSample int       `xml:"firstRangeSample"`
+	LastAzimuthLine  int       `xml:"lastAzimuthLine"`
+	LastRangeSample  int       `xml:"lastRangeSample"`
+	Line             floatList `xml:"line"`
+	LUT              floatList `xml:"noiseAzimuthLut"`
+}
+
+func (nv NoiseAzimuthVector) contains(line, pixel float64) bool {
+	return line >= float64(nv.FirstAzimuthLine) &&
+		line <= float64(nv.LastAzimuthLine) &&
+		pixel >= float64(nv.FirstRangeSample) &&
+		pixel <= float64(nv.LastRangeSample)
+}
+
+// NoiseAnnotation holds the contents of a noise annotation file.
+type NoiseAnnotation struct {
+	RangeVectors []NoiseRangeVector `xml:"noiseRangeVectorList>noiseRangeVector"`
+
+	// Vectors of products processed before IPF 2.9.
+	LegacyVectors []NoiseRangeVector `xml:"noiseVectorList>noiseVector"`
+
+	AzimuthVectors []NoiseAzimuthVector `xml:"noiseAzimuthVectorList>noiseAzimuthVector"`
+}
+
+func ParseNoise(r io.Reader) (n NoiseAnnotation, err error) {
+	err = xml.NewDecoder(r).Decode(&n)
+	return
+}
+
+/*
+Model creates the thermal noise model of the product. The noise power at
+a line and pixel is the bilinearly interpolated range noise multiplied
+by the azimuth scaling of the block containing the position (one if
+there are no azimuth vectors).
+*/
+func (n NoiseAnnotation) Model() (nm NoiseModel, err error) {
+	vectors := n.RangeVectors
+	if len(vectors) == 0 {
+		vectors = n.LegacyVectors
+	}
+
+	for _, v := range vectors {
+		if err = nm.rng.add(float64(v.Line), v.Pixel, v.values()); err != nil {
+			err = errors.WrapFmt(err,
+				"invalid noise range vector at line %d", v.Line)
+			return
+		}
+	}
+
+	if err = nm.rng.check(); err != nil {
+		return
+	}
+
+	for _, v := range n.AzimuthVectors {
+		if len(v.Line) != len(v.LUT) || len(v.LUT) == 0 {
+			return nm, fmt.Errorf("invalid noise azimuth vector of %s "+
+				"starting at line %d", v.Swath, v.FirstAzimuthLine)
+		}
+	}
+	nm.azi = n.AzimuthVectors
+
+	return nm, nil
+}
+
+// NoiseModel evaluates the thermal noise power of a product.
+type NoiseModel struct {
+	rng LUT
+	azi []NoiseAzimuthVector
+}
+
+func (nm NoiseModel) azimuthScale(line, pixel float64) float64 {
+	for _, v := range nm.azi {
+		if v.contains(line, pixel) {
+			return lerp(v.Line, v.LUT, line)
+		}
+	}
+	return 1.0
+}
+
+// At returns the noise power at the line and pixel.
+func (nm NoiseModel) At(line, pixel float64) float64 {
+	return nm.rng.At(line, pixel) * nm.azimuthScale(line, pixel)
+}
@@ -0,0 +1,98 @@
+package sentinel1
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func loadTestNoise(t *testing.T) (n NoiseAnnotation) {
+	f, err := os.Open("testdata/s1a-iw1-noise-vv.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if n, err = ParseNoise(f); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestNoiseModel(t *testing.T) {
+	nm, err := loadTestNoise(t).Model()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []lutPoint{
+		// range vector nodes inside the first azimuth block, where the
+		// azimuth scaling starts from one
+		{0, 0, 10}, {0, 80, 30},
+		// range noise 24.9 scaled by 2 at the end of the first block
+		{49, 40, 49.8},
+		// range noise 32.45 scaled by 1.5 in the middle of the block
+		{24.5, 80, 48.675},
+		// second block with a constant scaling of 0.5
+		{75, 80, 18.75}, {100, 0, 10},
+		// outside of the azimuth blocks only the range noise applies
+		{0, 90, 30},
+	} {
+		if got := nm.At(p.line, p.pixel); !closeTo(got, p.expected) {
+			t.Errorf("expected noise %g at line %g pixel %g, got %g",
+				p.expected, p.line, p.pixel, got)
+		}
+	}
+}
+
+func TestNoiseModelLegacy(t *testing.T) {
+	const legacy = `<noise>
+  <noiseVectorList count="1">
+    <noiseVector>
+      <azimuthTime>2016-03-01T16:50:10.533129</azimuthTime>
+      <line>0</line>
+      <pixel count="2">0 100</pixel>
+      <noiseLut count="2">5.000000e+00 1.500000e+01</noiseLut>
+    </noiseVector>
+  </noiseVectorList>
+</noise>`
+
+	n, err := ParseNoise(strings.NewReader(legacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nm, err := n.Model()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []lutPoint{{0, 0, 5}, {0, 50, 10}, {20, 50, 10}} {
+		if got := nm.At(p.line, p.pixel); !closeTo(got, p.expected) {
+			t.Errorf("expected noise %g at line %g pixel %g, got %g",
+				p.expected, p.line, p.pixel, got)
+		}
+	}
+}
+
+func TestCalibratorNoise(t *testing.T) {
+	noise := loadTestNoise(t)
+
+	cal, err := NewCalibrator(loadTestCalibration(t), SigmaNought, &noise)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sigma0 LUT is 500 and the noise power is 20 at the node
+	if nesz := cal.NESZ(0, 40); !closeTo(nesz, 20.0/(500*500)) {
+		t.Errorf("expected NESZ %g, got %g", 20.0/(500*500), nesz)
+	}
+
+	if got := cal.Intensity(0, 40, 500*500*0.1+20); !closeTo(got, 0.1) {
+		t.Errorf("expected noise corrected sigma0 0.1, got %g", got)
+	}
+
+	if got := cal.Intensity(0, 40, 10); got != 0.0 {
+		t.Errorf("expected sigma0 below the noise to be zero, got %g", got)
+	}
+}
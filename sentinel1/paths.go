@@ -14,7 +14,7 @@ type IWPath struct {
 	TOPSPar path.File
 }
 
-type IWPaths [maxIW]IWPath
+type IWPaths [maxSwath]IWPath
 
 func NewIW(datafile path.File) (p IWPath) {
 	p.DatFile = datafile
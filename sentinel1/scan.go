@@ -0,0 +1,133 @@
+package sentinel1
+
+import (
+	"log"
+	"runtime"
+	"sync"
+
+	"github.com/bozso/gotoolbox/path"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+
+	"github.com/bozso/gomma/common"
+)
+
+type Scanned struct {
+	Zip *Zip
+	IWs IWInfos
+
+	// Footprint of GRD products, which have no IW information.
+	Footprint orb.Ring
+
+	Err error
+}
+
+// Contains reports whether every corner of the area of interest is
+// covered by the subswaths, or by the footprint of a GRD product.
+func (sc Scanned) Contains(aoi common.AOI) bool {
+	if sc.Zip == nil || !sc.Zip.IsGRD() {
+		return sc.IWs.Contains(aoi)
+	}
+
+	for _, p := range aoi {
+		if !planar.RingContains(sc.Footprint, orb.Point{p.Lon, p.Lat}) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+Scanner parses the IW information of Sentinel-1 zipfiles with a
+bounded pool of workers. Results are looked up in, and stored into,
+an optional InfoCache so repeated scans of the same zipfiles do not
+need to extract and parse the annotation files again.
+*/
+type Scanner struct {
+	workers int
+	dst     path.Dir
+	cache   *InfoCache
+}
+
+func NewScanner(dst path.Dir) (s Scanner) {
+	return Scanner{
+		workers: runtime.NumCPU(),
+		dst:     dst,
+		cache:   nil,
+	}
+}
+
+func (s Scanner) WithWorkers(n int) (sc Scanner) {
+	if n > 0 {
+		s.workers = n
+	}
+	return s
+}
+
+func (s Scanner) WithCache(ic *InfoCache) (sc Scanner) {
+	s.cache = ic
+	return s
+}
+
+// Scan returns the results in the order of the input files.
+func (s Scanner) Scan(files []path.ValidFile) (sc []Scanned) {
+	sc = make([]Scanned, len(files))
+
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+
+	for ii := 0; ii < s.workers; ii++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for idx := range jobs {
+				sc[idx] = s.scan(files[idx])
+			}
+		}()
+	}
+
+	for ii := range files {
+		jobs <- ii
+	}
+	close(jobs)
+
+	wg.Wait()
+	return
+}
+
+func (s Scanner) scan(file path.ValidFile) (sc Scanned) {
+	if sc.Zip, sc.Err = NewZip(file); sc.Err != nil {
+		return
+	}
+
+	// GRD products have no bursts, they are selected by their footprint
+	if sc.Zip.IsGRD() {
+		sc.Footprint, sc.Err = sc.Zip.Footprint()
+		return
+	}
+
+	if s.cache != nil {
+		if iws, ok := s.cache.Get(file); ok {
+			sc.IWs = iws
+			return
+		}
+	}
+
+	log.Printf("Parsing IW Information for S1 zipfile '%s'", file)
+
+	if sc.IWs, sc.Err = sc.Zip.Info(s.dst); sc.Err != nil {
+		// drop the entry of the previous version of the zipfile
+		if s.cache != nil {
+			s.cache.Invalidate(file)
+		}
+		return
+	}
+
+	if s.cache != nil {
+		sc.Err = s.cache.Put(file, sc.IWs)
+	}
+
+	return
+}
@@ -0,0 +1,318 @@
+package sentinel1
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/bozso/gotoolbox/path"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+// SceneFeature holds the information of a zipfile that is exported
+// into scene catalogs.
+type SceneFeature struct {
+	Zip       *Zip
+	Footprint orb.Ring
+
+	// ESA burst IDs of the bursts of the product, empty for GRD
+	// products.
+	Bursts []string
+
+	// Path of the extracted quicklook image, empty if it was not
+	// extracted.
+	Quicklook string
+}
+
+/*
+NewSceneFeature collects the footprint and burst IDs of the zipfile. If
+quicklooks is not nil, the quicklook image is extracted into it, so it
+can be referenced from the KML.
+*/
+func NewSceneFeature(s1 *Zip, quicklooks *path.Dir) (sf SceneFeature, err error) {
+	sf.Zip = s1
+
+	if sf.Footprint, err = s1.Footprint(); err != nil {
+		return
+	}
+
+	if !s1.IsGRD() {
+		relOrbit, err := s1.RelativeOrbit()
+		if err != nil {
+			return sf, err
+		}
+
+		bursts, err := s1.Bursts(s1.pol)
+		if err != nil {
+			return sf, err
+		}
+
+		for _, b := range bursts {
+			sf.Bursts = append(sf.Bursts, b.ID(relOrbit, s1.SwathMode))
+		}
+	}
+
+	if quicklooks != nil {
+		ql, err := s1.Quicklook(*quicklooks)
+		if err != nil {
+			return sf, err
+		}
+		sf.Quicklook = ql.GetPath()
+	}
+
+	return sf, nil
+}
+
+func (sf SceneFeature) Name() string {
+	return strings.TrimSuffix(sf.Zip.Path.Base().String(), ".zip")
+}
+
+// Properties returns the attributes of the scene.
+func (sf SceneFeature) Properties() (p map[string]interface{}) {
+	s1 := sf.Zip
+
+	pols := make([]string, len(s1.pols))
+	for ii, pol := range s1.pols {
+		pols[ii] = pol.String()
+	}
+
+	p = map[string]interface{}{
+		"name":          sf.Name(),
+		"mission":       s1.Mission(),
+		"mode":          s1.SwathMode.String(),
+		"product_type":  s1.productType,
+		"start":         s1.Start().Format(time.RFC3339),
+		"stop":          s1.Stop().Format(time.RFC3339),
+		"polarizations": pols,
+	}
+
+	if n, err := s1.AbsoluteOrbit(); err == nil {
+		p["absolute_orbit"] = n
+	}
+
+	if n, err := s1.RelativeOrbit(); err == nil {
+		p["relative_orbit"] = n
+	}
+
+	if len(sf.Bursts) > 0 {
+		p["burst_ids"] = sf.Bursts
+	}
+
+	if sf.Quicklook != "" {
+		p["quicklook"] = sf.Quicklook
+	}
+
+	return
+}
+
+type SceneFeatures []SceneFeature
+
+func (sfs SceneFeatures) FeatureCollection() (fc *geojson.FeatureCollection) {
+	fc = geojson.NewFeatureCollection()
+
+	for _, sf := range sfs {
+		f := geojson.NewFeature(orb.Polygon{sf.Footprint})
+		f.Properties = sf.Properties()
+
+		fc.Append(f)
+	}
+
+	return
+}
+
+func (sfs SceneFeatures) WriteGeoJSON(w io.Writer) (err error) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(sfs.FeatureCollection())
+}
+
+type (
+	kmlData struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value"`
+	}
+
+	kmlPlacemark struct {
+		Name        string    `xml:"name"`
+		TimeSpan    *kmlSpan  `xml:"TimeSpan,omitempty"`
+		Data        []kmlData `xml:"ExtendedData>Data"`
+		Coordinates string    `xml:"Polygon>outerBoundaryIs>LinearRing>coordinates"`
+	}
+
+	kmlSpan struct {
+		Begin string `xml:"begin"`
+		End   string `xml:"end"`
+	}
+
+	kmlLatLonBox struct {
+		North float64 `xml:"north"`
+		South float64 `xml:"south"`
+		East  float64 `xml:"east"`
+		West  float64 `xml:"west"`
+	}
+
+	kmlOverlay struct {
+		Name       string        `xml:"name"`
+		Href       string        `xml:"Icon>href"`
+		LatLonQuad string        `xml:"gx:LatLonQuad>coordinates,omitempty"`
+		LatLonBox  *kmlLatLonBox `xml:"LatLonBox,omitempty"`
+	}
+
+	kmlFolder struct {
+		Name       string         `xml:"name"`
+		Placemarks []kmlPlacemark `xml:"Placemark,omitempty"`
+		Overlays   []kmlOverlay   `xml:"GroundOverlay,omitempty"`
+	}
+
+	kmlDocument struct {
+		XMLName xml.Name    `xml:"kml"`
+		Xmlns   string      `xml:"xmlns,attr"`
+		XmlnsGx string      `xml:"xmlns:gx,attr"`
+		Name    string      `xml:"Document>name"`
+		Folders []kmlFolder `xml:"Document>Folder"`
+	}
+)
+
+func kmlCoordinates(r orb.Ring) string {
+	s := make([]string, len(r))
+	for ii, p := range r {
+		s[ii] = fmt.Sprintf("%.6f,%.6f,0", p[0], p[1])
+	}
+	return strings.Join(s, " ")
+}
+
+func kmlValue(v interface{}) string {
+	switch v := v.(type) {
+	case []string:
+		return strings.Join(v, " ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+/*
+quadCorners orders the corners of a quadrilateral as gx:LatLonQuad
+expects them: counter-clockwise, starting from the lower-left corner,
+which is the western one of the two southernmost corners.
+*/
+func quadCorners(r orb.Ring) (q orb.Ring) {
+	q = append(orb.Ring{}, r[:4]...)
+
+	if q.Orientation() == orb.CW {
+		q.Reverse()
+	}
+
+	// the southernmost corner and its southernmost neighbour form the
+	// lower edge
+	low := 0
+	for ii, p := range q {
+		if p[1] < q[low][1] {
+			low = ii
+		}
+	}
+
+	prev, next := q[(low+3)%4], q[(low+1)%4]
+
+	first := low
+	if prev[1] < next[1] {
+		if prev[0] < q[low][0] {
+			first = (low + 3) % 4
+		}
+	} else if next[0] < q[low][0] {
+		first = (low + 1) % 4
+	}
+
+	return append(q[first:], q[:first]...)
+}
+
+/*
+overlay creates a ground overlay of the quicklook. If the footprint is a
+quadrilateral, the image is mapped to its corners, otherwise it is
+stretched to the bounding box of the footprint.
+*/
+func (sf SceneFeature) overlay() (o kmlOverlay) {
+	o.Name, o.Href = sf.Name(), sf.Quicklook
+
+	if fp := sf.Footprint; len(fp) == 5 && fp.Closed() {
+		o.LatLonQuad = kmlCoordinates(quadCorners(fp))
+		return
+	}
+
+	b := sf.Footprint.Bound()
+	o.LatLonBox = &kmlLatLonBox{
+		North: b.Max[1],
+		South: b.Min[1],
+		East:  b.Max[0],
+		West:  b.Min[0],
+	}
+	return
+}
+
+/*
+WriteKML writes the footprints as placemarks carrying the properties of
+the scenes. Quicklooks, if extracted, are attached as ground overlays.
+*/
+func (sfs SceneFeatures) WriteKML(w io.Writer) (err error) {
+	footprints := kmlFolder{Name: "Footprints"}
+	quicklooks := kmlFolder{Name: "Quicklooks"}
+
+	for _, sf := range sfs {
+		pm := kmlPlacemark{
+			Name: sf.Name(),
+			TimeSpan: &kmlSpan{
+				Begin: sf.Zip.Start().Format(time.RFC3339),
+				End:   sf.Zip.Stop().Format(time.RFC3339),
+			},
+			Coordinates: kmlCoordinates(sf.Footprint),
+		}
+
+		props := sf.Properties()
+		for _, key := range []string{"mission", "mode", "product_type",
+			"absolute_orbit", "relative_orbit", "polarizations",
+			"burst_ids"} {
+			if v, ok := props[key]; ok {
+				pm.Data = append(pm.Data, kmlData{
+					Name:  key,
+					Value: kmlValue(v),
+				})
+			}
+		}
+
+		footprints.Placemarks = append(footprints.Placemarks, pm)
+
+		if sf.Quicklook != "" {
+			quicklooks.Overlays = append(quicklooks.Overlays, sf.overlay())
+		}
+	}
+
+	doc := kmlDocument{
+		Xmlns:   "http://www.opengis.net/kml/2.2",
+		XmlnsGx: "http://www.google.com/kml/ext/2.2",
+		Name:    "Sentinel-1 scenes",
+		Folders: []kmlFolder{footprints},
+	}
+
+	if len(quicklooks.Overlays) > 0 {
+		doc.Folders = append(doc.Folders, quicklooks)
+	}
+
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		return
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	if err = enc.Encode(doc); err != nil {
+		return
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return
+}
@@ -0,0 +1,28 @@
+package sentinel1
+
+import (
+	"testing"
+
+	"github.com/paulmach/orb"
+)
+
+func TestQuadCorners(t *testing.T) {
+	// corners of a descending footprint, counter-clockwise from the
+	// lower-left one
+	ll, lr := orb.Point{20.5, 44.4}, orb.Point{24.0, 44.8}
+	ur, ul := orb.Point{23.6, 46.5}, orb.Point{20.1, 46.1}
+
+	expected := orb.Ring{ll, lr, ur, ul}
+
+	for _, r := range []orb.Ring{
+		{ll, lr, ur, ul, ll},
+		{ur, ul, ll, lr, ur},
+		// clockwise
+		{ul, ur, lr, ll, ul},
+		{lr, ll, ul, ur, lr},
+	} {
+		if q := quadCorners(r); !q.Equal(expected) {
+			t.Errorf("expected corners %v from %v, got %v", expected, r, q)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package sentinel1
 
 import (
 	"bufio"
+	"fmt"
 	"log"
 	"strings"
 	"time"
@@ -51,7 +52,12 @@ func FromTabfile(tab path.ValidFile) (s1 SLC, err error) {
 			return s1, err
 		}
 
-		log.Printf("Parsing IW%d\n", s1.nIW+1)
+		if s1.nIW >= maxSwath {
+			return s1, fmt.Errorf("tabfile '%s' has more than %d lines",
+				tab, maxSwath)
+		}
+
+		log.Printf("Parsing subswath %d\n", s1.nIW+1)
 
 		var f path.File
 
@@ -123,8 +129,8 @@ func (s1 SLC) Move(dir path.Dir) (ms1 SLC, err error) {
 }
 
 func (s1 SLC) Exist() (b bool, err error) {
-	for _, iw := range s1.IWs {
-		if b, err = iw.Exist(); err != nil {
+	for ii := 0; ii < s1.nIW; ii++ {
+		if b, err = s1.IWs[ii].Exist(); err != nil {
 			err = errors.WrapFmt(err,
 				"failed to determine whether IW datafile exists")
 			return
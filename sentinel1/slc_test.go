@@ -0,0 +1,94 @@
+package sentinel1
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bozso/gotoolbox/path"
+)
+
+/*
+writeTestTab writes a tabfile with nIW swaths into dir. Every swath uses
+the parameter files of testdata and an empty datafile.
+*/
+func writeTestTab(t *testing.T, dir string, nIW int) (tab path.ValidFile, dats []string) {
+	par, err := ioutil.ReadFile("testdata/s1a-iw1-slc-vv.par")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tops, err := ioutil.ReadFile("testdata/s1a-iw1-slc-vv.TOPS_par")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines strings.Builder
+
+	for ii := 1; ii <= nIW; ii++ {
+		dat := filepath.Join(dir, fmt.Sprintf("iw%d.slc", ii))
+
+		for name, content := range map[string][]byte{
+			dat:               nil,
+			dat + ".par":      par,
+			dat + ".TOPS_par": tops,
+		} {
+			if err = ioutil.WriteFile(name, content, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		dats = append(dats, dat)
+		fmt.Fprintf(&lines, "%s %s.par %s.TOPS_par\n", dat, dat, dat)
+	}
+
+	name := filepath.Join(dir, "SLC_tab")
+	if err = ioutil.WriteFile(name, []byte(lines.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if tab, err = path.New(name).ToValidFile(); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestSLCExist(t *testing.T) {
+	for _, nIW := range []int{3, 1} {
+		t.Run(fmt.Sprintf("%d swaths", nIW), func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "slc")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			tab, dats := writeTestTab(t, dir, nIW)
+
+			s1, err := FromTabfile(tab)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if b, err := s1.Exist(); err != nil {
+				t.Fatal(err)
+			} else if !b {
+				t.Errorf("expected the datafiles of all %d swaths to exist",
+					nIW)
+			}
+
+			if err = os.Remove(dats[nIW-1]); err != nil {
+				t.Fatal(err)
+			}
+
+			if b, err := s1.Exist(); err != nil {
+				t.Fatal(err)
+			} else if b {
+				t.Errorf("expected a missing datafile of IW%d to be reported",
+					nIW)
+			}
+		})
+	}
+}
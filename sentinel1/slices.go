@@ -0,0 +1,198 @@
+package sentinel1
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// number of orbits in one repeat cycle
+	orbitsPerCycle = 175
+
+	// Maximum allowed time difference between the stop time of a slice
+	// and the start time of the next one in the same datatake.
+	DefaultSliceTolerance = 2 * time.Second
+)
+
+func (s1 Zip) Mission() string {
+	return s1.mission
+}
+
+func (s1 Zip) Start() time.Time {
+	return s1.date.Start()
+}
+
+func (s1 Zip) Stop() time.Time {
+	return s1.date.Stop()
+}
+
+// Datatake returns the mission datatake identifier in lowercase
+// hexadecimal format.
+func (s1 Zip) Datatake() string {
+	return s1.DTID
+}
+
+func (s1 Zip) AbsoluteOrbit() (n int, err error) {
+	n, err = strconv.Atoi(s1.absoluteOrbit)
+	if err != nil {
+		err = fmt.Errorf("failed to parse absolute orbit number of "+
+			"zipfile '%s': %w", s1.Path, err)
+	}
+	return
+}
+
+/*
+RelativeOrbit computes the relative orbit (track) number from the
+absolute orbit number. The offsets of the two satellites are different,
+since their orbit counters start at different positions of the repeat
+cycle.
+*/
+func (s1 Zip) RelativeOrbit() (n int, err error) {
+	abs, err := s1.AbsoluteOrbit()
+	if err != nil {
+		return
+	}
+
+	switch s1.mission {
+	case "s1a":
+		n = (abs-73)%orbitsPerCycle + 1
+	case "s1b":
+		n = (abs-27)%orbitsPerCycle + 1
+	default:
+		return 0, fmt.Errorf("unrecognized mission '%s' of zipfile '%s'",
+			s1.mission, s1.Path)
+	}
+
+	if n <= 0 {
+		n += orbitsPerCycle
+	}
+
+	return n, nil
+}
+
+// Gap marks missing data between two consecutive slices of a datatake.
+type Gap struct {
+	After  string    `json:"after"`
+	Before string    `json:"before"`
+	Start  time.Time `json:"start"`
+	Stop   time.Time `json:"stop"`
+}
+
+func (g Gap) Duration() time.Duration {
+	return g.Stop.Sub(g.Start)
+}
+
+func (g Gap) String() string {
+	return fmt.Sprintf("gap of %s between '%s' and '%s'", g.Duration(),
+		g.After, g.Before)
+}
+
+/*
+Pass holds the slices acquired during one datatake, ordered by their
+start time. Slices of a pass can be imported together into one SLC.
+*/
+type Pass struct {
+	Mission       string `json:"mission"`
+	RelativeOrbit int    `json:"relative_orbit"`
+	Datatake      string `json:"datatake"`
+	Slices        Zips   `json:"-"`
+	Gaps          []Gap  `json:"gaps"`
+}
+
+func (p Pass) Contiguous() bool {
+	return len(p.Gaps) == 0
+}
+
+func (p Pass) Start() time.Time {
+	return p.Slices[0].Start()
+}
+
+func (p Pass) Stop() time.Time {
+	return p.Slices[len(p.Slices)-1].Stop()
+}
+
+// Date returns the center of the acquisition time of the pass.
+func (p Pass) Date() time.Time {
+	start := p.Start()
+	return start.Add(p.Stop().Sub(start) / 2)
+}
+
+func (p Pass) String() string {
+	return fmt.Sprintf("%s track %d datatake %s (%d slices)",
+		strings.ToUpper(p.Mission), p.RelativeOrbit, p.Datatake,
+		len(p.Slices))
+}
+
+type passKey struct {
+	mission  string
+	relOrbit int
+	datatake string
+}
+
+/*
+AssembleSlices groups the zipfiles by mission, relative orbit and
+datatake and orders the slices of every group by time. Consecutive
+slices whose stop and start times differ more than tolerance are
+reported as gaps. The passes are sorted by their start time.
+*/
+func AssembleSlices(zips Zips, tolerance time.Duration) (passes []Pass, err error) {
+	groups := map[passKey]*Pass{}
+	keys := []passKey{}
+
+	for _, zip := range zips {
+		relOrbit, err := zip.RelativeOrbit()
+		if err != nil {
+			return nil, err
+		}
+
+		key := passKey{zip.mission, relOrbit, zip.DTID}
+
+		pass, ok := groups[key]
+		if !ok {
+			pass = &Pass{
+				Mission:       key.mission,
+				RelativeOrbit: key.relOrbit,
+				Datatake:      key.datatake,
+			}
+			groups[key] = pass
+			keys = append(keys, key)
+		}
+
+		pass.Slices = append(pass.Slices, zip)
+	}
+
+	passes = make([]Pass, 0, len(keys))
+
+	for _, key := range keys {
+		pass := groups[key]
+		slices := pass.Slices
+
+		sort.Slice(slices, func(i, j int) bool {
+			return slices[i].Start().Before(slices[j].Start())
+		})
+
+		for ii := 1; ii < len(slices); ii++ {
+			prev, next := slices[ii-1], slices[ii]
+
+			if next.Start().Sub(prev.Stop()) > tolerance {
+				pass.Gaps = append(pass.Gaps, Gap{
+					After:  prev.Path.String(),
+					Before: next.Path.String(),
+					Start:  prev.Stop(),
+					Stop:   next.Start(),
+				})
+			}
+		}
+
+		passes = append(passes, *pass)
+	}
+
+	sort.Slice(passes, func(i, j int) bool {
+		return passes[i].Start().Before(passes[j].Start())
+	})
+
+	return passes, nil
+}
@@ -0,0 +1,160 @@
+package sentinel1
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bozso/gotoolbox/path"
+)
+
+// newTestZip creates an empty zipfile with the product name in dir.
+func newTestZip(t *testing.T, dir, name string) (s1 *Zip) {
+	p := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(p, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	vf, err := path.New(p).ToValidFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s1, err = NewZip(vf); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestRelativeOrbit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slices")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, c := range []struct {
+		mission  string
+		absolute int
+		relative int
+	}{
+		{"S1A", 73, 1},
+		{"S1A", 247, 175},
+		{"S1A", 248, 1},
+		{"S1B", 27, 1},
+		{"S1B", 26, 175},
+	} {
+		name := fmt.Sprintf("%s_IW_SLC__1SDV_20200301T165010_20200301T165037_"+
+			"%06d_039F5C_6A2B.zip", c.mission, c.absolute)
+
+		n, err := newTestZip(t, dir, name).RelativeOrbit()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n != c.relative {
+			t.Errorf("expected relative orbit %d of %s absolute orbit %d, "+
+				"got %d", c.relative, c.mission, c.absolute, n)
+		}
+	}
+}
+
+func TestAssembleSlices(t *testing.T) {
+	// slice of datatake 039F5C starting at the given time of 2020-03-01
+	slice := func(start, stop string) string {
+		return fmt.Sprintf("S1A_IW_SLC__1SDV_20200301T%s_20200301T%s_031476_"+
+			"039F5C_6A2B.zip", start, stop)
+	}
+
+	other := "S1A_IW_SLC__1SDV_20200313T165010_20200313T165037_031651_03A56E_1F2C.zip"
+
+	type pass struct {
+		datatake string
+		slices   int
+		gaps     []time.Duration
+	}
+
+	for _, c := range []struct {
+		name   string
+		zips   []string
+		passes []pass
+	}{
+		{
+			name: "gap in a datatake",
+			zips: []string{
+				slice("165010", "165037"),
+				slice("165035", "165102"),
+				slice("165125", "165152"),
+			},
+			passes: []pass{{"039f5c", 3, []time.Duration{23 * time.Second}}},
+		},
+		{
+			name:   "mixed datatakes",
+			zips:   []string{other, slice("165010", "165037")},
+			passes: []pass{{"039f5c", 1, nil}, {"03a56e", 1, nil}},
+		},
+		{
+			name: "out of order",
+			zips: []string{
+				slice("165100", "165127"),
+				slice("165010", "165037"),
+				slice("165035", "165102"),
+			},
+			passes: []pass{{"039f5c", 3, nil}},
+		},
+	} {
+		dir, err := ioutil.TempDir("", "slices")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		var zips Zips
+		for _, name := range c.zips {
+			zips = append(zips, newTestZip(t, dir, name))
+		}
+
+		passes, err := AssembleSlices(zips, DefaultSliceTolerance)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(passes) != len(c.passes) {
+			t.Fatalf("%s: expected %d passes, got %d", c.name,
+				len(c.passes), len(passes))
+		}
+
+		for ii, expected := range c.passes {
+			p := passes[ii]
+
+			if p.Datatake != expected.datatake ||
+				len(p.Slices) != expected.slices {
+				t.Errorf("%s: expected datatake %s with %d slices, got %s",
+					c.name, expected.datatake, expected.slices, p)
+			}
+
+			for jj := 1; jj < len(p.Slices); jj++ {
+				if p.Slices[jj].Start().Before(p.Slices[jj-1].Start()) {
+					t.Errorf("%s: slices of %s are not ordered by time",
+						c.name, p)
+				}
+			}
+
+			if len(p.Gaps) != len(expected.gaps) {
+				t.Errorf("%s: expected %d gaps in %s, got %v", c.name,
+					len(expected.gaps), p, p.Gaps)
+				continue
+			}
+
+			for jj, g := range p.Gaps {
+				if g.Duration() != expected.gaps[jj] {
+					t.Errorf("%s: expected gap of %s, got %s", c.name,
+						expected.gaps[jj], g)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,628 @@
+package sentinel1
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/bozso/gomma/common"
+)
+
+// SampleType is the data type of the samples of a measurement TIFF.
+type SampleType int
+
+const (
+	// Complex samples with int16 real and imaginary parts (SLC).
+	CInt16Sample SampleType = iota
+	// Unsigned 16 bit detected amplitudes (GRD).
+	UInt16Sample
+)
+
+func (s SampleType) String() (str string) {
+	switch s {
+	case CInt16Sample:
+		str = "CInt16"
+	case UInt16Sample:
+		str = "UInt16"
+	default:
+		str = "unknown"
+	}
+	return
+}
+
+// Size returns the number of bytes of one sample.
+func (s SampleType) Size() (n int) {
+	switch s {
+	case CInt16Sample:
+		n = 4
+	case UInt16Sample:
+		n = 2
+	}
+	return
+}
+
+// CInt16 is a complex sample of a SLC measurement file.
+type CInt16 struct {
+	Re, Im int16
+}
+
+func (c CInt16) Complex64() complex64 {
+	return complex(float32(c.Re), float32(c.Im))
+}
+
+// Power returns |DN|^2 of the sample.
+func (c CInt16) Power() float32 {
+	re, im := float32(c.Re), float32(c.Im)
+	return re*re + im*im
+}
+
+// TIFF tags used by the Sentinel-1 measurement files.
+const (
+	tagImageWidth      = 256
+	tagImageLength     = 257
+	tagBitsPerSample   = 258
+	tagCompression     = 259
+	tagStripOffsets    = 273
+	tagSamplesPerPixel = 277
+	tagRowsPerStrip    = 278
+	tagStripByteCounts = 279
+	tagTileWidth       = 322
+	tagSampleFormat    = 339
+)
+
+// TIFF field types.
+const (
+	tiffShort = 3
+	tiffLong  = 4
+)
+
+// Maximum number of values of a TIFF tag.
+const maxTIFFValues = 1 << 24
+
+// TIFF sample formats.
+const (
+	formatUInt       = 1
+	formatComplexInt = 5
+)
+
+/*
+Raster reads the lines of an uncompressed, stripped TIFF file as found in
+the measurement directory of Sentinel-1 products. Only the header and the
+requested lines are read, so large files can be processed in line
+windows without holding them in memory.
+
+Raster is not safe for concurrent use if it reads a compressed zip
+entry.
+*/
+type Raster struct {
+	Width, Height int
+	Type          SampleType
+
+	r            io.ReaderAt
+	order        binary.ByteOrder
+	rowsPerStrip int
+	stripOffsets []int64
+	closer       io.Closer
+}
+
+// NewRaster parses the header of the TIFF file read from r.
+func NewRaster(r io.ReaderAt) (ra *Raster, err error) {
+	ra = &Raster{r: r}
+	if err = ra.parseHeader(); err != nil {
+		return nil, err
+	}
+	return ra, nil
+}
+
+func (ra *Raster) Close() (err error) {
+	if ra.closer != nil {
+		err = ra.closer.Close()
+	}
+	return
+}
+
+type ifdEntry struct {
+	tag, typ uint16
+	count    uint32
+	value    []byte
+}
+
+func (ra *Raster) parseHeader() (err error) {
+	head := make([]byte, 8)
+	if _, err = ra.r.ReadAt(head, 0); err != nil {
+		return fmt.Errorf("failed to read TIFF header: %w", err)
+	}
+
+	switch string(head[:2]) {
+	case "II":
+		ra.order = binary.LittleEndian
+	case "MM":
+		ra.order = binary.BigEndian
+	default:
+		return fmt.Errorf("not a TIFF file")
+	}
+
+	if magic := ra.order.Uint16(head[2:]); magic != 42 {
+		return fmt.Errorf("unsupported TIFF version %d (BigTIFF?)", magic)
+	}
+
+	entries, err := ra.readIFD(int64(ra.order.Uint32(head[4:])))
+	if err != nil {
+		return
+	}
+
+	if _, ok := entries[tagTileWidth]; ok {
+		return fmt.Errorf("tiled TIFF files are not supported")
+	}
+
+	ints := func(tag uint16, dflt ...int64) (v []int64, err error) {
+		e, ok := entries[tag]
+		if !ok {
+			if len(dflt) > 0 {
+				return dflt, nil
+			}
+			return nil, fmt.Errorf("required TIFF tag %d is missing", tag)
+		}
+		if v, err = ra.values(e); err == nil && len(v) == 0 {
+			err = fmt.Errorf("TIFF tag %d has no values", tag)
+		}
+		return
+	}
+
+	var v []int64
+
+	if v, err = ints(tagImageWidth); err != nil {
+		return
+	}
+	ra.Width = int(v[0])
+
+	if v, err = ints(tagImageLength); err != nil {
+		return
+	}
+	ra.Height = int(v[0])
+
+	if v, err = ints(tagCompression, 1); err != nil {
+		return
+	}
+	if v[0] != 1 {
+		return fmt.Errorf("compressed TIFF files are not supported")
+	}
+
+	if v, err = ints(tagSamplesPerPixel, 1); err != nil {
+		return
+	}
+	if v[0] != 1 {
+		return fmt.Errorf("expected 1 sample per pixel, got %d", v[0])
+	}
+
+	bits, err := ints(tagBitsPerSample)
+	if err != nil {
+		return
+	}
+
+	format, err := ints(tagSampleFormat, formatUInt)
+	if err != nil {
+		return
+	}
+
+	switch {
+	case format[0] == formatComplexInt && bits[0] == 32:
+		ra.Type = CInt16Sample
+	case format[0] == formatUInt && bits[0] == 16:
+		ra.Type = UInt16Sample
+	default:
+		return fmt.Errorf("unsupported sample format %d with %d bits",
+			format[0], bits[0])
+	}
+
+	if v, err = ints(tagRowsPerStrip, int64(ra.Height)); err != nil {
+		return
+	}
+	if ra.rowsPerStrip = int(v[0]); ra.rowsPerStrip < 1 {
+		return fmt.Errorf("invalid number of rows per strip %d", v[0])
+	}
+
+	if ra.stripOffsets, err = ints(tagStripOffsets); err != nil {
+		return
+	}
+
+	nstrip := (ra.Height + ra.rowsPerStrip - 1) / ra.rowsPerStrip
+	if len(ra.stripOffsets) < nstrip {
+		return fmt.Errorf("expected %d strips, got %d", nstrip,
+			len(ra.stripOffsets))
+	}
+
+	return nil
+}
+
+func (ra *Raster) readIFD(offset int64) (entries map[uint16]ifdEntry, err error) {
+	buf := make([]byte, 2)
+	if _, err = ra.r.ReadAt(buf, offset); err != nil {
+		return
+	}
+
+	n := int(ra.order.Uint16(buf))
+	buf = make([]byte, 12*n)
+
+	if _, err = ra.r.ReadAt(buf, offset+2); err != nil {
+		return
+	}
+
+	entries = make(map[uint16]ifdEntry, n)
+
+	for ii := 0; ii < n; ii++ {
+		b := buf[12*ii : 12*(ii+1)]
+		entries[ra.order.Uint16(b)] = ifdEntry{
+			tag:   ra.order.Uint16(b),
+			typ:   ra.order.Uint16(b[2:]),
+			count: ra.order.Uint32(b[4:]),
+			value: b[8:12],
+		}
+	}
+
+	return
+}
+
+// values returns the integer values of an entry, reading them from the
+// file if they do not fit into the entry.
+func (ra *Raster) values(e ifdEntry) (v []int64, err error) {
+	size := 0
+	switch e.typ {
+	case tiffShort:
+		size = 2
+	case tiffLong:
+		size = 4
+	default:
+		return nil, fmt.Errorf("unsupported type %d of TIFF tag %d", e.typ,
+			e.tag)
+	}
+
+	// guards against allocating huge buffers for corrupt headers
+	if e.count > maxTIFFValues {
+		return nil, fmt.Errorf("TIFF tag %d has too many values (%d)",
+			e.tag, e.count)
+	}
+
+	b := e.value
+	if n := int(e.count) * size; n > 4 {
+		b = make([]byte, n)
+		if _, err = ra.r.ReadAt(b, int64(ra.order.Uint32(e.value))); err != nil {
+			return
+		}
+	}
+
+	v = make([]int64, e.count)
+	for ii := range v {
+		if size == 2 {
+			v[ii] = int64(ra.order.Uint16(b[2*ii:]))
+		} else {
+			v[ii] = int64(ra.order.Uint32(b[4*ii:]))
+		}
+	}
+
+	return
+}
+
+// LineSize returns the number of bytes of a line.
+func (ra *Raster) LineSize() int {
+	return ra.Width * ra.Type.Size()
+}
+
+func (ra *Raster) lineOffset(line int) int64 {
+	strip := line / ra.rowsPerStrip
+	return ra.stripOffsets[strip] +
+		int64((line%ra.rowsPerStrip)*ra.LineSize())
+}
+
+// ReadLines reads n lines starting at first into buf, which must hold
+// at least n*LineSize() bytes, in the byte order of the file.
+func (ra *Raster) ReadLines(first, n int, buf []byte) (err error) {
+	if first < 0 || n < 0 || first+n > ra.Height {
+		return fmt.Errorf("lines [%d, %d) are out of range [0, %d)", first,
+			first+n, ra.Height)
+	}
+
+	size := ra.LineSize()
+	if len(buf) < n*size {
+		return fmt.Errorf("buffer is too small for %d lines", n)
+	}
+
+	for ii := 0; ii < n; {
+		line := first + ii
+
+		// lines inside a strip are contiguous
+		run := ra.rowsPerStrip - line%ra.rowsPerStrip
+		if run > n-ii {
+			run = n - ii
+		}
+
+		_, err = ra.r.ReadAt(buf[ii*size:(ii+run)*size], ra.lineOffset(line))
+		if err != nil {
+			return fmt.Errorf("failed to read line %d: %w", line, err)
+		}
+
+		ii += run
+	}
+
+	return nil
+}
+
+// CInt16Lines reads n lines of a complex file starting at first into
+// dst, which must hold at least n*Width samples.
+func (ra *Raster) CInt16Lines(first, n int, dst []CInt16) (err error) {
+	if ra.Type != CInt16Sample {
+		return fmt.Errorf("expected CInt16 samples, file has %s", ra.Type)
+	}
+
+	buf := make([]byte, n*ra.LineSize())
+	if err = ra.ReadLines(first, n, buf); err != nil {
+		return
+	}
+
+	for ii := 0; ii < n*ra.Width; ii++ {
+		dst[ii] = CInt16{
+			Re: int16(ra.order.Uint16(buf[4*ii:])),
+			Im: int16(ra.order.Uint16(buf[4*ii+2:])),
+		}
+	}
+
+	return nil
+}
+
+// PowerLines reads n lines starting at first and stores |DN|^2 of the
+// samples in dst, which must hold at least n*Width values.
+func (ra *Raster) PowerLines(first, n int, dst []float32) (err error) {
+	buf := make([]byte, n*ra.LineSize())
+	if err = ra.ReadLines(first, n, buf); err != nil {
+		return
+	}
+
+	for ii := 0; ii < n*ra.Width; ii++ {
+		switch ra.Type {
+		case CInt16Sample:
+			re := float32(int16(ra.order.Uint16(buf[4*ii:])))
+			im := float32(int16(ra.order.Uint16(buf[4*ii+2:])))
+			dst[ii] = re*re + im*im
+		case UInt16Sample:
+			a := float32(ra.order.Uint16(buf[2*ii:]))
+			dst[ii] = a * a
+		}
+	}
+
+	return nil
+}
+
+// Window is a block of consecutive lines of a raster.
+type Window struct {
+	First, Lines, Width int
+	Power               []float32
+}
+
+// At returns the power of the sample in the line (relative to the first
+// line of the window) and column.
+func (w Window) At(line, col int) float32 {
+	return w.Power[line*w.Width+col]
+}
+
+/*
+Windows reads the power of the raster in windows of the given number of
+lines from top to bottom and calls fn with every window. The buffer of
+the window is reused between calls.
+*/
+func (ra *Raster) Windows(lines int, fn func(w Window) error) (err error) {
+	if lines < 1 {
+		lines = 1
+	}
+
+	buf := make([]float32, lines*ra.Width)
+
+	for first := 0; first < ra.Height; first += lines {
+		n := lines
+		if first+n > ra.Height {
+			n = ra.Height - first
+		}
+
+		if err = ra.PowerLines(first, n, buf); err != nil {
+			return
+		}
+
+		w := Window{First: first, Lines: n, Width: ra.Width,
+			Power: buf[:n*ra.Width]}
+
+		if err = fn(w); err != nil {
+			return
+		}
+	}
+
+	return nil
+}
+
+/*
+Multilook averages the power of the raster in blocks of looks.Rng
+samples and looks.Azi lines, e.g. for quicklooks. Partial blocks at the
+edges are dropped.
+*/
+func (ra *Raster) Multilook(looks common.RngAzi) (img []float32, width, height int, err error) {
+	if looks.Rng < 1 || looks.Azi < 1 {
+		return nil, 0, 0, fmt.Errorf("invalid number of looks %v", looks)
+	}
+
+	width, height = ra.Width/looks.Rng, ra.Height/looks.Azi
+	img = make([]float32, width*height)
+	norm := float32(looks.Rng * looks.Azi)
+
+	err = ra.Windows(looks.Azi, func(w Window) error {
+		row := w.First / looks.Azi
+		if row >= height {
+			return nil
+		}
+
+		for ii := 0; ii < w.Lines; ii++ {
+			for jj := 0; jj < width*looks.Rng; jj++ {
+				img[row*width+jj/looks.Rng] += w.At(ii, jj) / norm
+			}
+		}
+		return nil
+	})
+
+	return
+}
+
+/*
+ZeroFraction returns the fraction of samples with zero power, checking
+every step-th line. A high fraction hints at missing or corrupt
+measurement data.
+*/
+func (ra *Raster) ZeroFraction(step int) (f float64, err error) {
+	if step < 1 {
+		step = 1
+	}
+
+	buf := make([]float32, ra.Width)
+	zeros, total := 0, 0
+
+	for line := 0; line < ra.Height; line += step {
+		if err = ra.PowerLines(line, 1, buf); err != nil {
+			return
+		}
+
+		for _, p := range buf {
+			if p == 0.0 {
+				zeros++
+			}
+		}
+		total += len(buf)
+	}
+
+	if total == 0 {
+		return 0.0, nil
+	}
+
+	return float64(zeros) / float64(total), nil
+}
+
+/*
+entryReaderAt provides random access to a compressed zip entry by
+reading it as a stream. Reading forward only skips the bytes in between,
+reading backward reopens the entry, so access from top to bottom is
+efficient.
+*/
+type entryReaderAt struct {
+	file *zip.File
+	rc   io.ReadCloser
+	pos  int64
+}
+
+func (e *entryReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	if e.rc == nil || off < e.pos {
+		if err = e.Close(); err != nil {
+			return
+		}
+
+		if e.rc, err = e.file.Open(); err != nil {
+			return
+		}
+		e.pos = 0
+	}
+
+	if skip := off - e.pos; skip > 0 {
+		m, err := io.CopyN(ioutil.Discard, e.rc, skip)
+		e.pos += m
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err = io.ReadFull(e.rc, p)
+	e.pos += int64(n)
+	return
+}
+
+func (e *entryReaderAt) Close() (err error) {
+	if e.rc != nil {
+		err = e.rc.Close()
+		e.rc = nil
+	}
+	return
+}
+
+/*
+openEntryAt returns random access to a zip entry. Stored entries (the
+usual case for the measurement files of Sentinel-1 zipfiles) are read
+directly from the zipfile, compressed ones as a stream.
+*/
+func openEntryAt(zipPath string, file *zip.File) (r io.ReaderAt, c io.Closer, err error) {
+	if file.Method != zip.Store {
+		e := &entryReaderAt{file: file}
+		return e, e, nil
+	}
+
+	offset, err := file.DataOffset()
+	if err != nil {
+		return
+	}
+
+	f, err := os.Open(zipPath)
+	if err != nil {
+		return
+	}
+
+	return io.NewSectionReader(f, offset, int64(file.UncompressedSize64)),
+		f, nil
+}
+
+type rasterCloser struct {
+	entry, zip io.Closer
+}
+
+func (rc rasterCloser) Close() (err error) {
+	err = rc.entry.Close()
+	if Err := rc.zip.Close(); err == nil {
+		err = Err
+	}
+	return
+}
+
+/*
+Measurement opens the measurement TIFF of the subswath for reading
+directly from the zipfile, without extracting it. The subswath number is
+ignored for GRD products. The raster must be closed after use.
+*/
+func (s1 Zip) Measurement(pol common.Pol, iw int) (ra *Raster, err error) {
+	if pol == common.AllPolarisation {
+		pol = s1.pol
+	}
+
+	zr, err := zip.OpenReader(s1.Path.GetPath())
+	if err != nil {
+		return
+	}
+
+	tpl := s1.Templates[tiff].Render(iw, pol)
+
+	file := findEntry(zr.File, tpl)
+	if file == nil {
+		zr.Close()
+		return nil, fmt.Errorf("measurement file matching '%s' not found in "+
+			"zipfile '%s'", tpl, s1.Path)
+	}
+
+	r, c, err := openEntryAt(s1.Path.GetPath(), file)
+	if err != nil {
+		zr.Close()
+		return
+	}
+
+	if ra, err = NewRaster(r); err != nil {
+		c.Close()
+		zr.Close()
+		err = common.ParseFail(s1.Path, err).ToRetreive("measurement header")
+		return
+	}
+
+	ra.closer = rasterCloser{entry: c, zip: zr}
+	return ra, nil
+}
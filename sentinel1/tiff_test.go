@@ -0,0 +1,248 @@
+package sentinel1
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// padding after the strips of the generated TIFF files
+const tiffPadding = 6
+
+// testTIFF describes a small generated TIFF file.
+type testTIFF struct {
+	order                       binary.ByteOrder
+	width, height, rowsPerStrip int
+	typ                         SampleType
+	tiled                       bool
+}
+
+// sample returns the value of the sample in the line and column, the
+// imaginary part is only used for complex samples.
+func (tt testTIFF) sample(line, col int) (re, im int16) {
+	return int16(100*line + col), int16(-line - col)
+}
+
+/*
+bytes encodes the file with the IFD in front of the pixel data, so a
+truncated file still has a valid header. The strips are separated by
+padding to check that the strip offsets are used.
+*/
+func (tt testTIFF) bytes() []byte {
+	o := tt.order
+	size := tt.typ.Size()
+	nstrip := (tt.height + tt.rowsPerStrip - 1) / tt.rowsPerStrip
+
+	type entry struct {
+		tag, typ     uint16
+		count, value uint32
+	}
+
+	bits, format := uint32(32), uint32(formatComplexInt)
+	if tt.typ == UInt16Sample {
+		bits, format = 16, formatUInt
+	}
+
+	nentry := 10
+	if tt.tiled {
+		nentry++
+	}
+
+	extra := uint32(8 + 2 + 12*nentry + 4)
+	data := extra
+	if nstrip > 1 {
+		data += uint32(8 * nstrip)
+	}
+
+	offsets := make([]uint32, nstrip)
+	counts := make([]uint32, nstrip)
+
+	pos := data
+	for ii := range offsets {
+		rows := tt.rowsPerStrip
+		if rest := tt.height - ii*tt.rowsPerStrip; rest < rows {
+			rows = rest
+		}
+
+		offsets[ii], counts[ii] = pos, uint32(rows*tt.width*size)
+		pos += counts[ii] + tiffPadding
+	}
+
+	offsetValue, countValue := offsets[0], counts[0]
+	if nstrip > 1 {
+		offsetValue, countValue = extra, extra+uint32(4*nstrip)
+	}
+
+	entries := []entry{
+		{tagImageWidth, tiffLong, 1, uint32(tt.width)},
+		{tagImageLength, tiffLong, 1, uint32(tt.height)},
+		{tagBitsPerSample, tiffShort, 1, bits},
+		{tagCompression, tiffShort, 1, 1},
+		{tagStripOffsets, tiffLong, uint32(nstrip), offsetValue},
+		{tagSamplesPerPixel, tiffShort, 1, 1},
+		{tagRowsPerStrip, tiffShort, 1, uint32(tt.rowsPerStrip)},
+		{tagStripByteCounts, tiffLong, uint32(nstrip), countValue},
+	}
+
+	if tt.tiled {
+		entries = append(entries, entry{tagTileWidth, tiffShort, 1, 16})
+	}
+
+	entries = append(entries, entry{tagSampleFormat, tiffShort, 1, format})
+
+	b := make([]byte, pos)
+
+	if o == binary.LittleEndian {
+		copy(b, "II")
+	} else {
+		copy(b, "MM")
+	}
+	o.PutUint16(b[2:], 42)
+	o.PutUint32(b[4:], 8)
+
+	o.PutUint16(b[8:], uint16(len(entries)))
+	for ii, e := range entries {
+		eb := b[10+12*ii:]
+		o.PutUint16(eb, e.tag)
+		o.PutUint16(eb[2:], e.typ)
+		o.PutUint32(eb[4:], e.count)
+
+		if e.typ == tiffShort {
+			o.PutUint16(eb[8:], uint16(e.value))
+		} else {
+			o.PutUint32(eb[8:], e.value)
+		}
+	}
+
+	if nstrip > 1 {
+		for ii := range offsets {
+			o.PutUint32(b[int(extra)+4*ii:], offsets[ii])
+			o.PutUint32(b[int(extra)+4*(nstrip+ii):], counts[ii])
+		}
+	}
+
+	for line := 0; line < tt.height; line++ {
+		strip := line / tt.rowsPerStrip
+		off := int(offsets[strip]) +
+			(line%tt.rowsPerStrip)*tt.width*size
+
+		for col := 0; col < tt.width; col++ {
+			re, im := tt.sample(line, col)
+			sb := b[off+col*size:]
+
+			o.PutUint16(sb, uint16(re))
+			if tt.typ == CInt16Sample {
+				o.PutUint16(sb[2:], uint16(im))
+			}
+		}
+
+		// fill the padding after the strips
+		if (line+1)%tt.rowsPerStrip == 0 || line == tt.height-1 {
+			for ii := 0; ii < tiffPadding; ii++ {
+				b[off+tt.width*size+ii] = 0xff
+			}
+		}
+	}
+
+	return b
+}
+
+func TestRaster(t *testing.T) {
+	orders := map[string]binary.ByteOrder{
+		"little-endian": binary.LittleEndian,
+		"big-endian":    binary.BigEndian,
+	}
+
+	for name, order := range orders {
+		for _, tt := range []testTIFF{
+			{order: order, width: 3, height: 5, rowsPerStrip: 5, typ: CInt16Sample},
+			{order: order, width: 3, height: 5, rowsPerStrip: 2, typ: CInt16Sample},
+			{order: order, width: 4, height: 7, rowsPerStrip: 3, typ: UInt16Sample},
+		} {
+			ra, err := NewRaster(bytes.NewReader(tt.bytes()))
+			if err != nil {
+				t.Fatalf("%s, %d rows per strip: %s", name, tt.rowsPerStrip,
+					err)
+			}
+
+			if ra.Width != tt.width || ra.Height != tt.height ||
+				ra.Type != tt.typ {
+				t.Errorf("%s: expected %dx%d %s raster, got %dx%d %s", name,
+					tt.width, tt.height, tt.typ, ra.Width, ra.Height,
+					ra.Type)
+			}
+
+			// lines 1-3 cross the strip boundaries of multi-strip files
+			first, n := 1, 3
+			power := make([]float32, n*tt.width)
+
+			if err = ra.PowerLines(first, n, power); err != nil {
+				t.Fatal(err)
+			}
+
+			var samples []CInt16
+			if tt.typ == CInt16Sample {
+				samples = make([]CInt16, n*tt.width)
+				if err = ra.CInt16Lines(first, n, samples); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for ii := 0; ii < n; ii++ {
+				for jj := 0; jj < tt.width; jj++ {
+					re, im := tt.sample(first+ii, jj)
+					idx := ii*tt.width + jj
+
+					expected := float32(re) * float32(re)
+					if tt.typ == CInt16Sample {
+						expected += float32(im) * float32(im)
+
+						if s := samples[idx]; s.Re != re || s.Im != im {
+							t.Errorf("%s, %d rows per strip: expected "+
+								"sample %d%+di at line %d column %d, got "+
+								"%d%+di", name, tt.rowsPerStrip, re, im,
+								first+ii, jj, s.Re, s.Im)
+						}
+					}
+
+					if power[idx] != expected {
+						t.Errorf("%s, %d rows per strip: expected power %g "+
+							"at line %d column %d, got %g", name,
+							tt.rowsPerStrip, expected, first+ii, jj,
+							power[idx])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestRasterTiled(t *testing.T) {
+	tt := testTIFF{order: binary.LittleEndian, width: 3, height: 4,
+		rowsPerStrip: 4, typ: CInt16Sample, tiled: true}
+
+	if _, err := NewRaster(bytes.NewReader(tt.bytes())); err == nil {
+		t.Error("expected an error for a tiled TIFF file")
+	}
+}
+
+func TestRasterTruncated(t *testing.T) {
+	tt := testTIFF{order: binary.BigEndian, width: 3, height: 5,
+		rowsPerStrip: 2, typ: CInt16Sample}
+
+	b := tt.bytes()
+
+	// the padding after the last strip is not needed
+	for n := 0; n < len(b)-tiffPadding; n++ {
+		ra, err := NewRaster(bytes.NewReader(b[:n]))
+		if err != nil {
+			continue
+		}
+
+		power := make([]float32, tt.height*tt.width)
+		if err = ra.PowerLines(0, tt.height, power); err == nil {
+			t.Errorf("expected an error reading a file truncated to %d "+
+				"of %d bytes", n, len(b))
+		}
+	}
+}
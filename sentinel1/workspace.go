@@ -0,0 +1,114 @@
+package sentinel1
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bozso/gotoolbox/errors"
+	"github.com/bozso/gotoolbox/path"
+
+	"github.com/bozso/gomma/common"
+)
+
+/*
+Workspace is a scratch directory where a GAMMA program is run, so the
+temporary files it creates do not clash with other files in the current
+directory (or with other programs running in parallel). Outputs are
+moved out of the workspace and the workspace is removed afterwards.
+*/
+type Workspace struct {
+	dir string
+}
+
+// NewWorkspace creates a new, uniquely named directory inside parent.
+// It should be on the same filesystem as the final location of the
+// outputs, so they can be moved with a rename.
+func NewWorkspace(parent path.Dir, prefix string) (w Workspace, err error) {
+	dir, err := ioutil.TempDir(parent.GetPath(), prefix)
+	if err != nil {
+		err = errors.WrapFmt(err,
+			"failed to create workspace in directory '%s'", parent)
+		return
+	}
+
+	w.dir, err = filepath.Abs(dir)
+	return
+}
+
+func (w Workspace) String() string {
+	return w.dir
+}
+
+func (w Workspace) Join(elems ...string) (p path.Path) {
+	return path.New(filepath.Join(append([]string{w.dir}, elems...)...))
+}
+
+func (w Workspace) Dir() (d path.Dir, err error) {
+	return path.New(w.dir).ToDir()
+}
+
+func (w Workspace) Remove() (err error) {
+	return os.RemoveAll(w.dir)
+}
+
+/*
+Run calls the GAMMA program of the command registry with the workspace
+as its working directory, so the outputs and temporary files that the
+program creates in its working directory end up in the workspace.
+Programs in different workspaces can run in parallel. Paths passed to
+the program should be absolute.
+*/
+func (w Workspace) Run(cmd common.Command, args ...interface{}) (out string, err error) {
+	if out, err = cmd.CallIn(w.dir, args...); err != nil {
+		err = errors.WrapFmt(err, "execution in workspace '%s' failed", w.dir)
+	}
+	return
+}
+
+/*
+AbsTabfile copies the tabfile into the workspace with every path in it
+converted to an absolute path, so the tabfile is usable from inside the
+workspace.
+*/
+func (w Workspace) AbsTabfile(tab path.ValidFile) (p path.ValidFile, err error) {
+	src, err := os.Open(tab.GetPath())
+	if err != nil {
+		return
+	}
+	defer src.Close()
+
+	var buf bytes.Buffer
+
+	scan := bufio.NewScanner(src)
+	for scan.Scan() {
+		fields := strings.Fields(scan.Text())
+
+		// relative paths are relative to the current directory, like
+		// in the case of GAMMA programs
+		for ii, field := range fields {
+			if fields[ii], err = filepath.Abs(field); err != nil {
+				return
+			}
+		}
+
+		if len(fields) > 0 {
+			buf.WriteString(strings.Join(fields, " ") + "\n")
+		}
+	}
+
+	if err = scan.Err(); err != nil {
+		return
+	}
+
+	dst := w.Join(filepath.Base(tab.GetPath()))
+
+	if err = ioutil.WriteFile(dst.GetPath(), buf.Bytes(), 0644); err != nil {
+		return
+	}
+
+	return dst.ToValidFile()
+}
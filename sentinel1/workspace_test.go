@@ -0,0 +1,93 @@
+package sentinel1
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bozso/gotoolbox/path"
+
+	"github.com/bozso/gomma/common"
+)
+
+/*
+TestWorkspaceParallel runs a program in two workspaces at the same time.
+Each run waits until the other one has started, so the test fails if
+the runs are serialized, and records its working directory.
+*/
+func TestWorkspaceParallel(t *testing.T) {
+	const script = `touch "$1/$2"
+n=0
+while [ ! -e "$1/$3" ]; do
+	n=$((n + 1))
+	[ $n -gt 500 ] && exit 1
+	sleep 0.01
+done
+pwd > where`
+
+	tmp, err := ioutil.TempDir("", "workspaces")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	parent, err := path.New(tmp).ToDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sh := common.NewCommand("/bin/sh")
+	names := [2]string{"one", "two"}
+
+	var (
+		ws   [2]Workspace
+		errs [2]error
+		wg   sync.WaitGroup
+	)
+
+	for ii := range ws {
+		if ws[ii], err = NewWorkspace(parent, names[ii]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for ii := range ws {
+		wg.Add(1)
+
+		go func(ii int) {
+			defer wg.Done()
+			_, errs[ii] = ws[ii].Run(sh, "-c", script, "sh", tmp,
+				names[ii], names[1-ii])
+		}(ii)
+	}
+	wg.Wait()
+
+	for ii, w := range ws {
+		if errs[ii] != nil {
+			t.Fatalf("run in workspace '%s' failed: %s", w, errs[ii])
+		}
+
+		b, err := ioutil.ReadFile(filepath.Join(w.String(), "where"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := filepath.EvalSymlinks(strings.TrimSpace(string(b)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected, err := filepath.EvalSymlinks(w.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != expected {
+			t.Errorf("expected the program to run in '%s', got '%s'",
+				expected, got)
+		}
+	}
+}
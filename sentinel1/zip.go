@@ -20,7 +20,9 @@ type (
 		Path      path.ValidFile
 		Safe      path.File
 		pol       common.Pol
+		pols      []common.Pol
 		Templates templates
+		SwathMode AcquisitionMode
 		date      date.Range
 
 		mission       string
@@ -39,7 +41,12 @@ type (
 )
 
 func NewZip(zipPath path.ValidFile) (s1 *Zip, err error) {
-	const rexTemplate = "%s-iw%%d-slc-%%s-.*"
+	const (
+		rexTemplate = "%s-%s%%d-slc-%%s-.*"
+		// GRD products are not split into subswaths, so only the
+		// polarization is formatted into the template
+		grdTemplate = "%s-%s-grd-%%[2]s-.*"
+	)
 
 	s1 = &Zip{
 		Path: zipPath,
@@ -58,19 +65,31 @@ func NewZip(zipPath path.ValidFile) (s1 *Zip, err error) {
 	}
 
 	s1.mode = zipBase[4:6]
+	if err = s1.SwathMode.Set(s1.mode); err != nil {
+		return
+	}
+
+	s1.productType = zipBase[7:10]
+
 	safe := path.New(strings.ReplaceAll(zipBase, ".zip", ".SAFE")).ToFile()
-	tpl := fmt.Sprintf(rexTemplate, s1.mission)
+
+	rex := rexTemplate
+	if s1.IsGRD() {
+		rex = grdTemplate
+	}
+	tpl := fmt.Sprintf(rex, s1.mission, s1.SwathMode.SwathPrefix())
 
 	s1.Templates = newTemplates(safe, tpl)
 
 	s1.Safe = safe
 
-	err = s1.pol.Set(zipBase[14:16])
+	// polarization code of the product, e.g. "DV" for VV+VH
+	s1.pols, err = common.ParseProductPol(zipBase[14:16])
 	if err != nil {
 		return
 	}
+	s1.pol = s1.pols[0]
 
-	s1.productType = zipBase[7:10]
 	s1.resolution = string(zipBase[10])
 	s1.level = string(zipBase[12])
 	s1.productClass = string(zipBase[13])
@@ -146,6 +165,22 @@ func (s1 Zip) Quicklook(dst path.Dir) (s path.ValidFile, err error) {
 	return
 }
 
+// IsGRD reports whether the zipfile holds a Ground Range Detected product.
+func (s1 Zip) IsGRD() bool {
+	return s1.productType == "GRD"
+}
+
+// Pol returns the co-polarized channel of the product.
+func (s1 Zip) Pol() common.Pol {
+	return s1.pol
+}
+
+// Polarizations returns all channels of the product, the co-polarized
+// one first.
+func (s1 Zip) Polarizations() []common.Pol {
+	return s1.pols
+}
+
 func (s1 Zip) Date() time.Time {
 	return s1.date.Center()
 }
@@ -4,10 +4,13 @@ import (
 	"bufio"
 	"io"
 	"log"
+	"os"
 
 	"github.com/bozso/gotoolbox/cli/stream"
 	"github.com/bozso/gotoolbox/path"
 
+	"github.com/bozso/gomma/baseline"
+	"github.com/bozso/gomma/date"
 	s1 "github.com/bozso/gomma/sentinel1"
 )
 
@@ -21,20 +24,6 @@ type Input struct {
 	In stream.In `json:"input"`
 }
 
-func parseS1(zip path.ValidFile, dst path.Dir) (S1 *s1.Zip, IWs s1.IWInfos, err error) {
-	if S1, err = s1.NewZip(zip); err != nil {
-		return
-	}
-
-	log.Printf("Parsing IW Information for S1 zipfile '%s'", S1.Path)
-
-	if IWs, err = S1.Info(dst); err != nil {
-		return
-	}
-
-	return
-}
-
 func loadS1(reader io.Reader) (S1 s1.Zips, err error) {
 	file := bufio.NewScanner(reader)
 
@@ -60,3 +49,41 @@ func loadS1(reader io.Reader) (S1 s1.Zips, err error) {
 
 	return
 }
+
+/*
+selectMaster ranks the scenes of the stack as master candidates, logs
+them and optionally writes them to the report file. The date of the best
+candidate is returned.
+*/
+func selectMaster(stack baseline.Stack, opt baseline.MasterOptions, report string) (d string, err error) {
+	cands, err := stack.SelectMaster(opt)
+	if err != nil {
+		return
+	}
+
+	for _, c := range cands {
+		log.Printf("Master candidate %s: %s", c.Name, c.Rationale)
+	}
+
+	if report != "" {
+		file, err := os.Create(report)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+
+		if err = cands.WriteJSON(file); err != nil {
+			return "", err
+		}
+	}
+
+	best, err := cands.Best()
+	if err != nil {
+		return
+	}
+
+	d = date.Short.Format(best.Date)
+	log.Printf("Selected master date: %s", d)
+
+	return d, nil
+}
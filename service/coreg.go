@@ -0,0 +1,651 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bozso/gotoolbox/errors"
+	"github.com/bozso/gotoolbox/path"
+
+	"github.com/bozso/gomma/baseline"
+	"github.com/bozso/gomma/common"
+	"github.com/bozso/gomma/date"
+	s1 "github.com/bozso/gomma/sentinel1"
+)
+
+type CoregState int
+
+const (
+	CoregPending CoregState = iota
+	CoregDone
+	CoregFailed
+)
+
+func (c *CoregState) Set(s string) (err error) {
+	const mode errors.Mode = "coregistration state"
+
+	switch strings.ToLower(s) {
+	case "pending":
+		*c = CoregPending
+	case "done":
+		*c = CoregDone
+	case "failed":
+		*c = CoregFailed
+	default:
+		err = mode.Error(s)
+	}
+	return
+}
+
+func (c CoregState) String() (s string) {
+	switch c {
+	case CoregPending:
+		s = "pending"
+	case CoregDone:
+		s = "done"
+	case CoregFailed:
+		s = "failed"
+	default:
+		s = "unknown"
+	}
+	return
+}
+
+func (c CoregState) MarshalJSON() (b []byte, err error) {
+	return json.Marshal(c.String())
+}
+
+func (c *CoregState) UnmarshalJSON(b []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(b, &s); err != nil {
+		return
+	}
+
+	return c.Set(s)
+}
+
+// SceneStatus records the outcome of the coregistration of one scene.
+type SceneStatus struct {
+	Tab   string     `json:"slc_tab"`
+	State CoregState `json:"state"`
+
+	// Tabfile of the reference RSLC used for the coregistration, empty
+	// if the scene was coregistered directly to the master.
+	Reference string `json:"reference,omitempty"`
+
+	RSLC string `json:"rslc_tab,omitempty"`
+
+	// Tabfiles of the resampled cross-polarized SLCs.
+	CrossRSLC map[string]string `json:"cross_rslc_tabs,omitempty"`
+
+	// Tabfile of the RSLC that failed the quality check, it is moved
+	// into the rejected directory next to the RSLCs of the stack.
+	Rejected string `json:"rejected_rslc_tab,omitempty"`
+
+	Error   string           `json:"error,omitempty"`
+	Quality *s1.CoregQuality `json:"quality,omitempty"`
+	Updated time.Time        `json:"updated"`
+}
+
+func (s *SceneStatus) done(out s1.CoregOut, ref *s1.SLC) {
+	s.State, s.RSLC, s.Error = CoregDone, out.Rslc.Tab.String(), ""
+	s.CrossRSLC, s.Rejected = nil, ""
+
+	for pol, rslc := range out.Cross {
+		if s.CrossRSLC == nil {
+			s.CrossRSLC = map[string]string{}
+		}
+		s.CrossRSLC[pol.String()] = rslc.Tab.String()
+	}
+
+	s.setReference(ref)
+}
+
+func (s *SceneStatus) fail(err error, ref *s1.SLC) {
+	s.State, s.RSLC, s.Error = CoregFailed, "", err.Error()
+	s.CrossRSLC, s.Rejected = nil, ""
+	s.setReference(ref)
+}
+
+func (s *SceneStatus) setReference(ref *s1.SLC) {
+	s.Reference = tabOf(ref)
+	s.Updated = time.Now()
+}
+
+// tabOf returns the tabfile of the reference SLC, empty for the master.
+func tabOf(ref *s1.SLC) (s string) {
+	if ref != nil {
+		s = ref.Tab.String()
+	}
+	return
+}
+
+/*
+resumable reports whether the scene was coregistered in a previous run
+to the given reference, so its RSLC can be used without processing the
+scene again.
+*/
+func (s SceneStatus) resumable(ref *s1.SLC) (b bool) {
+	return s.State == CoregDone && s.RSLC != "" && s.Reference == tabOf(ref)
+}
+
+/*
+sceneCoreg coregisters and checks the scenes of a chain. It is
+implemented by coregRunner with the GAMMA programs.
+*/
+type sceneCoreg interface {
+	Coreg(slc, ref *s1.SLC, cross ...s1.CrossPol) (out s1.CoregOut, err error)
+	Quality(id string, out s1.CoregOut) (cq s1.CoregQuality, err error)
+	LoadRSLC(tab string) (rslc s1.SLC, err error)
+	Reject(out s1.CoregOut) (tab string, err error)
+}
+
+type coregRunner struct {
+	opt *s1.CoregOpt
+}
+
+func (c coregRunner) Coreg(slc, ref *s1.SLC, cross ...s1.CrossPol) (out s1.CoregOut, err error) {
+	return c.opt.Coreg(slc, ref, cross...)
+}
+
+func (c coregRunner) Quality(id string, out s1.CoregOut) (cq s1.CoregQuality, err error) {
+	return checkQuality(id, c.opt, out)
+}
+
+// LoadRSLC loads the coregistered SLC of an already processed scene.
+func (coregRunner) LoadRSLC(rslcTab string) (rslc s1.SLC, err error) {
+	tab, err := path.New(rslcTab).ToValidFile()
+	if err != nil {
+		return
+	}
+
+	if rslc, err = s1.FromTabfile(tab); err != nil {
+		return
+	}
+
+	exist, err := rslc.Exist()
+	if err != nil {
+		return
+	}
+
+	if !exist {
+		err = fmt.Errorf("datafiles of RSLC '%s' are missing", tab)
+	}
+
+	return
+}
+
+/*
+Reject moves the RSLCs of a scene that failed the quality check into the
+rejected subdirectory of their directory, so they are not mistaken for
+valid coregistered SLCs. It returns the tabfile of the moved co-polarized
+RSLC.
+*/
+func (coregRunner) Reject(out s1.CoregOut) (tab string, err error) {
+	rslcs := []s1.SLC{out.Rslc}
+	for _, rslc := range out.Cross {
+		rslcs = append(rslcs, rslc)
+	}
+
+	dir, err := out.Rslc.Tab.Dir().Join("rejected").Mkdir()
+	if err != nil {
+		return
+	}
+
+	for ii, rslc := range rslcs {
+		moved, err := rslc.Move(dir)
+		if err != nil {
+			return "", errors.WrapFmt(err, "failed to move rejected RSLC "+
+				"'%s'", rslc.Tab)
+		}
+
+		if err = rslc.Tab.Remove(); err != nil {
+			return "", err
+		}
+
+		if ii == 0 {
+			tab = moved.Tab.String()
+		}
+	}
+
+	return tab, nil
+}
+
+/*
+CoregStatus holds the status of the scenes of a stack coregistration
+keyed by the date of the scenes and the date of the master scene. It is
+saved after the master is chosen and after every scene, so an
+interrupted coregistration can be resumed with the same master.
+*/
+type CoregStatus struct {
+	file   path.File
+	Master string                  `json:"master"`
+	Scenes map[string]*SceneStatus `json:"scenes"`
+}
+
+// LoadCoregStatus loads the status saved in file, the returned status is
+// empty if the file does not exist.
+func LoadCoregStatus(file path.File) (cs CoregStatus, err error) {
+	cs = CoregStatus{
+		file:   file,
+		Scenes: map[string]*SceneStatus{},
+	}
+
+	exist, err := file.Exist()
+	if err != nil || !exist {
+		return
+	}
+
+	vf, err := file.ToValid()
+	if err != nil {
+		return
+	}
+
+	var loaded CoregStatus
+	if err = common.LoadJson(vf, &loaded); err != nil {
+		return
+	}
+
+	cs.Master = loaded.Master
+	if loaded.Scenes != nil {
+		cs.Scenes = loaded.Scenes
+	}
+
+	return cs, nil
+}
+
+// Scene returns the status of the scene with the given date, it is
+// created if it does not exist yet.
+func (cs *CoregStatus) Scene(id string, slc s1.SLC) (s *SceneStatus) {
+	s, ok := cs.Scenes[id]
+	if !ok {
+		s = &SceneStatus{State: CoregPending}
+		cs.Scenes[id] = s
+	}
+
+	s.Tab = slc.Tab.String()
+	return
+}
+
+// Failed returns the sorted dates of the scenes whose coregistration
+// failed.
+func (cs CoregStatus) Failed() (ids []string) {
+	for id, s := range cs.Scenes {
+		if s.State == CoregFailed {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Strings(ids)
+	return
+}
+
+// Quality collects the quality reports of the coregistered scenes.
+func (cs CoregStatus) Quality() (sq s1.StackQuality) {
+	for _, s := range cs.Scenes {
+		if s.Quality != nil {
+			sq = append(sq, *s.Quality)
+		}
+	}
+
+	sq.Sort()
+	return
+}
+
+// Save writes the status into a temporary file first and renames it,
+// so the status file is not corrupted if the process is interrupted.
+func (cs CoregStatus) Save() (err error) {
+	b, err := json.MarshalIndent(cs, "", "    ")
+	if err != nil {
+		return
+	}
+
+	p := cs.file.String()
+	tmp := p + ".tmp"
+
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return errors.WrapFmt(err,
+			"failed to write coregistration status to '%s'", tmp)
+	}
+
+	return os.Rename(tmp, p)
+}
+
+type SentinelCoreg struct {
+	// SLC tabfiles of the stack, one scene on every line. The first
+	// tabfile of a line is the co-polarized SLC, it can be followed by
+	// the tabfiles of the cross-polarized SLCs listed in CrossPols.
+	Input
+	MasterDate date.ShortTime `json:"master_date"`
+	Meta       s1.CoregMeta   `json:"coreg"`
+
+	// Select the master from the baselines of the SLCs if the master
+	// date is not set.
+	SelectMaster *baseline.MasterOptions `json:"select_master"`
+
+	// Optional JSON file where the ranked master candidates are written.
+	MasterReport string `json:"master_report"`
+
+	// Polarizations of the additional tabfiles in the lines of Input.
+	// They are resampled with the lookup table and offsets estimated
+	// from the co-polarized SLC.
+	CrossPols []common.Pol `json:"cross_polarizations"`
+
+	// JSON file where the status of the coregistration is saved.
+	StatusFile string `json:"status_file"`
+
+	// Do not try again to coregister scenes that failed in a previous
+	// run.
+	SkipFailed bool `json:"skip_failed"`
+
+	// Optional file where the quality summary of the stack is written.
+	QualityTable string `json:"quality_table"`
+}
+
+type stackScene struct {
+	s1.SLC
+	cross []s1.CrossPol
+}
+
+func loadSLC(tab string) (slc s1.SLC, err error) {
+	vf, err := path.New(tab).ToValidFile()
+	if err != nil {
+		return
+	}
+
+	if slc, err = s1.FromTabfile(vf); err != nil {
+		err = errors.WrapFmt(err,
+			"failed to parse Sentinel-1 SLC from '%s'", tab)
+	}
+	return
+}
+
+func loadScenes(reader io.Reader, crossPols []common.Pol) (scenes []stackScene, err error) {
+	file := bufio.NewScanner(reader)
+
+	for file.Scan() {
+		fields := strings.Fields(file.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) != len(crossPols)+1 {
+			return nil, fmt.Errorf("expected %d tabfiles in line '%s'",
+				len(crossPols)+1, file.Text())
+		}
+
+		var scene stackScene
+
+		if scene.SLC, err = loadSLC(fields[0]); err != nil {
+			return
+		}
+
+		for ii, pol := range crossPols {
+			slc, err := loadSLC(fields[ii+1])
+			if err != nil {
+				return nil, err
+			}
+
+			scene.cross = append(scene.cross, s1.CrossPol{
+				Pol: pol,
+				SLC: &slc,
+			})
+		}
+
+		scenes = append(scenes, scene)
+	}
+
+	err = file.Err()
+	return
+}
+
+/*
+StackCoreg coregisters every scene of the stack to the master scene.
+Starting from the master, scenes are processed forward and backward in
+time and the RSLC of the previous scene is used as the reference for
+the next one. If a scene fails, the last successfully coregistered
+scene stays the reference. Scenes finished in a previous run are
+skipped.
+*/
+func (s *S1Implement) StackCoreg(sc *SentinelCoreg) (err error) {
+	defer sc.In.Close()
+
+	if sc.StatusFile == "" {
+		return fmt.Errorf("coregistration status file is not set")
+	}
+
+	status, err := LoadCoregStatus(path.New(sc.StatusFile).ToFile())
+	if err != nil {
+		return
+	}
+
+	if !sc.MasterDate.IsSet() && sc.SelectMaster == nil && status.Master == "" {
+		return fmt.Errorf("master date is not set")
+	}
+
+	slcs, err := loadScenes(sc.In, sc.CrossPols)
+	if err != nil {
+		return
+	}
+
+	sort.Slice(slcs, func(i, j int) bool {
+		return slcs[i].Time.Before(slcs[j].Time)
+	})
+
+	masterDate, err := sc.masterDate(slcs, status.Master)
+	if err != nil {
+		return
+	}
+
+	midx := -1
+
+	for ii, slc := range slcs {
+		if date.Short.Format(slc.Time) == masterDate {
+			midx = ii
+			break
+		}
+	}
+
+	if midx < 0 {
+		return fmt.Errorf("could not find master SLC with date '%s'",
+			masterDate)
+	}
+
+	log.Printf("Master date: %s\n", masterDate)
+
+	opt, err := sc.Meta.Parse()
+	if err != nil {
+		return
+	}
+
+	status.Master = masterDate
+	if err = status.Save(); err != nil {
+		return
+	}
+
+	forward := slcs[midx+1:]
+
+	backward := make([]stackScene, 0, midx)
+	for ii := midx - 1; ii >= 0; ii-- {
+		backward = append(backward, slcs[ii])
+	}
+
+	for _, chain := range [][]stackScene{forward, backward} {
+		if err = sc.coregChain(coregRunner{&opt}, chain, &status); err != nil {
+			return
+		}
+	}
+
+	if failed := status.Failed(); len(failed) > 0 {
+		log.Printf("Coregistration failed for dates: %s",
+			strings.Join(failed, ", "))
+	}
+
+	if sc.QualityTable == "" {
+		return nil
+	}
+
+	table, err := os.Create(sc.QualityTable)
+	if err != nil {
+		return
+	}
+	defer table.Close()
+
+	return status.Quality().WriteTable(table)
+}
+
+/*
+masterDate returns the date of the master scene. It is the master date
+if it is set, otherwise the master saved in the status of a previous run
+is reused. Without a saved master it is selected from the baselines of
+the co-polarized SLCs. It is an error if the master date or the
+selection differs from the saved master, because the scenes already
+coregistered would belong to another master.
+*/
+func (sc SentinelCoreg) masterDate(slcs []stackScene, saved string) (d string, err error) {
+	switch {
+	case sc.MasterDate.IsSet():
+		d = date.Short.Format(sc.MasterDate.Time)
+	case saved != "" && sc.SelectMaster == nil:
+		return saved, nil
+	default:
+		if d, err = sc.selectMaster(slcs); err != nil {
+			return
+		}
+	}
+
+	if saved != "" && d != saved {
+		err = fmt.Errorf("master '%s' differs from master '%s' of the "+
+			"saved coregistration status '%s'", d, saved, sc.StatusFile)
+	}
+
+	return
+}
+
+// selectMaster selects the master from the baselines of the SLCs.
+func (sc SentinelCoreg) selectMaster(slcs []stackScene) (d string, err error) {
+	scenes := make([]baseline.Scene, 0, len(slcs))
+
+	for _, slc := range slcs {
+		scene, err := baseline.ReadTab(slc.Tab)
+		if err != nil {
+			return "", err
+		}
+
+		scene.Name = date.Short.Format(slc.Time)
+		scenes = append(scenes, scene)
+	}
+
+	return selectMaster(baseline.NewStack(scenes...), *sc.SelectMaster,
+		sc.MasterReport)
+}
+
+/*
+checkQuality parses the coregistration results of the scene, evaluates
+them against the burst overlap thresholds and saves the report as JSON
+next to the results file.
+*/
+func checkQuality(id string, opt *s1.CoregOpt, out s1.CoregOut) (cq s1.CoregQuality, err error) {
+	results, err := out.Ifg.Quality.ToValid()
+	if err != nil {
+		return
+	}
+
+	if cq, err = s1.LoadCoregQuality(results); err != nil {
+		return
+	}
+
+	cq.Scene = id
+	cq.Evaluate(opt.BurstOverlapThresh)
+
+	err = common.SaveJsonTo(results.AddExt("json"), cq)
+	return
+}
+
+/*
+coregChain coregisters the scenes of a chain. A scene is skipped if its
+persisted status shows that it was coregistered to the current reference
+and its RSLC can be loaded, scenes coregistered to an RSLC that was
+produced again in this run are processed again. RSLCs that fail the
+quality check are rejected and the scene is marked as failed.
+*/
+func (sc SentinelCoreg) coregChain(c sceneCoreg, slcs []stackScene, status *CoregStatus) (err error) {
+	var ref *s1.SLC
+
+	// set once the reference was coregistered in this run
+	redone := false
+
+	for ii := range slcs {
+		curr := &slcs[ii]
+		id := date.Short.Format(curr.Time)
+		scene := status.Scene(id, curr.SLC)
+
+		switch {
+		case !redone && scene.resumable(ref):
+			rslc, err := c.LoadRSLC(scene.RSLC)
+			if err == nil {
+				log.Printf("Scene '%s' is already coregistered, skipping",
+					id)
+				ref = &rslc
+				continue
+			}
+
+			log.Printf("Could not load RSLC of scene '%s', coregistering "+
+				"it again: %s", id, err)
+		case scene.State == CoregDone:
+			log.Printf("Reference of scene '%s' has changed, coregistering "+
+				"it again", id)
+		case scene.State == CoregFailed && sc.SkipFailed:
+			log.Printf("Coregistration of scene '%s' failed previously, "+
+				"skipping", id)
+			continue
+		}
+
+		out, Err := c.Coreg(&curr.SLC, ref, curr.cross...)
+
+		var quality s1.CoregQuality
+		if Err == nil {
+			quality, Err = c.Quality(id, out)
+		}
+
+		rejected := ""
+		if Err == nil && !quality.Passed {
+			Err = fmt.Errorf("coregistration quality check failed: %s",
+				strings.Join(quality.Problems, "; "))
+
+			if rejected, err = c.Reject(out); err != nil {
+				return
+			}
+		}
+
+		if Err != nil {
+			log.Printf("Coregistration of '%s' failed! Moving to the "+
+				"next scene\nError: %s", id, Err)
+			scene.fail(Err, ref)
+			scene.Rejected = rejected
+		} else {
+			scene.done(out, ref)
+			rslc := out.Rslc
+			ref, redone = &rslc, true
+		}
+
+		scene.Quality = nil
+		if quality.Scene != "" {
+			scene.Quality = &quality
+		}
+
+		if err = status.Save(); err != nil {
+			return
+		}
+	}
+
+	return nil
+}
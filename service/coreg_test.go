@@ -0,0 +1,299 @@
+package service
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bozso/gotoolbox/path"
+
+	"github.com/bozso/gomma/date"
+	s1 "github.com/bozso/gomma/sentinel1"
+)
+
+// fakeCoreg records the coregistrations instead of running GAMMA.
+type fakeCoreg struct {
+	t   *testing.T
+	dir string
+
+	// RSLC tabfiles that cannot be loaded
+	missing map[string]bool
+
+	// dates of the scenes that fail the quality check
+	badQuality map[string]bool
+
+	// date and reference of every coregistered scene
+	calls    []string
+	rejected []string
+}
+
+func (f *fakeCoreg) tab(name string) (vf path.ValidFile) {
+	p := filepath.Join(f.dir, name)
+	if err := ioutil.WriteFile(p, nil, 0644); err != nil {
+		f.t.Fatal(err)
+	}
+
+	vf, err := path.New(p).ToValidFile()
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return
+}
+
+func (f *fakeCoreg) Coreg(slc, ref *s1.SLC, cross ...s1.CrossPol) (out s1.CoregOut, err error) {
+	id := date.Short.Format(slc.Time)
+	f.calls = append(f.calls, fmt.Sprintf("%s <- %s", id,
+		filepath.Base(tabOf(ref))))
+
+	delete(f.missing, filepath.Join(f.dir, id+".RSLC_tab"))
+
+	out.Rslc = s1.SLC{Tab: f.tab(id + ".RSLC_tab"), SLCMeta: slc.SLCMeta}
+	return
+}
+
+func (f *fakeCoreg) Quality(id string, out s1.CoregOut) (cq s1.CoregQuality, err error) {
+	cq.Scene, cq.Passed = id, !f.badQuality[id]
+	if !cq.Passed {
+		cq.Problems = []string{"coherence below threshold"}
+	}
+	return
+}
+
+func (f *fakeCoreg) LoadRSLC(tab string) (rslc s1.SLC, err error) {
+	if f.missing[tab] {
+		return rslc, fmt.Errorf("datafiles of RSLC '%s' are missing", tab)
+	}
+
+	rslc.Tab, err = path.New(tab).ToValidFile()
+	return
+}
+
+func (f *fakeCoreg) Reject(out s1.CoregOut) (tab string, err error) {
+	tab = filepath.Join(f.dir, "rejected", filepath.Base(out.Rslc.Tab.String()))
+	f.rejected = append(f.rejected, tab)
+	return
+}
+
+func TestCoregChainResume(t *testing.T) {
+	ids := []string{"20200101", "20200113", "20200125", "20200206"}
+
+	type scene struct {
+		state     CoregState
+		reference string
+	}
+
+	for _, c := range []struct {
+		name       string
+		status     map[string]scene
+		missing    []string
+		badQuality []string
+		calls      []string
+		states     []CoregState
+		rejected   int
+	}{
+		{
+			name: "skip done and failed scenes",
+			status: map[string]scene{
+				"20200101": {CoregDone, ""},
+				"20200113": {CoregDone, "20200101.RSLC_tab"},
+				"20200125": {CoregFailed, "20200113.RSLC_tab"},
+			},
+			calls:  []string{"20200206 <- 20200113.RSLC_tab"},
+			states: []CoregState{CoregDone, CoregDone, CoregFailed, CoregDone},
+		},
+		{
+			name: "redo scenes after a missing reference",
+			status: map[string]scene{
+				"20200101": {CoregDone, ""},
+				"20200113": {CoregDone, "20200101.RSLC_tab"},
+				"20200125": {CoregFailed, "20200113.RSLC_tab"},
+			},
+			missing: []string{"20200101"},
+			calls: []string{
+				"20200101 <- .",
+				"20200113 <- 20200101.RSLC_tab",
+				"20200206 <- 20200113.RSLC_tab",
+			},
+			states: []CoregState{CoregDone, CoregDone, CoregFailed, CoregDone},
+		},
+		{
+			name: "redo scenes coregistered to another reference",
+			status: map[string]scene{
+				"20200101": {CoregDone, ""},
+				"20200113": {CoregDone, "20191220.RSLC_tab"},
+				"20200125": {CoregDone, "20200113.RSLC_tab"},
+			},
+			calls: []string{
+				"20200113 <- 20200101.RSLC_tab",
+				"20200125 <- 20200113.RSLC_tab",
+				"20200206 <- 20200125.RSLC_tab",
+			},
+			states: []CoregState{CoregDone, CoregDone, CoregDone, CoregDone},
+		},
+		{
+			name:       "reject scenes failing the quality check",
+			badQuality: []string{"20200113"},
+			calls: []string{
+				"20200101 <- .",
+				"20200113 <- 20200101.RSLC_tab",
+				"20200125 <- 20200101.RSLC_tab",
+				"20200206 <- 20200125.RSLC_tab",
+			},
+			states:   []CoregState{CoregDone, CoregFailed, CoregDone, CoregDone},
+			rejected: 1,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "coreg")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			fake := &fakeCoreg{
+				t:          t,
+				dir:        dir,
+				missing:    map[string]bool{},
+				badQuality: map[string]bool{},
+			}
+
+			for _, id := range c.missing {
+				fake.missing[filepath.Join(dir, id+".RSLC_tab")] = true
+			}
+
+			for _, id := range c.badQuality {
+				fake.badQuality[id] = true
+			}
+
+			file, err := path.New(filepath.Join(dir, "status.json")).ToFile()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			status, err := LoadCoregStatus(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			status.Master = "20191220"
+
+			slcs := make([]stackScene, len(ids))
+
+			for ii, id := range ids {
+				tm, err := date.Short.Parse(id)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				slcs[ii].SLC = s1.SLC{
+					Tab:     fake.tab(id + ".SLC_tab"),
+					SLCMeta: s1.SLCMeta{Time: tm},
+				}
+
+				s, ok := c.status[id]
+				if !ok {
+					continue
+				}
+
+				st := status.Scene(id, slcs[ii].SLC)
+				st.State = s.state
+
+				if s.state == CoregDone {
+					st.RSLC = fake.tab(id + ".RSLC_tab").String()
+				}
+
+				if s.reference != "" {
+					st.Reference = filepath.Join(dir, s.reference)
+				}
+			}
+
+			sc := SentinelCoreg{SkipFailed: true}
+
+			if err = sc.coregChain(fake, slcs, &status); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(fake.calls, c.calls) {
+				t.Errorf("expected coregistrations %q, got %q", c.calls,
+					fake.calls)
+			}
+
+			if len(fake.rejected) != c.rejected {
+				t.Errorf("expected %d rejected RSLCs, got %d", c.rejected,
+					len(fake.rejected))
+			}
+
+			for ii, id := range ids {
+				st := status.Scenes[id]
+				if st.State != c.states[ii] {
+					t.Errorf("expected scene '%s' to be %s, got %s", id,
+						c.states[ii], st.State)
+				}
+
+				if c.badQuality != nil && c.badQuality[0] == id &&
+					st.Rejected == "" {
+					t.Errorf("expected the RSLC of scene '%s' to be "+
+						"flagged as rejected", id)
+				}
+			}
+
+			// the status is saved after every coregistered scene
+			if len(c.calls) > 0 {
+				loaded, err := LoadCoregStatus(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if loaded.Master != status.Master {
+					t.Errorf("expected master '%s' in the saved status, "+
+						"got '%s'", status.Master, loaded.Master)
+				}
+
+				if len(loaded.Scenes) != len(ids) {
+					t.Errorf("expected %d scenes in the saved status, got %d",
+						len(ids), len(loaded.Scenes))
+				}
+			}
+		})
+	}
+}
+
+func TestCoregMasterDate(t *testing.T) {
+	master, err := date.Short.Parse("20200113")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name     string
+		master   date.ShortTime
+		saved    string
+		expected string
+		fail     bool
+	}{
+		{"reuse saved master", date.ShortTime{}, "20200113", "20200113", false},
+		{"master date set", date.NewShortTime(master), "", "20200113", false},
+		{"same as saved", date.NewShortTime(master), "20200113", "20200113", false},
+		{"differs from saved", date.NewShortTime(master), "20200125", "", true},
+	} {
+		sc := SentinelCoreg{MasterDate: c.master, StatusFile: "status.json"}
+
+		d, err := sc.masterDate(nil, c.saved)
+
+		if c.fail {
+			if err == nil {
+				t.Errorf("%s: expected an error, got master '%s'", c.name, d)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %s", c.name, err)
+		} else if d != c.expected {
+			t.Errorf("%s: expected master '%s', got '%s'", c.name,
+				c.expected, d)
+		}
+	}
+}
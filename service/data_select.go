@@ -2,6 +2,7 @@ package service
 
 import (
 	"fmt"
+	"log"
 
 	"github.com/bozso/gotoolbox/path"
 
@@ -21,8 +22,32 @@ type SentinelSelect struct {
 	AOI       common.AOI       `json:"aoi"`
 	CheckZips bool             `json:"check_zips"`
 	Pol       common.Pol       `json:"polarization"`
+
+	// Optional temporal criteria the acquisition dates have to satisfy
+	// in addition to Start and Stop.
+	Dates *date.Filter `json:"dates"`
+
+	// Whether to validate the MD5 checksums from the manifest when
+	// checking zipfiles.
+	VerifyChecksums bool `json:"verify_checksums"`
+
+	// Number of zipfiles parsed in parallel, defaults to the number
+	// of CPUs.
+	Workers int `json:"workers"`
+
+	// Maximum size of the extracted files kept in the cache directory
+	// in bytes, no limit is enforced if it is zero.
+	MaxCacheSize int64 `json:"max_cache_size"`
+
+	// Optional polygon area of interest. If set, zipfiles are selected
+	// by intersecting it with the burst footprints instead of checking
+	// the corners of AOI.
+	Polygon *s1.AOISelect `json:"polygon"`
 }
 
+// name of the IW information cache inside the cache directory
+const infoCacheFile = "iw_info_cache.json"
+
 func (s *S1Implement) SelectFiles(ss *SentinelSelect) (err error) {
 	dataFiles := ss.DataFiles
 
@@ -30,45 +55,172 @@ func (s *S1Implement) SelectFiles(ss *SentinelSelect) (err error) {
 		return fmt.Errorf("At least one datafile must be specified!")
 	}
 
-	checker := date.NewCheckers()
-
-	if d := ss.Start; d.IsSet() {
-		checker.Append(date.Min.New(d.Time))
-	}
-	if d := ss.Stop; d.IsSet() {
-		checker.Append(date.Max.New(d.Time))
+	checker, err := ss.dateChecker()
+	if err != nil {
+		return
 	}
 
-	// TODO: implement checkZip
-	//if dsect.CheckZips {
-	//    checker = func(s1zip S1Zip) bool {
-	//        return checker(s1zip) && s1zip.checkZip()
-	//    }
-	//    check = true
-	//
-	//}
-
-	// nzip := len(zipfiles)
+	checkOpt := s1.CheckOptions{
+		Pol:       ss.Pol,
+		VerifyMD5: ss.VerifyChecksums,
+	}
 
 	writer := ss.Out
 	defer writer.Close()
 
+	toScan := make([]path.ValidFile, 0, len(dataFiles))
+
 	for _, zip := range dataFiles {
-		s1zip, IWs, err := parseS1(zip, s.CacheDir)
+		if ss.CheckZips {
+			ok, err := checkZip(zip, checkOpt)
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				continue
+			}
+		}
+
+		toScan = append(toScan, zip)
+	}
+
+	if ss.Polygon != nil {
+		return ss.selectByPolygon(toScan, checker)
+	}
 
+	cacheFile := s.CacheDir.Join(infoCacheFile).ToFile()
+
+	cache, err := s1.LoadInfoCache(cacheFile)
+	if err != nil {
+		return
+	}
+
+	if n := cache.Prune(); n > 0 {
+		log.Printf("Removed %d outdated entries from the IW information "+
+			"cache", n)
+	}
+
+	scanner := s1.NewScanner(s.CacheDir).
+		WithWorkers(ss.Workers).
+		WithCache(cache)
+
+	for _, scanned := range scanner.Scan(toScan) {
+		if err = scanned.Err; err != nil {
+			break
+		}
+
+		s1zip := scanned.Zip
+
+		if scanned.Contains(ss.AOI) && checker.In(s1zip.Date()) {
+			_, err = fmt.Fprintf(writer, "%s\n", s1zip.Path)
+			if err != nil {
+				break
+			}
+		}
+	}
+
+	// the entries of the zipfiles scanned before a failure are kept
+	if Err := cache.Save(); Err != nil && err == nil {
+		err = Err
+	}
+
+	if err != nil {
+		return
+	}
+
+	if limit := ss.MaxCacheSize; limit > 0 {
+		freed, err := s1.LimitCacheDir(s.CacheDir, limit, cacheFile)
 		if err != nil {
 			return err
 		}
 
-		if IWs.Contains(ss.AOI) && checker.In(s1zip.Date()) {
-			_, err := fmt.Fprintf(writer, "%s\n", s1zip.Path)
+		log.Printf("Freed %d bytes from cache directory '%s'", freed,
+			s.CacheDir)
+	}
+
+	return nil
+}
+
+// dateChecker combines the start and stop dates, both inclusive, with the
+// date filter.
+func (ss SentinelSelect) dateChecker() (ch date.Checker, err error) {
+	f := date.Filter{}
+	if ss.Dates != nil {
+		f = *ss.Dates
+	}
+
+	if ss.Start.IsSet() || ss.Stop.IsSet() {
+		f.And = append(f.And, date.Filter{
+			Interval: &date.Interval{Start: ss.Start, Stop: ss.Stop},
+		})
+	}
+
+	if err = f.Validate(); err != nil {
+		return
+	}
+
+	return f.Checker(), nil
+}
+
+func (ss SentinelSelect) selectByPolygon(files []path.ValidFile, checker date.Checker) (err error) {
+	for _, zip := range files {
+		s1zip, err := s1.NewZip(zip)
+		if err != nil {
+			return err
+		}
+
+		if !checker.In(s1zip.Date()) {
+			continue
+		}
+
+		var bursts []s1.Burst
+
+		if s1zip.IsGRD() {
+			// the footprint of a GRD product is treated as a single burst
+			fp, err := s1zip.Footprint()
 			if err != nil {
 				return err
 			}
+
+			bursts = []s1.Burst{{Index: 1, Footprint: fp}}
+		} else if bursts, err = s1zip.Bursts(ss.Pol); err != nil {
+			return err
+		}
+
+		sel, ok := ss.Polygon.Select(bursts)
+		if !ok {
+			continue
 		}
+
+		log.Printf("Zipfile '%s' covers %.1f%% of the area of interest",
+			zip, sel.Coverage)
+
+		if _, err = fmt.Fprintf(ss.Out, "%s\n", s1zip.Path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func checkZip(zip path.ValidFile, opt s1.CheckOptions) (ok bool, err error) {
+	s1zip, err := s1.NewZip(zip)
+	if err != nil {
+		return
+	}
+
+	zc, err := s1zip.Check(opt)
+	if err != nil {
+		return
+	}
+
+	for _, failed := range zc.Failed() {
+		log.Printf("Rejecting zipfile '%s': %s: %s (%s)", zip, failed.Name,
+			failed.Problem, failed.Detail)
 	}
 
-	return
+	return zc.OK(), nil
 }
 
 //func (s *selector) extOpt(satellite string) *ExtractOpt {
@@ -229,18 +381,6 @@ func stepCoreg(self *Config) (err error) {
 }
 */
 
-func Search(s1 *s1.Zip, zips s1.Zips) *s1.Zip {
-	date1 := date.Short.Format(s1.Date())
-
-	for _, zip := range zips {
-		if date1 == date.Short.Format(zip.Date()) && s1.Path != zip.Path {
-			return zip
-		}
-	}
-
-	return nil
-}
-
 /*
 [check_ionosphere]
 # range and azimuth window size used in offset estimation
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"os"
+
+	"github.com/bozso/gotoolbox/path"
+
+	s1 "github.com/bozso/gomma/sentinel1"
+)
+
+/*
+SentinelFootprints exports the footprints of the zipfiles listed in the
+input into a GeoJSON FeatureCollection (written to the output) and
+optionally into a KML file with the quicklooks attached.
+*/
+type SentinelFootprints struct {
+	Output
+	Input
+
+	// Path of the KML file, it is not written if empty.
+	KML string `json:"kml"`
+
+	// Directory where the quicklooks are extracted. Quicklooks are
+	// not exported if it is not set.
+	QuicklookDir string `json:"quicklook_dir"`
+}
+
+func (s *S1Implement) ExportFootprints(sf *SentinelFootprints) (err error) {
+	defer sf.In.Close()
+	defer sf.Out.Close()
+
+	zips, err := loadS1(sf.In)
+	if err != nil {
+		return
+	}
+
+	var qlDir *path.Dir
+	if sf.QuicklookDir != "" {
+		d, err := path.New(sf.QuicklookDir).Mkdir()
+		if err != nil {
+			return err
+		}
+		qlDir = &d
+	}
+
+	features := make(s1.SceneFeatures, len(zips))
+
+	for ii, s1zip := range zips {
+		if features[ii], err = s1.NewSceneFeature(s1zip, qlDir); err != nil {
+			return
+		}
+	}
+
+	if err = features.WriteGeoJSON(&sf.Out); err != nil {
+		return
+	}
+
+	if sf.KML == "" {
+		return nil
+	}
+
+	f, err := os.Create(sf.KML)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	if err = features.WriteKML(f); err != nil {
+		return
+	}
+
+	return f.Close()
+}
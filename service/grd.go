@@ -0,0 +1,54 @@
+package service
+
+import (
+	"fmt"
+	"log"
+
+	s1 "github.com/bozso/gomma/sentinel1"
+)
+
+/*
+SentinelGRD converts GRD zipfiles, one on every line of the input, into
+terrain-corrected backscatter. The paths of the geocoded images are
+written to the output.
+*/
+type SentinelGRD struct {
+	Output
+	Input
+	Options s1.GRDOptions `json:"grd"`
+}
+
+func (s *S1Implement) GRDBackscatter(sg *SentinelGRD) (err error) {
+	defer sg.In.Close()
+
+	zips, err := loadS1(sg.In)
+	if err != nil {
+		return
+	}
+
+	grdDir, err := s.OutputDir.Join("GRD").Mkdir()
+	if err != nil {
+		return
+	}
+
+	defer sg.Out.Close()
+
+	for _, s1zip := range zips {
+		if !s1zip.IsGRD() {
+			return fmt.Errorf("zipfile '%s' is not a GRD product", s1zip.Path)
+		}
+
+		log.Printf("Processing GRD '%s'", s1zip.Path)
+
+		out, Err := s1zip.ImportGRD(grdDir, sg.Options)
+		if Err != nil {
+			return Err
+		}
+
+		if _, err = fmt.Fprintln(&sg.Out, out.Geocoded); err != nil {
+			return
+		}
+	}
+
+	return nil
+}
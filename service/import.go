@@ -3,11 +3,17 @@ package service
 import (
 	"bufio"
 	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
 
+	"github.com/bozso/gotoolbox/errors"
 	"github.com/bozso/gotoolbox/path"
 
 	//"github.com/bozso/emath/geometry"
 
+	"github.com/bozso/gomma/baseline"
 	"github.com/bozso/gomma/common"
 	"github.com/bozso/gomma/date"
 	s1 "github.com/bozso/gomma/sentinel1"
@@ -18,6 +24,146 @@ type SentinelImport struct {
 	Input
 	MasterDate date.ShortTime `json:"master_date"`
 	Pol        common.Pol     `json:"polarization"`
+
+	// Select the master from the baselines of the zipfiles if the master
+	// date is not set.
+	SelectMaster *baseline.MasterOptions `json:"select_master"`
+
+	// Optional JSON file where the ranked master candidates are written.
+	MasterReport string `json:"master_report"`
+
+	// Channels to import in multi-polarization mode. The co-polarized
+	// channel is coregistered and the cross-polarized ones are resampled
+	// using its lookup table later.
+	Pols []common.Pol `json:"polarizations"`
+
+	// Area of interest used for selecting the bursts of the master
+	// zipfile that are imported.
+	AOI *s1.AOISelect `json:"aoi"`
+
+	// JSON file holding the burst table. If it exists, it is used as is,
+	// otherwise it is created from AOI.
+	BurstTable string `json:"burst_table"`
+
+	// Only create the burst table, so it can be reviewed before import.
+	TableOnly bool `json:"table_only"`
+
+	// Import the slices of a datatake even if some of them are missing.
+	AllowGaps bool `json:"allow_gaps"`
+
+	// Import the zipfiles without S1_import_SLC_from_zipfiles. Only
+	// datatakes consisting of a single slice are supported.
+	Native bool `json:"native"`
+}
+
+/*
+polarizations returns the channels to be imported with the
+co-polarized channel first. If the polarization is set to "all", every
+channel of the master zipfile is imported.
+*/
+func (si SentinelImport) polarizations(master *s1.Zip) (pols []common.Pol) {
+	pols = append(pols, si.Pols...)
+
+	if len(pols) == 0 {
+		pols = []common.Pol{si.Pol}
+		if si.Pol == common.AllPolarisation {
+			pols = append([]common.Pol(nil), master.Polarizations()...)
+		}
+	}
+
+	sort.SliceStable(pols, func(i, j int) bool {
+		return !pols[i].IsCross() && pols[j].IsCross()
+	})
+
+	return
+}
+
+/*
+burstTable loads the burst table if the JSON file exists, otherwise it
+derives the table from the area of interest and the bursts of the
+master zipfile and saves it. The table is validated against the bursts
+of the master in both cases.
+*/
+func (si SentinelImport) burstTable(master *s1.Zip) (bt s1.BurstTable, err error) {
+	if si.BurstTable == "" {
+		return bt, fmt.Errorf("burst table file is not set")
+	}
+
+	file := path.New(si.BurstTable)
+
+	exists, err := file.Exist()
+	if err != nil {
+		return
+	}
+
+	if exists {
+		vf, err := file.ToValidFile()
+		if err != nil {
+			return bt, err
+		}
+
+		if err = common.LoadJson(vf, &bt); err != nil {
+			return bt, err
+		}
+	} else {
+		if si.AOI == nil {
+			return bt, fmt.Errorf("burst table '%s' does not exist and no "+
+				"area of interest is set to create it", file)
+		}
+
+		if bt, err = si.AOI.NewBurstTable(master, si.Pol); err != nil {
+			return
+		}
+	}
+
+	bursts, err := master.Bursts(si.Pol)
+	if err != nil {
+		return
+	}
+
+	if err = bt.ValidateBursts(bursts); err != nil {
+		return bt, errors.WrapFmt(err, "invalid burst table '%s'", file)
+	}
+
+	if !exists {
+		err = common.SaveJsonTo(file, bt)
+	}
+	return
+}
+
+/*
+masterDate returns the date of the master scene. If it is not set but
+master selection is configured, the master is selected from the
+baselines of the zipfiles, one scene per date.
+*/
+func (si SentinelImport) masterDate(zips s1.Zips) (d string, err error) {
+	if si.MasterDate.IsSet() {
+		return date.Short.Format(si.MasterDate.Time), nil
+	}
+
+	if si.SelectMaster == nil {
+		return "", fmt.Errorf("master date is not set")
+	}
+
+	scenes := make([]baseline.Scene, 0, len(zips))
+	seen := make(map[string]bool)
+
+	for _, s1zip := range zips {
+		day := date.Short.Format(s1zip.Date())
+		if seen[day] {
+			continue
+		}
+		seen[day] = true
+
+		scene, err := s1zip.Scene(si.Pol)
+		if err != nil {
+			return "", err
+		}
+		scenes = append(scenes, scene)
+	}
+
+	return selectMaster(baseline.NewStack(scenes...), *si.SelectMaster,
+		si.MasterReport)
 }
 
 var s1Import = common.Must("S1_import_SLC_from_zipfiles")
@@ -35,11 +181,11 @@ func (s *S1Implement) DataImport(si *SentinelImport) (err error) {
 		return
 	}
 
-	if !si.MasterDate.IsSet() {
-		return fmt.Errorf("master date is not set")
+	masterDate, err := si.masterDate(zips)
+	if err != nil {
+		return
 	}
 
-	masterDate := date.Short.Format(si.MasterDate.Time)
 	var master *s1.Zip
 	for _, s1zip := range zips {
 		if date.Short.Format(s1zip.Date()) == masterDate {
@@ -51,52 +197,21 @@ func (s *S1Implement) DataImport(si *SentinelImport) (err error) {
 		return fmt.Errorf("could not find master file, Sentinel 1 zipfile with date '%s' not found", masterDate)
 	}
 
-	masterIW, err := master.Info(s.CachePath)
+	table, err := si.burstTable(master)
 	if err != nil {
 		return
 	}
 
-	fburst, err := path.New(burst_table).Create()
-	if err != nil {
-		return
+	if si.TableOnly {
+		log.Printf("Burst table is stored in '%s', review it before importing",
+			si.BurstTable)
+		return nil
 	}
-	defer fburst.Close()
 
-	_, err = fmt.Fprintf(fburst, "zipfile: %s\n", master.Path)
-	if err != nil {
+	if err = table.Save(path.New(burst_table).ToFile()); err != nil {
 		return
 	}
 
-	nIWs := 0
-
-	for ii, iw := range si.IWs {
-		min, max := iw.Min, iw.Max
-
-		if min == 0 && max == 0 {
-			continue
-		}
-
-		nburst := max - min
-
-		if nburst < 0 {
-			return fmt.Errorf(
-				"number of burst for IW%d is negative, did you mix up first and last burst numbers?", ii+1)
-		}
-
-		IW := masterIW[ii]
-		first := IW.bursts[min-1]
-		last := IW.bursts[max-1]
-
-		const tpl = "iw%d_number_of_bursts: %d\niw%d_first_burst: %f\niw%d_last_burst: %f\n"
-		_, err = fmt.Fprintf(fburst, tpl, ii+1, nburst, ii+1, first, ii+1, last)
-
-		if err != nil {
-			return
-		}
-
-		nIWs++
-	}
-
 	// defer os.Remove(ziplist)
 
 	slcDir, err := s.OutputDir.Join("SLC").Mkdir()
@@ -104,15 +219,29 @@ func (s *S1Implement) DataImport(si *SentinelImport) (err error) {
 		return
 	}
 
-	pol, writer := si.Pol, bufio.NewWriter(&si.Out)
+	writer := bufio.NewWriter(&si.Out)
 	defer si.Out.Close()
+	defer writer.Flush()
 
-	for _, s1zip := range zips {
-		// iw, err := s1zip.Info(extInfo)
-		date := date.Short.Format(s1zip.Date())
-		other := Search(s1zip, zips)
+	pols := si.polarizations(master)
+
+	passes, err := s1.AssembleSlices(zips, s1.DefaultSliceTolerance)
+	if err != nil {
+		return
+	}
+
+	for _, pass := range passes {
+		for _, gap := range pass.Gaps {
+			log.Printf("%s: %s", pass, gap)
+		}
+
+		if !pass.Contiguous() && !si.AllowGaps {
+			return fmt.Errorf("slices of %s are not contiguous", pass)
+		}
+
+		date := date.Short.Format(pass.Date())
 
-		err = toZiplist(ziplist, s1zip, other)
+		err = toZiplist(ziplist, pass.Slices)
 
 		if err != nil {
 			return fmt.Errorf(
@@ -120,28 +249,57 @@ func (s *S1Implement) DataImport(si *SentinelImport) (err error) {
 				ziplist, err)
 		}
 
-		tab, err := path.New(fmt.Sprintf("%s.%s.SLC_TAB", date, pol)).
-			ToValidFile()
-		if err != nil {
-			return
-		}
+		// tabfiles of the scene, co-polarized first
+		tabs := make([]string, 0, len(pols))
 
-		slc := s1.FromTabfile()
+		for _, pol := range pols {
+			// outputs are grouped per polarization in multi-pol mode
+			dir := slcDir
+			if len(pols) > 1 {
+				if dir, err = slcDir.Join(pol.String()).Mkdir(); err != nil {
+					return err
+				}
+			}
 
-		for ii := 0; ii < nIWs; ii++ {
-			dat := fmt.Sprintf("%s.slc.iw%d", base, ii+1)
+			if si.Native {
+				tab, err := importNative(pass, pol, &table, dir)
+				if err != nil {
+					return err
+				}
 
-			slc.IWs[ii], err = s1.NewIW(dat).Load()
+				tabs = append(tabs, tab.String())
+				continue
+			}
+
+			// single precision complex output with the subswaths
+			// selected by the burst table, written into the current
+			// directory
+			_, err = s1Import.Call(ziplist, burst_table, pol, 0,
+				table.SwathFlag())
 			if err != nil {
 				return
 			}
-		}
 
-		if slc, err = slc.Move(slcDir); err != nil {
-			return
+			tab, err := path.New(fmt.Sprintf("%s.%s.SLC_TAB", date, pol)).
+				ToValidFile()
+			if err != nil {
+				return err
+			}
+
+			slc, err := s1.FromTabfile(tab)
+			if err != nil {
+				return err
+			}
+
+			if slc, err = slc.Move(dir); err != nil {
+				return err
+			}
+
+			tabs = append(tabs, slc.Tab.String())
 		}
 
-		if _, err = writer.WriteString(slc.Tab); err != nil {
+		_, err = writer.WriteString(strings.Join(tabs, " ") + "\n")
+		if err != nil {
 			return
 		}
 	}
@@ -155,3 +313,24 @@ func (s *S1Implement) DataImport(si *SentinelImport) (err error) {
 
 	return nil
 }
+
+// importNative imports the pass without GAMMA into the dst directory.
+func importNative(pass s1.Pass, pol common.Pol, table *s1.BurstTable, dst path.Dir) (tab path.ValidFile, err error) {
+	if len(pass.Slices) != 1 {
+		err = fmt.Errorf("native import of %s is not possible, it "+
+			"consists of %d slices", pass, len(pass.Slices))
+		return
+	}
+
+	return pass.Slices[0].ImportNative(dst, pol, table)
+}
+
+func toZiplist(name string, zips s1.Zips) (err error) {
+	file, err := os.Create(name)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	return s1.FormatZiplist(file, zips...)
+}
@@ -12,4 +12,7 @@ type Setup interface {
 type Sentinel1 interface {
 	SelectFiles(SentinelSelect) error
 	DataImport(SentinelImport) error
+	StackCoreg(SentinelCoreg) error
+	GRDBackscatter(SentinelGRD) error
+	ExportFootprints(SentinelFootprints) error
 }
@@ -5,6 +5,8 @@ import (
 
 	"github.com/bozso/gotoolbox/enum"
 	"github.com/bozso/gotoolbox/path"
+
+	"github.com/bozso/gomma/download"
 	//"git.st.ht/~istvan_bozso/shutil/command"
 )
 
@@ -34,10 +36,11 @@ func (r RasterExtension) Validate() (err error) {
 }
 
 type Common struct {
-	RasterExtension RasterExtension `json:"raster_extension"`
-	GammaDirectory  path.Dir        `json:"gamma_directory"`
-	CachePath       path.Dir        `json:"cache_path"`
-	Logging         Logger          `json:"logging"`
+	RasterExtension RasterExtension   `json:"raster_extension"`
+	GammaDirectory  path.Dir          `json:"gamma_directory"`
+	CachePath       path.Dir          `json:"cache_path"`
+	Logging         Logger            `json:"logging"`
+	Download        download.Settings `json:"download"`
 }
 
 type Payload struct {
@@ -0,0 +1,275 @@
+package stac
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type (
+	SpatialExtent struct {
+		BBox [][]float64 `json:"bbox"`
+	}
+
+	TemporalExtent struct {
+		Interval [][]string `json:"interval"`
+	}
+
+	Extent struct {
+		Spatial  SpatialExtent  `json:"spatial"`
+		Temporal TemporalExtent `json:"temporal"`
+	}
+)
+
+// Collection is a STAC Collection holding the items of a track.
+type Collection struct {
+	Type           string                 `json:"type"`
+	StacVersion    string                 `json:"stac_version"`
+	StacExtensions []string               `json:"stac_extensions"`
+	ID             string                 `json:"id"`
+	Description    string                 `json:"description"`
+	License        string                 `json:"license"`
+	Extent         Extent                 `json:"extent"`
+	Summaries      map[string]interface{} `json:"summaries"`
+	Links          []Link                 `json:"links"`
+
+	Track Track  `json:"-"`
+	Items []Item `json:"-"`
+}
+
+func newCollection(t Track) (c *Collection) {
+	return &Collection{
+		Type:           "Collection",
+		StacVersion:    Version,
+		StacExtensions: []string{SARExtension, SatelliteExtension},
+		ID:             t.ID(),
+		Description: fmt.Sprintf("Products of relative orbit %d (%s)",
+			t.RelativeOrbit, strings.ToLower(t.Pass)),
+		License: "proprietary",
+		Track:   t,
+	}
+}
+
+// extent computes the union of the bounds and time ranges of the items.
+func (c *Collection) extent() {
+	if len(c.Items) == 0 {
+		return
+	}
+
+	b := c.Items[0].bound
+	start, end := c.Items[0].start, c.Items[0].end
+
+	kinds := map[string]bool{}
+
+	for _, it := range c.Items {
+		b = b.Union(it.bound)
+
+		if it.start.Before(start) {
+			start = it.start
+		}
+
+		if it.end.After(end) {
+			end = it.end
+		}
+
+		kinds[fmt.Sprint(it.Properties["gomma:kind"])] = true
+	}
+
+	c.Extent = Extent{
+		Spatial: SpatialExtent{BBox: [][]float64{bbox(b)}},
+		Temporal: TemporalExtent{
+			Interval: [][]string{{start.Format(timeFmt), end.Format(timeFmt)}},
+		},
+	}
+
+	summary := make([]string, 0, len(kinds))
+	for k := range kinds {
+		summary = append(summary, k)
+	}
+	sort.Strings(summary)
+
+	c.Summaries = map[string]interface{}{
+		"gomma:kind":         summary,
+		"sat:relative_orbit": []int{c.Track.RelativeOrbit},
+		"sat:orbit_state":    []string{strings.ToLower(c.Track.Pass)},
+	}
+}
+
+/*
+Catalog is a static, self-contained STAC catalog. Written to a
+directory, it has the layout
+
+	catalog.json
+	<collection id>/collection.json
+	<collection id>/<item id>/<item id>.json
+
+and every link and local asset path is relative, so the directory can be
+moved or served as is.
+*/
+type Catalog struct {
+	Type        string `json:"type"`
+	StacVersion string `json:"stac_version"`
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Links       []Link `json:"links"`
+
+	collections map[string]*Collection
+}
+
+func NewCatalog(id, description string) (c *Catalog) {
+	return &Catalog{
+		Type:        "Catalog",
+		StacVersion: Version,
+		ID:          id,
+		Description: description,
+		collections: map[string]*Collection{},
+	}
+}
+
+// Add converts the product into an item and adds it to the collection of
+// its track.
+func (c *Catalog) Add(p Product) (err error) {
+	it, err := NewItem(p)
+	if err != nil {
+		return
+	}
+
+	col, ok := c.collections[it.Collection]
+	if !ok {
+		col = newCollection(p.Track)
+		c.collections[it.Collection] = col
+	}
+
+	for _, other := range col.Items {
+		if other.ID == it.ID {
+			return fmt.Errorf("item '%s' is already in collection '%s'",
+				it.ID, col.ID)
+		}
+	}
+
+	col.Items = append(col.Items, it)
+	return nil
+}
+
+// Collections returns the collections sorted by their IDs.
+func (c *Catalog) Collections() (cols []*Collection) {
+	for _, col := range c.collections {
+		cols = append(cols, col)
+	}
+
+	sort.Slice(cols, func(ii, jj int) bool {
+		return cols[ii].ID < cols[jj].ID
+	})
+
+	return
+}
+
+func writeJSON(file string, v interface{}) (err error) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return
+	}
+
+	return ioutil.WriteFile(file, append(b, '\n'), 0644)
+}
+
+func relLink(rel, href, title string) Link {
+	return Link{Rel: rel, Href: href, Type: JSON, Title: title}
+}
+
+// relAsset makes the path of local assets relative to dir.
+func relAsset(dir string, a Asset) (ra Asset, err error) {
+	ra = a
+
+	if strings.Contains(a.Href, "://") {
+		return ra, nil
+	}
+
+	abs, err := filepath.Abs(a.Href)
+	if err != nil {
+		return
+	}
+
+	if ra.Href, err = filepath.Rel(dir, abs); err != nil {
+		return
+	}
+
+	ra.Href = "./" + filepath.ToSlash(ra.Href)
+	return ra, nil
+}
+
+// Write writes the catalog with its collections and items into root.
+func (c *Catalog) Write(root string) (err error) {
+	if root, err = filepath.Abs(root); err != nil {
+		return
+	}
+
+	if err = os.MkdirAll(root, os.ModePerm); err != nil {
+		return
+	}
+
+	c.Links = []Link{relLink("root", "./catalog.json", c.ID)}
+
+	for _, col := range c.Collections() {
+		if err = c.writeCollection(root, col); err != nil {
+			return
+		}
+
+		c.Links = append(c.Links, relLink("child",
+			"./"+col.ID+"/collection.json", col.ID))
+	}
+
+	return writeJSON(filepath.Join(root, "catalog.json"), c)
+}
+
+func (c *Catalog) writeCollection(root string, col *Collection) (err error) {
+	dir := filepath.Join(root, col.ID)
+
+	col.extent()
+	col.Links = []Link{
+		relLink("root", "../catalog.json", c.ID),
+		relLink("parent", "../catalog.json", c.ID),
+	}
+
+	sort.Slice(col.Items, func(ii, jj int) bool {
+		a, b := col.Items[ii], col.Items[jj]
+		if !a.start.Equal(b.start) {
+			return a.start.Before(b.start)
+		}
+		return a.ID < b.ID
+	})
+
+	for _, it := range col.Items {
+		itemDir := filepath.Join(dir, it.ID)
+		if err = os.MkdirAll(itemDir, os.ModePerm); err != nil {
+			return
+		}
+
+		assets := make(map[string]Asset, len(it.Assets))
+		for key, a := range it.Assets {
+			if assets[key], err = relAsset(itemDir, a); err != nil {
+				return
+			}
+		}
+		it.Assets = assets
+
+		it.Links = []Link{
+			relLink("root", "../../catalog.json", c.ID),
+			relLink("parent", "../collection.json", col.ID),
+			relLink("collection", "../collection.json", col.ID),
+		}
+
+		if err = writeJSON(filepath.Join(itemDir, it.ID+".json"), it); err != nil {
+			return
+		}
+
+		col.Links = append(col.Links, relLink("item",
+			"./"+it.ID+"/"+it.ID+".json", ""))
+	}
+
+	return writeJSON(filepath.Join(dir, "collection.json"), col)
+}
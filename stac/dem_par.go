@@ -0,0 +1,101 @@
+package stac
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/bozso/gotoolbox/errors"
+	"github.com/bozso/gotoolbox/path"
+
+	"github.com/paulmach/orb"
+
+	"github.com/bozso/gomma/utils/params"
+)
+
+// DemPar holds the grid of a geocoded product in the EQA projection.
+type DemPar struct {
+	Width, Lines       int
+	CornerLat, PostLat float64
+	CornerLon, PostLon float64
+}
+
+/*
+ParseDemPar reads the grid parameters from a dem_par file. Only grids in
+geographic coordinates (the EQA projection of GAMMA) are accepted, since
+STAC geometries are in longitude and latitude and the grids of map
+projections would need to be reprojected.
+*/
+func ParseDemPar(p params.Parser) (d DemPar, err error) {
+	proj, err := p.Param("DEM_projection")
+	if err != nil {
+		return
+	}
+
+	if proj = strings.TrimSpace(proj); proj != "EQA" {
+		err = fmt.Errorf("unsupported DEM projection '%s', only EQA "+
+			"grids can be published", proj)
+		return
+	}
+
+	if d.Width, err = p.Int("width", 0); err != nil {
+		return
+	}
+
+	if d.Lines, err = p.Int("nlines", 0); err != nil {
+		return
+	}
+
+	if d.CornerLat, err = p.Float("corner_lat", 0); err != nil {
+		return
+	}
+
+	if d.CornerLon, err = p.Float("corner_lon", 0); err != nil {
+		return
+	}
+
+	if d.PostLat, err = p.Float("post_lat", 0); err != nil {
+		return
+	}
+
+	if d.PostLon, err = p.Float("post_lon", 0); err != nil {
+		return
+	}
+
+	if d.Width <= 0 || d.Lines <= 0 {
+		err = fmt.Errorf("invalid dimensions %d x %d of dem_par",
+			d.Width, d.Lines)
+	}
+
+	return
+}
+
+func ReadDemPar(file path.ValidFile) (d DemPar, err error) {
+	p, err := params.FromFile(file, ":")
+	if err != nil {
+		return
+	}
+
+	if d, err = ParseDemPar(p.ToParser()); err != nil {
+		err = errors.WrapFmt(err, "failed to parse dem_par file '%s'", file)
+	}
+	return
+}
+
+/*
+Bound returns the area covered by the grid. The corner of the dem_par
+is the centre of the upper left pixel, so the bound is extended by half
+a pixel on every side.
+*/
+func (d DemPar) Bound() (b orb.Bound) {
+	lat1 := d.CornerLat - d.PostLat/2.0
+	lat2 := lat1 + float64(d.Lines)*d.PostLat
+
+	lon1 := d.CornerLon - d.PostLon/2.0
+	lon2 := lon1 + float64(d.Width)*d.PostLon
+
+	return orb.Bound{
+		Min: orb.Point{math.Min(lon1, lon2), math.Min(lat1, lat2)},
+		Max: orb.Point{math.Max(lon1, lon2), math.Max(lat1, lat2)},
+	}
+}
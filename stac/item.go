@@ -0,0 +1,205 @@
+package stac
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+
+	"github.com/bozso/gomma/data"
+)
+
+// Track identifies the acquisition geometry shared by the products of a
+// collection.
+type Track struct {
+	// Platform, e.g. "sentinel-1".
+	Platform string `json:"platform"`
+
+	RelativeOrbit int `json:"relative_orbit"`
+
+	// "ascending" or "descending".
+	Pass string `json:"pass"`
+}
+
+func (t Track) ID() string {
+	platform := strings.ToLower(t.Platform)
+	if platform == "" {
+		platform = "sar"
+	}
+
+	return fmt.Sprintf("%s-track-%03d-%s", platform, t.RelativeOrbit,
+		strings.ToLower(t.Pass))
+}
+
+func (t Track) Validate() (err error) {
+	switch strings.ToLower(t.Pass) {
+	case "ascending", "descending":
+	default:
+		return fmt.Errorf("expected 'ascending' or 'descending' pass, got '%s'",
+			t.Pass)
+	}
+
+	if t.RelativeOrbit <= 0 {
+		return fmt.Errorf("invalid relative orbit %d", t.RelativeOrbit)
+	}
+
+	return nil
+}
+
+// SAR holds the fields of the SAR extension.
+type SAR struct {
+	// e.g. "IW"
+	InstrumentMode string `json:"instrument_mode"`
+
+	// e.g. "C"
+	FrequencyBand string `json:"frequency_band"`
+
+	// Centre frequency in GHz, 0 if unknown.
+	CenterFrequency float64 `json:"center_frequency"`
+
+	Polarizations []string `json:"polarizations"`
+
+	// Number of looks, zero values are treated as single look.
+	Looks data.RngAzi `json:"looks"`
+}
+
+/*
+Product describes a geocoded output of the processing. The acquisition
+date comes from its metadata; products derived from image pairs, like
+interferograms and coherences, also need the date of the secondary
+image. Products are filled in by the caller; the package does not read
+the file types of the processing (MLIs, interferograms, lookup tables)
+itself, only their dem_par files with ReadDemPar.
+*/
+type Product struct {
+	ID   string    `json:"id"`
+	Kind Kind      `json:"kind"`
+	Meta data.Meta `json:"meta"`
+
+	// Date of the secondary acquisition of pair products.
+	Secondary time.Time `json:"secondary"`
+
+	// Grid of the geocoded product.
+	DemPar DemPar `json:"dem_par"`
+
+	Track Track `json:"track"`
+	SAR   SAR   `json:"sar"`
+
+	// Assets keyed by their role in the product (e.g. "data", "preview").
+	// Local paths are converted to paths relative to the item when the
+	// catalog is written.
+	Assets map[string]Asset `json:"assets"`
+}
+
+// Item is a STAC Item.
+type Item struct {
+	Type           string                 `json:"type"`
+	StacVersion    string                 `json:"stac_version"`
+	StacExtensions []string               `json:"stac_extensions"`
+	ID             string                 `json:"id"`
+	Geometry       *geojson.Geometry      `json:"geometry"`
+	BBox           []float64              `json:"bbox"`
+	Properties     map[string]interface{} `json:"properties"`
+	Links          []Link                 `json:"links"`
+	Assets         map[string]Asset       `json:"assets"`
+	Collection     string                 `json:"collection,omitempty"`
+
+	bound      orb.Bound
+	start, end time.Time
+}
+
+func bbox(b orb.Bound) []float64 {
+	return []float64{b.Min[0], b.Min[1], b.Max[0], b.Max[1]}
+}
+
+const timeFmt = "2006-01-02T15:04:05Z"
+
+/*
+NewItem converts the product into an Item. Single date products get a
+"datetime", pair products get "start_datetime" and "end_datetime" with a
+null "datetime".
+*/
+func NewItem(p Product) (it Item, err error) {
+	if p.ID == "" {
+		return it, fmt.Errorf("product needs an ID")
+	}
+
+	if err = p.Track.Validate(); err != nil {
+		return
+	}
+
+	if len(p.Assets) == 0 {
+		return it, fmt.Errorf("product '%s' has no assets", p.ID)
+	}
+
+	it.start = p.Meta.Date.UTC()
+	it.end = it.start
+
+	if p.Kind.IsPair() {
+		if p.Secondary.IsZero() {
+			return it, fmt.Errorf("%s product '%s' needs a secondary date",
+				p.Kind, p.ID)
+		}
+
+		if sec := p.Secondary.UTC(); sec.Before(it.start) {
+			it.start = sec
+		} else {
+			it.end = sec
+		}
+	}
+
+	it.bound = p.DemPar.Bound()
+
+	it.Type = "Feature"
+	it.StacVersion = Version
+	it.StacExtensions = []string{SARExtension, SatelliteExtension}
+	it.ID = p.ID
+	it.Geometry = geojson.NewGeometry(it.bound.ToPolygon())
+	it.BBox = bbox(it.bound)
+	it.Collection = p.Track.ID()
+	it.Assets = p.Assets
+
+	sar := p.SAR
+	looks := sar.Looks
+	if looks.Rng == 0 {
+		looks.Rng = 1
+	}
+
+	if looks.Azi == 0 {
+		looks.Azi = 1
+	}
+
+	props := map[string]interface{}{
+		"gomma:kind":                p.Kind.String(),
+		"sar:instrument_mode":       sar.InstrumentMode,
+		"sar:frequency_band":        sar.FrequencyBand,
+		"sar:polarizations":         sar.Polarizations,
+		"sar:product_type":          p.Kind.ProductType(),
+		"sar:looks_range":           looks.Rng,
+		"sar:looks_azimuth":         looks.Azi,
+		"sar:observation_direction": "right",
+		"sat:relative_orbit":        p.Track.RelativeOrbit,
+		"sat:orbit_state":           strings.ToLower(p.Track.Pass),
+	}
+
+	if sar.CenterFrequency > 0 {
+		props["sar:center_frequency"] = sar.CenterFrequency
+	}
+
+	if p.Track.Platform != "" {
+		props["platform"] = strings.ToLower(p.Track.Platform)
+	}
+
+	if p.Kind.IsPair() {
+		props["datetime"] = nil
+		props["start_datetime"] = it.start.Format(timeFmt)
+		props["end_datetime"] = it.end.Format(timeFmt)
+	} else {
+		props["datetime"] = it.start.Format(timeFmt)
+	}
+
+	it.Properties = props
+	return it, nil
+}
@@ -0,0 +1,147 @@
+/*
+Package stac publishes the products of gomma as a static SpatioTemporal
+Asset Catalog. Products are converted into STAC Items carrying the
+fields of the SAR and satellite extensions and they are organised into
+one collection per track.
+*/
+package stac
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/bozso/gotoolbox/errors"
+)
+
+const (
+	Version = "1.0.0"
+
+	SARExtension       = "https://stac-extensions.github.io/sar/v1.0.0/schema.json"
+	SatelliteExtension = "https://stac-extensions.github.io/sat/v1.0.0/schema.json"
+
+	// media types of the assets and links
+	GeoTIFF = "image/tiff; application=geotiff"
+	PNG     = "image/png"
+	Binary  = "application/octet-stream"
+	Text    = "text/plain"
+	JSON    = "application/json"
+	GeoJSON = "application/geo+json"
+)
+
+// Kind of a gomma product.
+type Kind int
+
+const (
+	Backscatter Kind = iota
+	MultiLook
+	Interferogram
+	Coherence
+	Unwrapped
+	Displacement
+	Elevation
+)
+
+func (k *Kind) Set(s string) (err error) {
+	const mode errors.Mode = "product kind"
+
+	switch strings.ToLower(s) {
+	case "backscatter":
+		*k = Backscatter
+	case "mli", "multilook":
+		*k = MultiLook
+	case "interferogram", "ifg":
+		*k = Interferogram
+	case "coherence":
+		*k = Coherence
+	case "unwrapped":
+		*k = Unwrapped
+	case "displacement":
+		*k = Displacement
+	case "elevation", "dem":
+		*k = Elevation
+	default:
+		err = mode.Error(s)
+	}
+
+	return
+}
+
+func (k Kind) String() (s string) {
+	switch k {
+	case Backscatter:
+		s = "backscatter"
+	case MultiLook:
+		s = "mli"
+	case Interferogram:
+		s = "interferogram"
+	case Coherence:
+		s = "coherence"
+	case Unwrapped:
+		s = "unwrapped"
+	case Displacement:
+		s = "displacement"
+	case Elevation:
+		s = "elevation"
+	default:
+		s = "unknown"
+	}
+	return
+}
+
+// ProductType returns the value of the "sar:product_type" field.
+func (k Kind) ProductType() (s string) {
+	switch k {
+	case Backscatter, MultiLook:
+		s = "GRD"
+	case Interferogram, Unwrapped:
+		s = "IFG"
+	case Coherence:
+		s = "COH"
+	case Displacement:
+		s = "DISP"
+	case Elevation:
+		s = "DEM"
+	default:
+		s = "unknown"
+	}
+	return
+}
+
+// IsPair reports whether the product is derived from two acquisitions.
+func (k Kind) IsPair() bool {
+	switch k {
+	case Interferogram, Coherence, Unwrapped, Displacement:
+		return true
+	default:
+		return false
+	}
+}
+
+func (k Kind) MarshalJSON() (b []byte, err error) {
+	return json.Marshal(k.String())
+}
+
+func (k *Kind) UnmarshalJSON(b []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(b, &s); err != nil {
+		return
+	}
+
+	return k.Set(s)
+}
+
+// Link is a STAC link object.
+type Link struct {
+	Rel   string `json:"rel"`
+	Href  string `json:"href"`
+	Type  string `json:"type,omitempty"`
+	Title string `json:"title,omitempty"`
+}
+
+// Asset is a STAC asset object.
+type Asset struct {
+	Href  string   `json:"href"`
+	Title string   `json:"title,omitempty"`
+	Type  string   `json:"type,omitempty"`
+	Roles []string `json:"roles,omitempty"`
+}
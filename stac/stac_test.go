@@ -0,0 +1,145 @@
+package stac
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bozso/gomma/data"
+	"github.com/bozso/gomma/date"
+	"github.com/bozso/gomma/utils/params"
+)
+
+const demPar = `Gamma DIFF&GEO DEM/MAP parameter file
+title: dem_seg
+DEM_projection:     EQA
+data_format:        REAL*4
+width:                 200
+nlines:                100
+corner_lat:      47.5000000  decimal degrees
+corner_lon:      19.0000000  decimal degrees
+post_lat:        -0.0100000  decimal degrees
+post_lon:         0.0100000  decimal degrees
+`
+
+func TestParseDemPar(t *testing.T) {
+	d, err := ParseDemPar(params.FromString(demPar, ":").ToParser())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := d.Bound()
+	expected := []float64{18.995, 46.505, 20.995, 47.505}
+
+	for ii, v := range bbox(b) {
+		if diff := v - expected[ii]; diff > 1e-9 || diff < -1e-9 {
+			t.Fatalf("expected bounding box %v, got %v", expected, bbox(b))
+		}
+	}
+}
+
+func TestParseDemParProjection(t *testing.T) {
+	utm := strings.Replace(demPar, "EQA", "UTM", 1)
+
+	if _, err := ParseDemPar(params.FromString(utm, ":").ToParser()); err == nil {
+		t.Errorf("expected an error for a UTM grid")
+	}
+}
+
+func testProduct(id string, kind Kind, orbit int) (p Product) {
+	p = Product{
+		ID:   id,
+		Kind: kind,
+		Meta: data.Meta{
+			Date: date.New(time.Date(2020, 3, 1, 5, 0, 0, 0, time.UTC)),
+		},
+		DemPar: DemPar{
+			Width: 10, Lines: 10,
+			CornerLat: 47.0, PostLat: -0.1,
+			CornerLon: 19.0, PostLon: 0.1,
+		},
+		Track: Track{
+			Platform:      "sentinel-1",
+			RelativeOrbit: orbit,
+			Pass:          "Ascending",
+		},
+		SAR: SAR{
+			InstrumentMode: "IW",
+			FrequencyBand:  "C",
+			Polarizations:  []string{"VV"},
+		},
+		Assets: map[string]Asset{
+			"data": {Href: "/data/" + id + ".tif", Type: GeoTIFF,
+				Roles: []string{"data"}},
+		},
+	}
+
+	if kind.IsPair() {
+		p.Secondary = p.Meta.Date.AddDate(0, 0, 12)
+	}
+
+	return
+}
+
+func TestCatalogWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := NewCatalog("gomma", "test catalog")
+
+	for _, p := range []Product{
+		testProduct("mli", MultiLook, 51),
+		testProduct("ifg", Interferogram, 51),
+		testProduct("coh", Coherence, 124),
+	} {
+		if err = c.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = c.Add(testProduct("mli", MultiLook, 51)); err == nil {
+		t.Errorf("expected an error for a duplicate item")
+	}
+
+	if err = c.Write(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "sentinel-1-track-051-ascending",
+		"ifg", "ifg.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var it struct {
+		Properties map[string]interface{} `json:"properties"`
+		Assets     map[string]Asset       `json:"assets"`
+	}
+
+	if err = json.Unmarshal(b, &it); err != nil {
+		t.Fatal(err)
+	}
+
+	props := it.Properties
+	if props["datetime"] != nil ||
+		props["start_datetime"] != "2020-03-01T05:00:00Z" ||
+		props["end_datetime"] != "2020-03-13T05:00:00Z" {
+		t.Errorf("unexpected datetime fields %v", props)
+	}
+
+	if props["sat:orbit_state"] != "ascending" {
+		t.Errorf("unexpected orbit state %v", props["sat:orbit_state"])
+	}
+
+	if _, err = os.Stat(filepath.Join(dir, "sentinel-1-track-124-ascending",
+		"collection.json")); err != nil {
+		t.Error(err)
+	}
+}
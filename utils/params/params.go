@@ -70,7 +70,7 @@ func FromFile(file path.ValidFile, sep string) (p Params, err error) {
 }
 
 func FromString(elems, sep string) (p Params) {
-	p.sep = sep
+	p.sep, p.filepath = sep, &np
 	split := strings.Split(elems, "\n")
 
 	for _, line := range split {